@@ -0,0 +1,89 @@
+package address
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	zipcodeLength    = 5 // 2015년 이후 국가기초구역번호
+	maxAddressLength = 200
+	maxNameLength    = 50
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+// 하이픈과 공백을 지운다. 숫자가 아닌 문자는 x 로 바꿔 검증에서 걸러지게 한다.
+func digits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return 'x'
+	}, value)
+}
+
+// 우편번호와 전화번호는 숫자만 남기고 나머지 값은 앞뒤 공백을 지운다.
+func Normalize(address *protocol.AddressInfo) error {
+	address.Recipient = strings.TrimSpace(address.Recipient)
+	address.RoadAddress = strings.TrimSpace(address.RoadAddress)
+	address.DetailAddress = strings.TrimSpace(address.DetailAddress)
+	address.Zipcode = digits(address.Zipcode)
+	address.Phone = digits(address.Phone)
+
+	if address.Recipient == "" || utf8.RuneCountInString(address.Recipient) > maxNameLength {
+		return ErrInvalidAddress
+	}
+	if len(address.Zipcode) != zipcodeLength || strings.Contains(address.Zipcode, "x") {
+		return ErrInvalidAddress
+	}
+	if len(address.Phone) < 9 || len(address.Phone) > 11 || strings.Contains(address.Phone, "x") {
+		return ErrInvalidAddress
+	}
+	if address.RoadAddress == "" || utf8.RuneCountInString(address.RoadAddress) > maxAddressLength ||
+		utf8.RuneCountInString(address.DetailAddress) > maxAddressLength {
+		return ErrInvalidAddress
+	}
+	return nil
+}
+
+func scan(rows *sql.Rows) (protocol.AddressInfo, error) {
+	address := protocol.AddressInfo{}
+	err := rows.Scan(&address.AddressId, &address.Recipient, &address.Phone, &address.Zipcode,
+		&address.RoadAddress, &address.DetailAddress, &address.Default)
+	return address, err
+}
+
+func selectOne(m database.Manager, timer *time.Timer, selectQuery string, args ...interface{}) (*protocol.AddressInfo, error) {
+	rows, err := database.Select(m, timer, selectQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	address, err := scan(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// 사용자의 배송지. addressId 가 비어 있으면 기본 배송지이다. 없으면 nil 을 돌려준다.
+func Find(m database.Manager, timer *time.Timer, uniqueId, addressId string) (*protocol.AddressInfo, error) {
+	if addressId == "" {
+		return selectOne(m, timer, query.SelectDefaultAddress, uniqueId)
+	}
+	return selectOne(m, timer, query.SelectAddress, addressId, uniqueId)
+}
@@ -0,0 +1,287 @@
+package address
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 배송지 목록(GET), 추가(POST)
+	addressUrl = "/api/address"
+	// 배송지 수정(PUT), 삭제(DELETE)
+	addressItemUrl = "/api/address/:address_id"
+	// 기본 배송지로 지정
+	addressDefaultUrl = "/api/address/:address_id/default"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(addressUrl, "GET"), getAddresses)
+	route.AddRoute(route.NewRouteType(addressUrl, "POST"), addAddress)
+	route.AddRoute(route.NewRouteType(addressItemUrl, "PUT"), updateAddress)
+	route.AddRoute(route.NewRouteType(addressItemUrl, "DELETE"), deleteAddress)
+	route.AddRoute(route.NewRouteType(addressDefaultUrl, "PUT"), setDefaultAddress)
+}
+
+func formAddress(ctx echo.Context) protocol.AddressInfo {
+	return protocol.AddressInfo{
+		Recipient:     ctx.FormValue("recipient"),
+		Phone:         ctx.FormValue("phone"),
+		Zipcode:       ctx.FormValue("zipcode"),
+		RoadAddress:   ctx.FormValue("road_address"),
+		DetailAddress: ctx.FormValue("detail_address"),
+	}
+}
+
+func getAddresses(ctx echo.Context) error {
+	resp := &protocol.AddressListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectAddresses, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Addresses = make([]protocol.AddressInfo, 0)
+	for rows.Next() {
+		address, err := scan(rows)
+		if err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Addresses = append(resp.Addresses, address)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 첫 배송지이거나 default=1 이면 기본 배송지가 된다.
+func addAddress(ctx echo.Context) error {
+	resp := &protocol.AddressResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	address := formAddress(ctx)
+	if err := Normalize(&address); err != nil {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	current, err := Find(customContext.Manager, timer, uniqueId, "")
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	addressId := util.RandString()
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertAddress, addressId, uniqueId,
+		address.Recipient, address.Phone, address.Zipcode, address.RoadAddress, address.DetailAddress); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if current == nil || ctx.FormValue("default") == "1" {
+		if _, err := database.Exec(customContext.Manager, timer, query.UpdateDefaultAddress, addressId, uniqueId); err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+	}
+
+	resp.AddressId = addressId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func updateAddress(ctx echo.Context) error {
+	resp := &protocol.AddressResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	addressId := ctx.Param("address_id")
+	current, err := Find(customContext.Manager, timer, uniqueId, addressId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if current == nil {
+		resp.Status = vcomError.AddressNotFound
+		resp.Detail = vcomError.MessageAddressNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	address := formAddress(ctx)
+	if err := Normalize(&address); err != nil {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.UpdateAddress, address.Recipient, address.Phone,
+		address.Zipcode, address.RoadAddress, address.DetailAddress, addressId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.AddressId = addressId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 기본 배송지를 지우면 가장 최근에 추가한 배송지가 기본 배송지가 된다.
+func deleteAddress(ctx echo.Context) error {
+	resp := &protocol.AddressResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	addressId := ctx.Param("address_id")
+	current, err := Find(customContext.Manager, timer, uniqueId, addressId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if current == nil {
+		resp.Status = vcomError.AddressNotFound
+		resp.Detail = vcomError.MessageAddressNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteAddress, addressId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if current.Default {
+		next, err := Find(customContext.Manager, timer, uniqueId, "")
+		if err == nil && next != nil {
+			_, err = database.Exec(customContext.Manager, timer, query.UpdateDefaultAddress, next.AddressId, uniqueId)
+		}
+		if err != nil {
+			customContext.Log.Error("default address update failed. unique_id: ", uniqueId, ", err: ", err)
+		}
+	}
+
+	resp.AddressId = addressId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func setDefaultAddress(ctx echo.Context) error {
+	resp := &protocol.AddressResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	addressId := ctx.Param("address_id")
+	current, err := Find(customContext.Manager, timer, uniqueId, addressId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if current == nil {
+		resp.Status = vcomError.AddressNotFound
+		resp.Detail = vcomError.MessageAddressNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.UpdateDefaultAddress, addressId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.AddressId = addressId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
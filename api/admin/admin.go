@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/config"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+// 관리자 api 의 handler 에서 ctx.Get 으로 꺼낼 수 있는 관리자 unique_id
+const adminIdKey = "admin_id"
+
+// session 이 관리자인 경우에만 handler 를 호출한다.
+func adminOnly(handler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		resp := &protocol.BaseResponse{}
+		customContext, ok := ctx.(*context.CustomContext)
+		if !ok {
+			log.Error("failed to casting echo.Context to api.CustomContext")
+			resp.Status = vcomError.InternalError
+			resp.Detail = vcomError.MessageUnknownError
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+
+		timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+		defer timer.Stop()
+
+		uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+		if err != nil {
+			customContext.Log.Error("validate session failed. err: ", err)
+			resp.Status = vcomError.SessionValidationFailed
+			resp.Detail = vcomError.MessageInvalidSession
+			return ctx.JSON(http.StatusUnauthorized, resp)
+		}
+
+		isAdmin, err := customContext.IsAdmin(uniqueId, timer)
+		if err != nil {
+			customContext.Log.Error("admin check failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		if !isAdmin {
+			customContext.Log.Warning("admin api access denied. unique_id: ", uniqueId, ", uri: ", ctx.Request().RequestURI)
+			resp.Status = vcomError.PermissionDenied
+			resp.Detail = vcomError.MessagePermissionDenied
+			return ctx.JSON(http.StatusForbidden, resp)
+		}
+
+		ctx.Set(adminIdKey, uniqueId)
+		return handler(ctx)
+	}
+}
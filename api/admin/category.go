@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"github.com/4538cgy/backend-second/api/category"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/search"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// 카테고리 생성. parent_id 가 없으면 최상위 노드가 된다.
+	categoryCreateUrl = "/api/admin/category"
+	// 카테고리 수정(PUT), 삭제(DELETE). 하위 노드가 있으면 삭제할 수 없다.
+	categoryUrl = "/api/admin/category/:category_id"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(categoryCreateUrl, "POST"), adminOnly(createCategory))
+	route.AddRoute(route.NewRouteType(categoryUrl, "PUT"), adminOnly(updateCategory))
+	route.AddRoute(route.NewRouteType(categoryUrl, "DELETE"), adminOnly(deleteCategory))
+}
+
+type categoryForm struct {
+	parentId  string
+	name      string
+	slug      string
+	sortOrder int
+}
+
+func readCategoryForm(ctx echo.Context) (*categoryForm, bool) {
+	form := &categoryForm{
+		parentId: ctx.FormValue("parent_id"),
+		name:     ctx.FormValue("name"),
+		slug:     ctx.FormValue("slug"),
+	}
+	if sortOrder := ctx.FormValue("sort_order"); sortOrder != "" {
+		value, err := strconv.Atoi(sortOrder)
+		if err != nil {
+			return nil, false
+		}
+		form.sortOrder = value
+	}
+	return form, form.name != "" && category.ValidSlug(form.slug)
+}
+
+func createCategory(ctx echo.Context) error {
+	return saveCategory(ctx, "")
+}
+
+func updateCategory(ctx echo.Context) error {
+	return saveCategory(ctx, ctx.Param("category_id"))
+}
+
+// categoryId 가 비어 있으면 새로 만든다.
+func saveCategory(ctx echo.Context, categoryId string) error {
+	resp := &protocol.CategoryResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	form, ok := readCategoryForm(ctx)
+	if !ok {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	tree, err := category.Load(customContext.Manager, timer)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if categoryId != "" {
+		if _, ok := tree.Find(categoryId); !ok {
+			resp.Status = vcomError.CategoryNotFound
+			resp.Detail = vcomError.MessageCategoryNotFound
+			return ctx.JSON(http.StatusNotFound, resp)
+		}
+	}
+	if !tree.ValidParent(categoryId, form.parentId) {
+		resp.Status = vcomError.InvalidCategoryParent
+		resp.Detail = vcomError.MessageInvalidCategoryParent
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	if tree.SlugUsed(categoryId, form.slug) {
+		resp.Status = vcomError.CategorySlugBeingUsed
+		resp.Detail = vcomError.MessageCategorySlugBeingUsed
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	if categoryId == "" {
+		categoryId = util.RandString()
+		_, err = database.Exec(customContext.Manager, timer, query.InsertCategory,
+			categoryId, form.parentId, form.name, form.slug, form.sortOrder)
+	} else {
+		_, err = database.Exec(customContext.Manager, timer, query.UpdateCategory,
+			form.parentId, form.name, form.slug, form.sortOrder, categoryId)
+	}
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := search.IndexCategory(customContext.Manager, timer, categoryId); err != nil {
+		customContext.Log.Error("search index failed. category_id: ", categoryId, ", err: ", err)
+	}
+
+	customContext.Log.Info("category saved. admin: ", ctx.Get(adminIdKey), ", category: ", categoryId, ", slug: ", form.slug)
+	resp.CategoryId = categoryId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func deleteCategory(ctx echo.Context) error {
+	resp := &protocol.CategoryResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	tree, err := category.Load(customContext.Manager, timer)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	categoryId := ctx.Param("category_id")
+	if _, ok := tree.Find(categoryId); !ok {
+		resp.Status = vcomError.CategoryNotFound
+		resp.Detail = vcomError.MessageCategoryNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	if tree.HasChildren(categoryId) {
+		resp.Status = vcomError.CategoryHasChildren
+		resp.Detail = vcomError.MessageCategoryHasChildren
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	// 노드를 먼저 지우고 연결된 상품을 다시 색인한 뒤 연결을 끊는다.
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteCategory, categoryId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := search.IndexCategory(customContext.Manager, timer, categoryId); err != nil {
+		customContext.Log.Error("search index failed. category_id: ", categoryId, ", err: ", err)
+	}
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteCategoryProducts, categoryId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	customContext.Log.Info("category deleted. admin: ", ctx.Get(adminIdKey), ", category: ", categoryId)
+	resp.CategoryId = categoryId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"github.com/4538cgy/backend-second/api/promotion"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/labstack/echo/v4"
+	"time"
+)
+
+const (
+	// 플랫폼 쿠폰 생성. 할인 금액은 플랫폼이 부담한다.
+	couponCreateUrl = "/api/admin/coupon"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(couponCreateUrl, "POST"), adminOnly(createPlatformCoupon))
+}
+
+func createPlatformCoupon(ctx echo.Context) error {
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	return promotion.CreateCoupon(ctx, timer, "")
+}
@@ -0,0 +1,189 @@
+package admin
+
+import (
+	"github.com/4538cgy/backend-second/api/asset"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/seller"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 승인 대기중인 기업 판매자 목록
+	pendingSellerUrl = "/api/admin/seller/pending"
+	// 판매자 등록 서류의 서명된 url
+	sellerDocumentUrl = "/api/admin/seller/:unique_id/document"
+	// 승인, 반려. 반려는 reason 이 필요하다.
+	sellerApproveUrl = "/api/admin/seller/:unique_id/approve"
+	sellerRejectUrl  = "/api/admin/seller/:unique_id/reject"
+	// 승인, 반려 이력
+	sellerAuditUrl = "/api/admin/seller/:unique_id/audit"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(pendingSellerUrl, "GET"), adminOnly(getPendingSellers))
+	route.AddRoute(route.NewRouteType(sellerDocumentUrl, "GET"), adminOnly(getSellerDocument))
+	route.AddRoute(route.NewRouteType(sellerApproveUrl, "POST"), adminOnly(approveSeller))
+	route.AddRoute(route.NewRouteType(sellerRejectUrl, "POST"), adminOnly(rejectSeller))
+	route.AddRoute(route.NewRouteType(sellerAuditUrl, "GET"), adminOnly(getSellerAudits))
+}
+
+func getPendingSellers(ctx echo.Context) error {
+	resp := &protocol.SellerRegistrationListResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, query.SelectSellerRegistrations,
+		seller.SellerWaitAuthentication, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Registrations = make([]protocol.SellerRegistrationInfo, 0)
+	for rows.Next() {
+		info := protocol.SellerRegistrationInfo{}
+		if err := rows.Scan(&info.UniqueId, &info.SellerType, &info.CompanyRegistrationNumber, &info.OwnerName,
+			&info.CompanyName, &info.ChannelName, &info.BankName, &info.BankAccountNumber,
+			&info.Authentication, &info.Created); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Registrations = append(resp.Registrations, info)
+	}
+	if len(resp.Registrations) > count {
+		resp.Registrations = resp.Registrations[:count]
+		resp.HasNext = true
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getSellerDocument(ctx echo.Context) error {
+	resp := &protocol.SellerDocumentResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	url, expires := customContext.SignedAssetUrl(asset.KindSeller, ctx.Param("unique_id")+".pdf")
+	customContext.Log.Info("seller document opened. admin: ", ctx.Get(adminIdKey), ", seller: ", ctx.Param("unique_id"))
+	resp.Url = url
+	resp.ExpiresAt = expires.Unix()
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func approveSeller(ctx echo.Context) error {
+	return decideSeller(ctx, seller.SellerAuthenticated)
+}
+
+func rejectSeller(ctx echo.Context) error {
+	if ctx.FormValue("reason") == "" {
+		resp := &protocol.SellerDecisionResponse{}
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	return decideSeller(ctx, seller.SellerRejected)
+}
+
+// 승인 대기중인 판매자만 처리할 수 있다. 처리 결과는 이력으로 남기고 판매자에게 알린다.
+func decideSeller(ctx echo.Context, decision seller.SellerAuthType) error {
+	resp := &protocol.SellerDecisionResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	adminId := ctx.Get(adminIdKey).(string)
+	sellerId := ctx.Param("unique_id")
+	reason := ctx.FormValue("reason")
+
+	res, err := database.Exec(customContext.Manager, timer, query.UpdateSellerAuthentication,
+		decision, reason, sellerId, seller.SellerWaitAuthentication)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		resp.Status = vcomError.SellerNotPending
+		resp.Detail = vcomError.MessageSellerNotPending
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertSellerAudit,
+		util.RandString(), sellerId, adminId, decision, reason); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	notifySeller(customContext.Notifier, sellerId, decision, reason)
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getSellerAudits(ctx echo.Context) error {
+	resp := &protocol.SellerAuditResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectSellerAudits, ctx.Param("unique_id"))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Audits = make([]protocol.SellerAuditInfo, 0)
+	for rows.Next() {
+		audit := protocol.SellerAuditInfo{}
+		if err := rows.Scan(&audit.AuditId, &audit.UniqueId, &audit.AdminId,
+			&audit.Authentication, &audit.Reason, &audit.Created); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Audits = append(resp.Audits, audit)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func notifySeller(notifier notification.Notifier, sellerId string, decision seller.SellerAuthType, reason string) {
+	n := notification.Notification{
+		UniqueId:    sellerId,
+		Category:    notification.CategorySeller,
+		Title:       "판매자 등록이 승인되었습니다.",
+		Body:        "지금부터 상품을 등록할 수 있습니다.",
+		ReferenceId: sellerId,
+	}
+	if decision == seller.SellerRejected {
+		n.Title = "판매자 등록이 반려되었습니다."
+		n.Body = "반려 사유: " + reason
+	}
+	if err := notifier.Notify(n); err != nil {
+		log.Error("notify seller failed. unique_id: ", sellerId, ", err: ", err)
+	}
+}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/settlement"
+	"github.com/4538cgy/backend-second/config"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 정산서 지급 완료 처리. 실제 송금은 관리자가 은행에서 한다.
+	settlementPayoutUrl = "/api/admin/settlement/:statement_id/payout"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(settlementPayoutUrl, "POST"), adminOnly(payoutStatement))
+}
+
+func payoutStatement(ctx echo.Context) error {
+	resp := &protocol.SettlementPayoutResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	statementId := ctx.Param("statement_id")
+	err := settlement.MarkPaid(customContext.Manager, timer, statementId)
+	if err == settlement.ErrStatementNotPayable {
+		resp.Status = vcomError.StatementNotPayable
+		resp.Detail = vcomError.MessageStatementNotPayable
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	customContext.Log.Info("settlement paid. admin: ", ctx.Get(adminIdKey), ", statement: ", statementId)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
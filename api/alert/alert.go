@@ -0,0 +1,180 @@
+package alert
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"sync"
+	"time"
+)
+
+const (
+	checkTimeout       = 30 * time.Second
+	maxPendingProducts = 10000
+)
+
+// wishlist_alert.kind
+const (
+	KindPriceDrop = "price_drop"
+	KindRestock   = "restock"
+)
+
+type Alert struct {
+	AlertId   string
+	UniqueId  string
+	ProductId string
+	Kind      string
+	Price     int
+}
+
+type watch struct {
+	uniqueId        string
+	title           string
+	notifiedPrice   int
+	notifiedInStock bool
+	price           int
+	amount          int
+}
+
+// 찜한 사용자마다 마지막으로 알려준 값과 비교해서
+// 가격이 내렸거나 품절에서 다시 재고가 생겼으면 알림을 쌓고 사용자에게 보낸다. 만든 알림을 돌려준다.
+func Check(m database.Manager, notifier notification.Notifier, timer *time.Timer, productId string) ([]Alert, error) {
+	rows, err := database.Select(m, timer, query.SelectWishlistWatchers, productId)
+	if err != nil {
+		return nil, err
+	}
+	watchers := make([]watch, 0)
+	for rows.Next() {
+		w := watch{}
+		if err := rows.Scan(&w.uniqueId, &w.title, &w.notifiedPrice, &w.notifiedInStock, &w.price, &w.amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		watchers = append(watchers, w)
+	}
+	rows.Close()
+
+	alerts := make([]Alert, 0)
+	for _, w := range watchers {
+		inStock := w.amount > 0
+		kinds := make([]string, 0, 2)
+		if w.price < w.notifiedPrice {
+			kinds = append(kinds, KindPriceDrop)
+		}
+		if inStock && !w.notifiedInStock {
+			kinds = append(kinds, KindRestock)
+		}
+		for _, kind := range kinds {
+			alert := Alert{AlertId: util.RandString(), UniqueId: w.uniqueId, ProductId: productId, Kind: kind, Price: w.price}
+			if _, err := database.Exec(m, timer, query.InsertWishlistAlert,
+				alert.AlertId, alert.UniqueId, alert.ProductId, alert.Kind, alert.Price); err != nil {
+				return alerts, err
+			}
+			alerts = append(alerts, alert)
+			notify(notifier, alert, w.title)
+		}
+		// 가격이 올랐거나 품절된 것도 기록해 두어야 다음에 내리거나 재입고될 때 알 수 있다.
+		if _, err := database.Exec(m, timer, query.UpdateWishlistNotified, w.price, inStock, w.uniqueId, productId); err != nil {
+			return alerts, err
+		}
+	}
+	if len(alerts) > 0 {
+		log.Info("wishlist alerts created. product_id: ", productId, ", count: ", len(alerts))
+	}
+	return alerts, nil
+}
+
+// 찜한 사용자가 많은 상품은 알림에 오래 걸리므로 주문, 상품 수정 요청에서 바로 확인하지 않고
+// 상품 id 만 모아 두었다가 background 에서 확인한다.
+type Watcher interface {
+	Start()
+	// 상품 가격이나 재고가 바뀐 뒤 부른다.
+	ProductChanged(productId string)
+}
+
+type CheckFunc func(timer *time.Timer, productId string) error
+
+type alertHandler struct {
+	check CheckFunc
+
+	lock    sync.Mutex
+	pending map[string]bool
+	wakeCh  chan struct{}
+}
+
+func NewAlertHandler(dbManager database.Manager, notifier notification.Notifier) Watcher {
+	return NewAlertHandlerWith(func(timer *time.Timer, productId string) error {
+		_, err := Check(dbManager, notifier, timer, productId)
+		return err
+	})
+}
+
+// 확인 방법을 직접 지정한다. test 에 사용한다.
+func NewAlertHandlerWith(check CheckFunc) Watcher {
+	return &alertHandler{
+		check:   check,
+		pending: map[string]bool{},
+		wakeCh:  make(chan struct{}, 1),
+	}
+}
+
+func (a *alertHandler) Start() {
+	go func() {
+		for range a.wakeCh {
+			a.run()
+		}
+	}()
+}
+
+// 같은 상품은 한 번만 확인한다. 너무 많이 쌓이면 버린다. 다음 변경 때 다시 확인된다.
+func (a *alertHandler) ProductChanged(productId string) {
+	a.lock.Lock()
+	if !a.pending[productId] && len(a.pending) >= maxPendingProducts {
+		a.lock.Unlock()
+		log.Warning("wishlist check queue is full. product_id: ", productId)
+		return
+	}
+	a.pending[productId] = true
+	a.lock.Unlock()
+
+	select {
+	case a.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (a *alertHandler) run() int {
+	a.lock.Lock()
+	pending := a.pending
+	a.pending = map[string]bool{}
+	a.lock.Unlock()
+
+	for productId := range pending {
+		timer := time.NewTimer(checkTimeout)
+		if err := a.check(timer, productId); err != nil {
+			log.Error("wishlist check failed. product_id: ", productId, ", err: ", err)
+		}
+		timer.Stop()
+	}
+	return len(pending)
+}
+
+func notify(notifier notification.Notifier, alert Alert, title string) {
+	n := notification.Notification{
+		UniqueId:    alert.UniqueId,
+		Category:    notification.CategoryWishlist,
+		Title:       "찜한 상품의 가격이 내렸습니다.",
+		Body:        fmt.Sprintf("%s 상품이 지금 %d원입니다.", title, alert.Price),
+		ReferenceId: alert.ProductId,
+	}
+	if alert.Kind == KindRestock {
+		n.Title = "찜한 상품이 재입고되었습니다."
+		n.Body = fmt.Sprintf("%s 상품을 다시 구매할 수 있습니다.", title)
+	}
+	if err := notifier.Notify(n); err != nil {
+		log.Error("notify wishlist alert failed. alert_id: ", alert.AlertId, ", err: ", err)
+	}
+}
@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	lock    sync.Mutex
+	checked map[string]int
+	done    chan struct{}
+}
+
+func (r *recorder) check(timer *time.Timer, productId string) error {
+	r.lock.Lock()
+	r.checked[productId]++
+	r.lock.Unlock()
+	if r.done != nil {
+		r.done <- struct{}{}
+	}
+	return nil
+}
+
+// 같은 상품의 변경은 한 번만 확인한다.
+func TestProductChangedDeduplicates(t *testing.T) {
+	r := &recorder{checked: map[string]int{}}
+	handler := NewAlertHandlerWith(r.check).(*alertHandler)
+	for index := 0; index < 5; index++ {
+		handler.ProductChanged("a")
+		handler.ProductChanged("b")
+	}
+	if checked := handler.run(); checked != 2 {
+		t.Fatal("checked: ", checked)
+	}
+	if r.checked["a"] != 1 || r.checked["b"] != 1 {
+		t.Fatal("checked: ", r.checked)
+	}
+	if checked := handler.run(); checked != 0 {
+		t.Fatal("checked again: ", checked)
+	}
+}
+
+func TestProductChangedQueueLimit(t *testing.T) {
+	r := &recorder{checked: map[string]int{}}
+	handler := NewAlertHandlerWith(r.check).(*alertHandler)
+	for index := 0; index < maxPendingProducts+10; index++ {
+		handler.ProductChanged(fmt.Sprint("product", index))
+	}
+	// 이미 기다리는 상품은 가득 차도 받아들인다.
+	handler.ProductChanged("product0")
+	if pending := len(handler.pending); pending != maxPendingProducts {
+		t.Fatal("pending: ", pending)
+	}
+}
+
+// 요청은 기다리지 않고 background 에서 확인한다.
+func TestStartChecksInBackground(t *testing.T) {
+	r := &recorder{checked: map[string]int{}, done: make(chan struct{}, 1)}
+	handler := NewAlertHandlerWith(r.check)
+	handler.Start()
+	handler.ProductChanged("a")
+
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("product not checked")
+	}
+}
@@ -0,0 +1,212 @@
+package asset
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/labstack/echo/v4"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// asset 서빙 uri. /assets/<kind>/<name>
+	AssetUri = "/assets/:kind/*"
+
+	paramKind      = "kind"
+	paramExpires   = "expires"
+	paramSignature = "signature"
+
+	defaultSignedUrlExpireSec = 600
+
+	// 이전에 config.toml 에 들어 있던 예시 key
+	placeholderSigningKey = "change-me-asset-url-signing-key"
+)
+
+// public asset 으로 저장할 수 있는 확장자. Content-Type 이 확장자로 정해지므로
+// html, svg 처럼 브라우저가 실행할 수 있는 파일은 API origin 에서 서빙하지 않는다.
+var publicExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true,
+	".mp4": true, ".mov": true, ".m4v": true, ".webm": true,
+}
+
+var ErrUnsupportedType = errors.New("unsupported asset type")
+
+type Kind string
+
+const (
+	KindProfile = Kind("profile") // 사용자 프로필 이미지
+	KindMedia   = Kind("media")   // 상품, 리뷰 미디어
+	KindSeller  = Kind("seller")  // 판매자 등록 서류. 사업자 등록증, 통장 사본
+)
+
+type Policy int
+
+const (
+	PolicyPublic = Policy(0) // 누구나 접근 가능
+	PolicyOwner  = Policy(1) // 소유자 혹은 관리자만 접근 가능. 서명된 url 로만 접근할 수 있다.
+)
+
+type Asset interface {
+	AssetPolicy(kind Kind) Policy
+	AssetUrl(kind Kind, name string) string                          // public asset url
+	SignedAssetUrl(kind Kind, name string) (string, time.Time)       // 서명된 url, 만료시각
+	SaveAsset(kind Kind, name string, src io.Reader) (string, error) // 저장된 파일 경로, error
+	ServeAsset(ctx echo.Context) error
+	CheckWritable() error // 저장소마다 파일을 만들고 지워 본다.
+}
+
+type storage struct {
+	dir    string
+	policy Policy
+}
+
+type assetHandler struct {
+	signingKey []byte
+	expire     time.Duration
+	storages   map[Kind]storage
+}
+
+// 서명 key 가 없거나 예전 예시 값이면 seller 서류 url 을 위조할 수 있으므로 시작하지 않는다.
+func NewAssetHandler(cfg *config.Config) (Asset, error) {
+	key := cfg.Asset.UrlSigningKey
+	if key == "" || key == placeholderSigningKey {
+		return nil, errors.New("asset url signing key is not configured")
+	}
+	expireSec := cfg.Asset.SignedUrlExpireSec
+	if expireSec <= 0 {
+		expireSec = defaultSignedUrlExpireSec
+	}
+
+	return &assetHandler{
+		signingKey: []byte(key),
+		expire:     time.Duration(expireSec) * time.Second,
+		storages: map[Kind]storage{
+			KindProfile: {dir: cfg.Asset.UserProfileImageSavePath, policy: PolicyPublic},
+			KindMedia:   {dir: cfg.Asset.MediaSavePath, policy: PolicyPublic},
+			KindSeller:  {dir: cfg.Api.SellerUploadFilePath, policy: PolicyOwner},
+		},
+	}, nil
+}
+
+func (a *assetHandler) AssetPolicy(kind Kind) Policy {
+	st, ok := a.storages[kind]
+	if !ok {
+		return PolicyOwner
+	}
+	return st.policy
+}
+
+func (a *assetHandler) AssetUrl(kind Kind, name string) string {
+	if a.AssetPolicy(kind) != PolicyPublic {
+		url, _ := a.SignedAssetUrl(kind, name)
+		return url
+	}
+	return fmt.Sprintf("/assets/%s/%s", kind, name)
+}
+
+func (a *assetHandler) SignedAssetUrl(kind Kind, name string) (string, time.Time) {
+	expires := time.Now().Add(a.expire)
+	return fmt.Sprintf("/assets/%s/%s?%s=%d&%s=%s",
+		kind, name,
+		paramExpires, expires.Unix(),
+		paramSignature, a.sign(kind, name, expires.Unix())), expires
+}
+
+func (a *assetHandler) SaveAsset(kind Kind, name string, src io.Reader) (string, error) {
+	filePath, err := a.path(kind, name)
+	if err != nil {
+		return "", err
+	}
+	if a.AssetPolicy(kind) == PolicyPublic && !publicExtensions[strings.ToLower(filepath.Ext(name))] {
+		return "", ErrUnsupportedType
+	}
+
+	dst, err := os.Create(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+func (a *assetHandler) CheckWritable() error {
+	for kind, st := range a.storages {
+		f, err := ioutil.TempFile(st.dir, ".ready-")
+		if err != nil {
+			return errors.New(fmt.Sprintf("%s storage is not writable. err: %s", kind, err))
+		}
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			return errors.New(fmt.Sprintf("%s storage cleanup failed. err: %s", kind, err))
+		}
+	}
+	return nil
+}
+
+func (a *assetHandler) ServeAsset(ctx echo.Context) error {
+	kind := Kind(ctx.Param(paramKind))
+	name := ctx.Param("*")
+	filePath, err := a.path(kind, name)
+	if err != nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	// 확장자 검사 전에 저장된 파일도 있다.
+	if a.AssetPolicy(kind) == PolicyPublic && !publicExtensions[strings.ToLower(filepath.Ext(name))] {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	ctx.Response().Header().Set("X-Content-Type-Options", "nosniff")
+
+	if a.AssetPolicy(kind) != PolicyPublic {
+		expires, err := strconv.ParseInt(ctx.QueryParam(paramExpires), 10, 64)
+		if err != nil {
+			return ctx.NoContent(http.StatusForbidden)
+		}
+		if time.Now().Unix() > expires {
+			return ctx.NoContent(http.StatusForbidden)
+		}
+		expected := a.sign(kind, name, expires)
+		if !hmac.Equal([]byte(expected), []byte(ctx.QueryParam(paramSignature))) {
+			log.Warning("asset signature mismatch. kind: ", kind, ", name: ", name)
+			return ctx.NoContent(http.StatusForbidden)
+		}
+		ctx.Response().Header().Set("Cache-Control", "private, no-store")
+	}
+
+	if _, err := os.Stat(filePath); err != nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	return ctx.File(filePath)
+}
+
+// kind 별 저장 위치의 파일 경로. 하위 디렉토리나 상위 디렉토리 접근은 허용하지 않는다.
+func (a *assetHandler) path(kind Kind, name string) (string, error) {
+	st, ok := a.storages[kind]
+	if !ok {
+		return "", errors.New("unknown asset kind")
+	}
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", errors.New("invalid asset name")
+	}
+	return filepath.Join(st.dir, name), nil
+}
+
+func (a *assetHandler) sign(kind Kind, name string, expires int64) string {
+	mac := hmac.New(sha256.New, a.signingKey)
+	mac.Write([]byte(fmt.Sprintf("%s/%s:%d", kind, name, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,168 @@
+package asset
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/labstack/echo/v4"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 저장소마다 임시 디렉토리를 쓴다. 끝나면 dir 을 지워야 한다.
+func newTestHandler(t *testing.T) (*assetHandler, string) {
+	dir, err := ioutil.TempDir("", "asset-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Asset.UrlSigningKey = "test-signing-key"
+	cfg.Asset.UserProfileImageSavePath = dir
+	cfg.Asset.MediaSavePath = filepath.Join(dir, "media")
+	cfg.Api.SellerUploadFilePath = filepath.Join(dir, "seller")
+	for _, sub := range []string{cfg.Asset.MediaSavePath, cfg.Api.SellerUploadFilePath} {
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler, err := NewAssetHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler.(*assetHandler), dir
+}
+
+// uri 로 ServeAsset 을 호출하고 응답을 돌려준다.
+func serve(t *testing.T, a *assetHandler, uri string) *httptest.ResponseRecorder {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(parsed.Path, "/assets/"), "/", 2)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, uri, nil), rec)
+	ctx.SetParamNames(paramKind, "*")
+	ctx.SetParamValues(parts[0], parts[1])
+	if err := a.ServeAsset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestNewAssetHandlerSigningKey(t *testing.T) {
+	for _, key := range []string{"", placeholderSigningKey} {
+		cfg := &config.Config{}
+		cfg.Asset.UrlSigningKey = key
+		if _, err := NewAssetHandler(cfg); err == nil {
+			t.Fatalf("key %q accepted", key)
+		}
+	}
+}
+
+func TestServeSignedAsset(t *testing.T) {
+	a, dir := newTestHandler(t)
+	defer os.RemoveAll(dir)
+	if _, err := a.SaveAsset(KindSeller, "seller.pdf", strings.NewReader("document")); err != nil {
+		t.Fatal(err)
+	}
+	signed, _ := a.SignedAssetUrl(KindSeller, "seller.pdf")
+	past := time.Now().Add(-time.Minute).Unix()
+	future := time.Now().Add(time.Minute).Unix()
+
+	tests := []struct {
+		name   string
+		uri    string
+		status int
+	}{
+		{name: "signed", uri: signed, status: http.StatusOK},
+		{name: "unsigned", uri: "/assets/seller/seller.pdf", status: http.StatusForbidden},
+		{name: "expired", uri: fmt.Sprintf("/assets/seller/seller.pdf?expires=%d&signature=%s",
+			past, a.sign(KindSeller, "seller.pdf", past)), status: http.StatusForbidden},
+		// 만료 시각을 늘리면 서명이 맞지 않는다.
+		{name: "extended", uri: fmt.Sprintf("/assets/seller/seller.pdf?expires=%d&signature=%s",
+			future+3600, a.sign(KindSeller, "seller.pdf", future)), status: http.StatusForbidden},
+		{name: "tampered", uri: fmt.Sprintf("/assets/seller/seller.pdf?expires=%d&signature=%s",
+			future, strings.Repeat("0", 64)), status: http.StatusForbidden},
+		// 다른 파일의 서명은 쓸 수 없다.
+		{name: "other name", uri: fmt.Sprintf("/assets/seller/seller.pdf?expires=%d&signature=%s",
+			future, a.sign(KindSeller, "other.pdf", future)), status: http.StatusForbidden},
+		{name: "other kind", uri: fmt.Sprintf("/assets/seller/seller.pdf?expires=%d&signature=%s",
+			future, a.sign(KindMedia, "seller.pdf", future)), status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(t, a, test.uri)
+			if rec.Code != test.status {
+				t.Fatalf("status: %d", rec.Code)
+			}
+			if test.status == http.StatusOK && rec.Header().Get("Cache-Control") != "private, no-store" {
+				t.Fatal("cache control: ", rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	a, dir := newTestHandler(t)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		kind Kind
+		name string
+		ok   bool
+	}{
+		{kind: KindMedia, name: "a.jpg", ok: true},
+		{kind: KindMedia, name: "", ok: false},
+		{kind: KindMedia, name: ".", ok: false},
+		{kind: KindMedia, name: "..", ok: false},
+		{kind: KindMedia, name: "../seller.pdf", ok: false},
+		{kind: KindMedia, name: "../seller/seller.pdf", ok: false},
+		{kind: KindMedia, name: "sub/a.jpg", ok: false},
+		{kind: KindMedia, name: "/etc/passwd", ok: false},
+		{kind: Kind("unknown"), name: "a.jpg", ok: false},
+	}
+	for _, test := range tests {
+		filePath, err := a.path(test.kind, test.name)
+		if (err == nil) != test.ok {
+			t.Fatalf("kind: %s, name: %q, err: %v", test.kind, test.name, err)
+		}
+		if err == nil && filepath.Dir(filePath) != a.storages[test.kind].dir {
+			t.Fatalf("kind: %s, name: %q, path: %s", test.kind, test.name, filePath)
+		}
+	}
+}
+
+// public asset 은 Content-Type 이 확장자로 정해지므로 이미지, 동영상만 저장하고 서빙한다.
+func TestPublicAssetExtension(t *testing.T) {
+	a, dir := newTestHandler(t)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.html", "a.svg", "a.HTM", "a"} {
+		if _, err := a.SaveAsset(KindMedia, name, strings.NewReader("<script></script>")); err != ErrUnsupportedType {
+			t.Fatalf("name: %s, err: %v", name, err)
+		}
+	}
+	if _, err := a.SaveAsset(KindMedia, "a.JPG", strings.NewReader("image")); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, a, "/assets/media/a.JPG")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("status: %d, header: %v", rec.Code, rec.Header())
+	}
+
+	// 확장자 검사 전에 저장된 파일
+	legacy := filepath.Join(a.storages[KindMedia].dir, "legacy.html")
+	if err := ioutil.WriteFile(legacy, []byte("<script></script>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(t, a, "/assets/media/legacy.html"); rec.Code != http.StatusNotFound {
+		t.Fatalf("legacy status: %d", rec.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package category
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// 전체 카테고리 tree
+	categoryTreeUrl = "/api/category"
+	// 카테고리와 하위 카테고리의 상품
+	categoryProductsUrl = "/api/category/:category_id/products"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(categoryTreeUrl, "GET"), getCategoryTree)
+	route.AddRoute(route.NewRouteType(categoryProductsUrl, "GET"), getCategoryProducts)
+}
+
+func getCategoryTree(ctx echo.Context) error {
+	resp := &protocol.CategoryTreeResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	tree, err := Load(customContext.Manager, timer)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Categories = tree.Build()
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getCategoryProducts(ctx echo.Context) error {
+	resp := &protocol.CategoryProductsResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	tree, err := Load(customContext.Manager, timer)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	categoryId := ctx.Param("category_id")
+	if _, ok := tree.Find(categoryId); !ok {
+		resp.Status = vcomError.CategoryNotFound
+		resp.Detail = vcomError.MessageCategoryNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	ids := tree.Descendants(categoryId)
+	page, count := customContext.Paging()
+	args := make([]interface{}, 0, len(ids)+2)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, count+1, page*count)
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := database.Select(customContext.Manager, timer, fmt.Sprintf(query.SelectProductsByCategories, placeholders), args...)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	products, hasNext, err := product.Page(customContext.Manager, timer, rows, count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Products = products
+	resp.Page = page
+	resp.HasNext = hasNext
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
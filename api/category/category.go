@@ -0,0 +1,186 @@
+package category
+
+import (
+	"errors"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+// slug 는 url 에 쓰이므로 소문자, 숫자, - 만 허용한다.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func ValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// 카테고리 노드 하나. 최상위 노드의 ParentId 는 빈 문자열이다.
+type Node struct {
+	CategoryId string
+	ParentId   string
+	Name       string
+	Slug       string
+	SortOrder  int
+}
+
+// 카테고리 전체. 노드 수가 많지 않으므로 한 번에 읽어 메모리에서 tree 를 만든다.
+type Tree struct {
+	nodes    map[string]Node
+	children map[string][]string
+}
+
+func Load(m database.Manager, timer *time.Timer) (*Tree, error) {
+	rows, err := database.Select(m, timer, query.SelectCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tree := &Tree{nodes: map[string]Node{}, children: map[string][]string{}}
+	for rows.Next() {
+		node := Node{}
+		if err := rows.Scan(&node.CategoryId, &node.ParentId, &node.Name, &node.Slug, &node.SortOrder); err != nil {
+			return nil, err
+		}
+		tree.nodes[node.CategoryId] = node
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, node := range tree.nodes {
+		tree.children[node.ParentId] = append(tree.children[node.ParentId], id)
+	}
+	for parentId := range tree.children {
+		ids := tree.children[parentId]
+		sort.Slice(ids, func(i, j int) bool {
+			left, right := tree.nodes[ids[i]], tree.nodes[ids[j]]
+			if left.SortOrder != right.SortOrder {
+				return left.SortOrder < right.SortOrder
+			}
+			return left.CategoryId < right.CategoryId
+		})
+	}
+	return tree, nil
+}
+
+func (t *Tree) Find(categoryId string) (Node, bool) {
+	node, ok := t.nodes[categoryId]
+	return node, ok
+}
+
+func (t *Tree) HasChildren(categoryId string) bool {
+	return len(t.children[categoryId]) > 0
+}
+
+// categoryId 가 아닌 다른 노드가 slug 를 쓰고 있는지 확인한다.
+func (t *Tree) SlugUsed(categoryId, slug string) bool {
+	for id, node := range t.nodes {
+		if node.Slug == slug && id != categoryId {
+			return true
+		}
+	}
+	return false
+}
+
+// categoryId 와 그 아래 모든 노드의 id
+func (t *Tree) Descendants(categoryId string) []string {
+	ids := []string{categoryId}
+	for index := 0; index < len(ids); index++ {
+		ids = append(ids, t.children[ids[index]]...)
+	}
+	return ids
+}
+
+// categoryId 의 parent 를 parentId 로 바꿔도 되는지 확인한다. 자기 자신이나 자손 아래로는 옮길 수 없다.
+func (t *Tree) ValidParent(categoryId, parentId string) bool {
+	if parentId == "" {
+		return true
+	}
+	if _, ok := t.nodes[parentId]; !ok {
+		return false
+	}
+	if categoryId == "" {
+		return true
+	}
+	for _, id := range t.Descendants(categoryId) {
+		if id == parentId {
+			return false
+		}
+	}
+	return true
+}
+
+// 최상위부터 sort_order 순으로 정렬된 tree
+func (t *Tree) Build() []protocol.CategoryNode {
+	return t.build("")
+}
+
+func (t *Tree) build(parentId string) []protocol.CategoryNode {
+	result := make([]protocol.CategoryNode, 0, len(t.children[parentId]))
+	for _, id := range t.children[parentId] {
+		node := t.nodes[id]
+		result = append(result, protocol.CategoryNode{
+			CategoryId: node.CategoryId,
+			ParentId:   node.ParentId,
+			Name:       node.Name,
+			Slug:       node.Slug,
+			SortOrder:  node.SortOrder,
+			Children:   t.build(id),
+		})
+	}
+	return result
+}
+
+// , 로 구분된 category id 목록을 읽는다. 빈 값과 중복은 빼고 모두 tree 에 있어야 한다.
+func ParseIds(t *Tree, categoryIds string) ([]string, error) {
+	result := make([]string, 0)
+	seen := map[string]bool{}
+	for _, id := range strings.Split(categoryIds, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, ok := t.nodes[id]; !ok {
+			return nil, ErrCategoryNotFound
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result, nil
+}
+
+// 상품을 카테고리 노드들에 연결한다.
+func Assign(m database.Manager, timer *time.Timer, productId string, categoryIds []string) error {
+	for _, categoryId := range categoryIds {
+		if _, err := database.Exec(m, timer, query.InsertProductCategory, productId, categoryId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 상품이 연결된 카테고리 id 목록
+func OfProduct(m database.Manager, timer *time.Timer, productId string) ([]string, error) {
+	rows, err := database.Select(m, timer, query.SelectProductCategories, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
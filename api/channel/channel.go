@@ -0,0 +1,202 @@
+package channel
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 판매자 채널 페이지. 로그인 없이 볼 수 있다.
+	channelUrl = "/api/channel/:name"
+	// 채널 팔로우(POST), 언팔로우(DELETE)
+	channelFollowUrl = "/api/channel/:name/follow"
+	// 팔로우한 채널들의 새 상품
+	followingFeedUrl = "/api/following"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(channelUrl, "GET"), getChannel)
+	route.AddRoute(route.NewRouteType(channelFollowUrl, "POST"), followChannel)
+	route.AddRoute(route.NewRouteType(channelFollowUrl, "DELETE"), unfollowChannel)
+	route.AddRoute(route.NewRouteType(followingFeedUrl, "GET"), getFollowingFeed)
+}
+
+func getChannel(ctx echo.Context) error {
+	resp := &protocol.ChannelResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	channelName := ctx.Param("name")
+	rows, err := database.Select(customContext.Manager, timer, query.SelectChannel, channelName)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	found := rows.Next()
+	if found {
+		err = rows.Scan(&resp.Channel.SellerId, &resp.Channel.ChannelName, &resp.Channel.ChannelUrl,
+			&resp.Channel.Description, &resp.Channel.FollowerCount)
+	}
+	rows.Close()
+	if err != nil {
+		customContext.Log.Error("scan failed. err: ", err)
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+	if !found {
+		resp.Status = vcomError.ChannelNotFound
+		resp.Detail = vcomError.MessageChannelNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	// 로그인한 사용자라면 팔로우 여부도 알려준다.
+	if sessionToken := ctx.QueryParam("session_token"); sessionToken != "" {
+		if uniqueId, err := customContext.ValidateSession(sessionToken, timer); err == nil {
+			rows, err := database.Select(customContext.Manager, timer, query.SelectChannelFollow, uniqueId, channelName)
+			if err != nil {
+				customContext.Log.Error("database operation failed. err: ", err)
+				return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+			}
+			resp.Channel.Following = rows.Next()
+			rows.Close()
+		}
+	}
+
+	page, count := customContext.Paging()
+	rows, err = database.Select(customContext.Manager, timer, query.SelectProductsBySeller,
+		resp.Channel.SellerId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	resp.Products, resp.HasNext, err = product.Page(customContext.Manager, timer, rows, count)
+	if err != nil {
+		customContext.Log.Error("product page failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func followChannel(ctx echo.Context) error {
+	return updateFollow(ctx, query.InsertChannelFollow)
+}
+
+func unfollowChannel(ctx echo.Context) error {
+	return updateFollow(ctx, query.DeleteChannelFollow)
+}
+
+func updateFollow(ctx echo.Context, followQuery string) error {
+	resp := &protocol.ChannelFollowResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	channelName := ctx.Param("name")
+	rows, err := database.Select(customContext.Manager, timer, query.SelectChannel, channelName)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	found := rows.Next()
+	rows.Close()
+	if !found {
+		resp.Status = vcomError.ChannelNotFound
+		resp.Detail = vcomError.MessageChannelNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	// 이미 팔로우 중이거나 팔로우하지 않은 채널이어도 성공으로 처리한다.
+	if _, err := database.Exec(customContext.Manager, timer, followQuery, uniqueId, channelName); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	rows, err = database.Select(customContext.Manager, timer, query.SelectChannelFollowerCount, channelName)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		rows.Scan(&resp.FollowerCount)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getFollowingFeed(ctx echo.Context) error {
+	resp := &protocol.ProductListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, query.SelectFollowingProducts,
+		uniqueId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	resp.Products, resp.HasNext, err = product.Page(customContext.Manager, timer, rows, count)
+	if err != nil {
+		customContext.Log.Error("product page failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,381 @@
+package claim
+
+import (
+	"encoding/json"
+	"github.com/4538cgy/backend-second/api/asset"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/media"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/order"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+const (
+	// 반품, 교환 요청(POST). 사진은 files 로 올린다. 내 요청 목록(GET). role=seller 면 판매자가 받은 요청
+	claimUrl = "/api/claim"
+	// 요청 정보와 상태 변경 기록
+	claimInfoUrl = "/api/claim/:claim_id"
+	// 판매자의 승인. 반품은 환불까지 진행되고, 환불이 실패했다면 다시 요청해서 재시도한다.
+	claimApproveUrl = "/api/claim/:claim_id/approve"
+	// 판매자의 거절. reason 이 필요하다.
+	claimRejectUrl = "/api/claim/:claim_id/reject"
+
+	roleSeller = "seller"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(claimUrl, "POST"), requestClaim)
+	route.AddRoute(route.NewRouteType(claimUrl, "GET"), getClaims)
+	route.AddRoute(route.NewRouteType(claimInfoUrl, "GET"), getClaim)
+	route.AddRoute(route.NewRouteType(claimApproveUrl, "POST"), approveClaim)
+	route.AddRoute(route.NewRouteType(claimRejectUrl, "POST"), rejectClaim)
+}
+
+func requestClaim(ctx echo.Context) error {
+	resp := &protocol.ClaimCreateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	kind := ctx.FormValue("kind")
+	reason := ctx.FormValue("reason")
+	if (kind != KindReturn && kind != KindExchange) || reason == "" {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	// 배송 완료된 본인 주문 line 에 대해서만 요청할 수 있다.
+	line, err := order.FindLine(customContext.Manager, timer, ctx.FormValue("order_line_id"), uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if line == nil || line.Status != order.StatusDelivered {
+		resp.Status = vcomError.ClaimNotAllowed
+		resp.Detail = vcomError.MessageClaimNotAllowed
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+	open, err := HasOpenClaim(customContext.Manager, timer, line.OrderLineId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if open {
+		resp.Status = vcomError.ClaimNotAllowed
+		resp.Detail = vcomError.MessageClaimNotAllowed
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	// 사진은 없어도 된다.
+	files := make([]*multipart.FileHeader, 0)
+	if form, err := ctx.MultipartForm(); err == nil {
+		files = form.File["files"]
+	}
+	mediaIndices, err := media.Save(customContext.Asset, customContext.Manager, timer, files)
+	if err == media.ErrSaveFailed {
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageIOFailed
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+	if err == asset.ErrUnsupportedType {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	mediaInfoJson, err := json.Marshal(&mediaIndices)
+	if err != nil {
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	// 위의 확인 뒤에 같은 line 의 요청이 동시에 들어왔을 수 있다.
+	claimId := util.RandString()
+	res, err := database.Exec(customContext.Manager, timer, query.InsertClaim, claimId, line.OrderLineId,
+		line.OrderId, uniqueId, line.SellerId, kind, reason, string(mediaInfoJson), StatusRequested,
+		line.OrderLineId, StatusRejected)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		resp.Status = vcomError.ClaimNotAllowed
+		resp.Detail = vcomError.MessageClaimNotAllowed
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+	if err := Record(customContext.Manager, timer, claimId, StatusRequested, uniqueId, reason); err != nil {
+		customContext.Log.Error("claim history record failed. claim_id: ", claimId, ", err: ", err)
+	}
+
+	title := "반품 요청이 접수되었습니다."
+	if kind == KindExchange {
+		title = "교환 요청이 접수되었습니다."
+	}
+	notify(customContext, line.SellerId, notification.CategorySeller, title, reason, claimId)
+
+	resp.ClaimId = claimId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getClaims(ctx echo.Context) error {
+	resp := &protocol.ClaimListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	// 판매자는 처리할 요청을 먼저 본다.
+	listQuery := query.SelectClaimsByBuyer
+	if ctx.QueryParam("role") == roleSeller {
+		listQuery = query.SelectClaimsBySeller
+	}
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, listQuery, uniqueId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	resp.Claims, err = scanClaims(customContext.Manager, timer, rows)
+	if err != nil {
+		customContext.Log.Error("scan failed. err: ", err)
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+	if len(resp.Claims) > count {
+		resp.Claims = resp.Claims[:count]
+		resp.HasNext = true
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getClaim(ctx echo.Context) error {
+	resp := &protocol.ClaimResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	c, err := Find(customContext.Manager, timer, ctx.Param("claim_id"), uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if c == nil {
+		resp.Status = vcomError.ClaimNotFound
+		resp.Detail = vcomError.MessageClaimNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	history, err := History(customContext.Manager, timer, c.ClaimId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Claim = *c
+	resp.History = history
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func approveClaim(ctx echo.Context) error {
+	resp := &protocol.ClaimResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	c, status := validateSeller(customContext, &resp.BaseResponse, timer)
+	if c == nil {
+		return ctx.JSON(status, resp)
+	}
+
+	switch {
+	case Status(c.Status) == StatusRequested:
+		if err := Transition(customContext.Manager, timer, c.ClaimId, StatusRequested, StatusApproved, c.SellerId, ""); err != nil {
+			return ctx.JSON(setClaimError(&resp.BaseResponse, err), resp)
+		}
+		c.Status = int(StatusApproved)
+		if c.Kind == KindExchange {
+			notify(customContext, c.UniqueId, notification.CategoryOrder, "교환 요청이 승인되었습니다.", "", c.ClaimId)
+		}
+	case Status(c.Status) == StatusApproved && c.Kind == KindReturn:
+		// 이전 환불이 실패한 반품
+	default:
+		resp.Status = vcomError.ClaimInvalidStatus
+		resp.Detail = vcomError.MessageClaimInvalidStatus
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	if c.Kind == KindReturn {
+		if err := Refund(customContext.Manager, timer, customContext.Provider, c, c.SellerId); err != nil {
+			customContext.Log.Error("claim refund failed. claim_id: ", c.ClaimId, ", err: ", err)
+			if err == ErrInvalidStatus {
+				return ctx.JSON(setClaimError(&resp.BaseResponse, err), resp)
+			}
+			return ctx.JSON(order.SetPaymentError(&resp.BaseResponse, err), resp)
+		}
+		c.Status = int(StatusRefunded)
+		notify(customContext, c.UniqueId, notification.CategoryOrder, "반품 환불이 완료되었습니다.", "", c.ClaimId)
+	}
+
+	resp.Claim = *c
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func rejectClaim(ctx echo.Context) error {
+	resp := &protocol.ClaimResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	reason := ctx.FormValue("reason")
+	c, status := validateSeller(customContext, &resp.BaseResponse, timer)
+	if c == nil {
+		return ctx.JSON(status, resp)
+	}
+	if reason == "" {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	if err := Transition(customContext.Manager, timer, c.ClaimId, StatusRequested, StatusRejected, c.SellerId, reason); err != nil {
+		return ctx.JSON(setClaimError(&resp.BaseResponse, err), resp)
+	}
+	notify(customContext, c.UniqueId, notification.CategoryOrder, "반품, 교환 요청이 거절되었습니다.", reason, c.ClaimId)
+
+	c.Status = int(StatusRejected)
+	c.RejectReason = reason
+	resp.Claim = *c
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// session 의 사용자가 요청을 받은 판매자인지 확인한다. 실패하면 nil 과 http status code 를 돌려준다.
+func validateSeller(customContext *context.CustomContext, resp *protocol.BaseResponse, timer *time.Timer) (*protocol.ClaimInfo, int) {
+	uniqueId, err := customContext.ValidateSession(customContext.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return nil, http.StatusUnauthorized
+	}
+
+	c, err := Find(customContext.Manager, timer, customContext.Param("claim_id"), uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return nil, context.SetQueryError(resp, err)
+	}
+	if c == nil {
+		resp.Status = vcomError.ClaimNotFound
+		resp.Detail = vcomError.MessageClaimNotFound
+		return nil, http.StatusNotFound
+	}
+	if c.SellerId != uniqueId {
+		customContext.Log.Warning("claim permission denied. unique_id: ", uniqueId, ", claim_id: ", c.ClaimId)
+		resp.Status = vcomError.PermissionDenied
+		resp.Detail = vcomError.MessagePermissionDenied
+		return nil, http.StatusForbidden
+	}
+	return c, http.StatusOK
+}
+
+func setClaimError(resp *protocol.BaseResponse, err error) int {
+	if err == ErrInvalidStatus {
+		resp.Status = vcomError.ClaimInvalidStatus
+		resp.Detail = vcomError.MessageClaimInvalidStatus
+		return http.StatusConflict
+	}
+	log.Error("database operation failed. err: ", err)
+	return context.SetQueryError(resp, err)
+}
+
+func notify(customContext *context.CustomContext, uniqueId, category, title, body, claimId string) {
+	if err := customContext.Notify(notification.Notification{
+		UniqueId:    uniqueId,
+		Category:    category,
+		Title:       title,
+		Body:        body,
+		ReferenceId: claimId,
+	}); err != nil {
+		customContext.Log.Error("notify claim failed. claim_id: ", claimId, ", err: ", err)
+	}
+}
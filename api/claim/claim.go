@@ -0,0 +1,176 @@
+package claim
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/4538cgy/backend-second/api/media"
+	"github.com/4538cgy/backend-second/api/order"
+	"github.com/4538cgy/backend-second/api/payment"
+	"github.com/4538cgy/backend-second/api/settlement"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"time"
+)
+
+const (
+	KindReturn   = "return"   // 반품. 승인되면 환불한다.
+	KindExchange = "exchange" // 교환. 승인되면 판매자가 새 상품을 보낸다.
+)
+
+type Status int
+
+const (
+	StatusRequested = Status(0)
+	StatusApproved  = Status(1)
+	StatusRejected  = Status(2)
+	StatusRefunded  = Status(3) // 반품 환불 완료
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusRequested:
+		return "Requested"
+	case StatusApproved:
+		return "Approved"
+	case StatusRejected:
+		return "Rejected"
+	case StatusRefunded:
+		return "Refunded"
+	}
+	return "Unknown"
+}
+
+var ErrInvalidStatus = errors.New("invalid claim status")
+
+// 구매자 혹은 판매자의 요청 하나를 읽는다. 없으면 nil 을 돌려준다.
+func Find(m database.Manager, timer *time.Timer, claimId, uniqueId string) (*protocol.ClaimInfo, error) {
+	rows, err := database.Select(m, timer, query.SelectClaim, claimId, uniqueId, uniqueId)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := scanClaims(m, timer, rows)
+	if err != nil || len(claims) == 0 {
+		return nil, err
+	}
+	return &claims[0], nil
+}
+
+// claim row 들을 읽고 사진을 확장한다. rows 는 닫힌다.
+func scanClaims(m database.Manager, timer *time.Timer, rows *sql.Rows) ([]protocol.ClaimInfo, error) {
+	claims := make([]protocol.ClaimInfo, 0)
+	mediaIndices := make([][]string, 0)
+	for rows.Next() {
+		c := protocol.ClaimInfo{}
+		var mediaInfoJson string
+		if err := rows.Scan(&c.ClaimId, &c.OrderLineId, &c.OrderId, &c.UniqueId, &c.SellerId, &c.Kind,
+			&c.Reason, &mediaInfoJson, &c.Status, &c.RejectReason, &c.Created, &c.Updated); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		claims = append(claims, c)
+		mediaIndices = append(mediaIndices, media.ParseIndices(mediaInfoJson))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	all := make([]string, 0)
+	for _, ids := range mediaIndices {
+		all = append(all, ids...)
+	}
+	infos, err := media.Expand(m, timer, all)
+	if err != nil {
+		return nil, err
+	}
+	for index := range claims {
+		claims[index].Medias = media.Pick(infos, mediaIndices[index])
+	}
+	return claims, nil
+}
+
+// 주문 line 에 거절되지 않은 요청이 있는지 확인한다.
+func HasOpenClaim(m database.Manager, timer *time.Timer, orderLineId string) (bool, error) {
+	rows, err := database.Select(m, timer, query.SelectOpenClaimByOrderLine, orderLineId, StatusRejected)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// status 를 from 에서 to 로 바꾸고 기록을 남긴다. 이미 다른 status 라면 ErrInvalidStatus 이다.
+func Transition(m database.Manager, timer *time.Timer, claimId string, from, to Status, actorId, note string) error {
+	rejectReason := ""
+	if to == StatusRejected {
+		rejectReason = note
+	}
+	res, err := database.Exec(m, timer, query.UpdateClaimStatus, to, rejectReason, claimId, from)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrInvalidStatus
+	}
+	return Record(m, timer, claimId, to, actorId, note)
+}
+
+func Record(m database.Manager, timer *time.Timer, claimId string, status Status, actorId, note string) error {
+	_, err := database.Exec(m, timer, query.InsertClaimHistory, util.RandString(), claimId, status, actorId, note)
+	return err
+}
+
+func History(m database.Manager, timer *time.Timer, claimId string) ([]protocol.ClaimHistoryInfo, error) {
+	rows, err := database.Select(m, timer, query.SelectClaimHistory, claimId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]protocol.ClaimHistoryInfo, 0)
+	for rows.Next() {
+		h := protocol.ClaimHistoryInfo{}
+		if err := rows.Scan(&h.Status, &h.ActorId, &h.Note, &h.Created); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// 주문 line 의 반품 환불 key. 같은 line 에 요청이 여러 개 승인되어도 환불과 ledger 정정은 한 번만 일어난다.
+func refundKey(orderLineId string) string {
+	return "return:" + orderLineId
+}
+
+// 승인된 반품을 환불한다. 구매자가 낸 금액을 PG 로 돌려주고 주문 line 을 반품 완료로 바꾼 뒤
+// 판매자 ledger 에서 판매 금액을 되돌린다. 중간에 실패하면 같은 요청으로 다시 시도할 수 있다.
+func Refund(m database.Manager, timer *time.Timer, provider payment.Provider, c *protocol.ClaimInfo, actorId string) error {
+	line, err := order.FindSellerLine(m, timer, c.OrderLineId, c.SellerId)
+	if err != nil {
+		return err
+	}
+	// 반품 완료된 line 은 이전 환불을 다시 시도하는 경우다.
+	if line == nil || (line.Status != order.StatusDelivered && line.Status != order.StatusReturned) {
+		return ErrInvalidStatus
+	}
+
+	key := refundKey(line.OrderLineId)
+	if err := payment.Refund(m, timer, provider, line.OrderId, key, int(line.PaidAmount()), "반품: "+c.Reason); err != nil {
+		return err
+	}
+	if _, err := database.Exec(m, timer, query.UpdateOrderLineReturned,
+		order.StatusReturned, line.OrderLineId, order.StatusDelivered); err != nil {
+		return err
+	}
+	if err := order.SyncStatus(m, timer, line.OrderId); err != nil {
+		log.Error("order status sync failed. order_id: ", line.OrderId, ", err: ", err)
+	}
+	if err := settlement.Reverse(m, timer, line.SellerId, line.OrderLineId, key, line.SaleAmount()); err != nil {
+		return err
+	}
+	return Transition(m, timer, c.ClaimId, StatusApproved, StatusRefunded, actorId, "")
+}
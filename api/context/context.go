@@ -1,10 +1,23 @@
 package context
 
 import (
+	"github.com/4538cgy/backend-second/api/alert"
+	"github.com/4538cgy/backend-second/api/asset"
+	"github.com/4538cgy/backend-second/api/engagement"
 	"github.com/4538cgy/backend-second/api/firebase"
+	"github.com/4538cgy/backend-second/api/inventory"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/payment"
 	"github.com/4538cgy/backend-second/api/session"
 	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/metrics"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/validation"
 	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
 )
 
 type CustomContext struct {
@@ -12,4 +25,63 @@ type CustomContext struct {
 	database.Manager
 	firebase.Firebase
 	session.Session
+	asset.Asset
+	validation.Verifier
+	engagement.Engagement
+	notification.Notifier
+	payment.Provider
+	inventory.Inventory
+	alert.Watcher
+	// 요청 id 가 붙은 logger
+	Log *log.Entry
+}
+
+// 응답 status 가 타임아웃이면 metrics 에 남긴다. 응답을 만드는 handler 는 그대로 ctx.JSON 을 쓴다.
+func (c *CustomContext) JSON(code int, i interface{}) error {
+	if resp, ok := i.(interface{ GetStatus() protocol.Code }); ok {
+		switch status := resp.GetStatus(); status {
+		case vcomError.ApiOperationRequestTimeout, vcomError.ApiOperationResponseTimeout:
+			metrics.CountTimeout(int(status))
+		}
+	}
+	return c.Context.JSON(code, i)
+}
+
+// database.Select, database.Exec 실패를 응답 status 로 변환한다. http status code 를 돌려준다.
+func SetQueryError(resp *protocol.BaseResponse, err error) int {
+	switch err {
+	case database.ErrQueryRequestTimeout:
+		resp.Status = vcomError.ApiOperationRequestTimeout
+		resp.Detail = vcomError.MessageOperationTimeout
+	case database.ErrQueryResponseTimeout:
+		resp.Status = vcomError.ApiOperationResponseTimeout
+		resp.Detail = vcomError.MessageOperationTimeout
+	default:
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+	}
+	return http.StatusInternalServerError
+}
+
+const (
+	paramPage        = "page"
+	paramCount       = "count"
+	defaultPageCount = 20
+	maxPageCount     = 100
+)
+
+// page, count query param 을 읽는다. page 는 0 부터 시작하고 잘못된 값은 기본값으로 대체한다.
+func (c *CustomContext) Paging() (int, int) {
+	page, err := strconv.Atoi(c.QueryParam(paramPage))
+	if err != nil || page < 0 {
+		page = 0
+	}
+	count, err := strconv.Atoi(c.QueryParam(paramCount))
+	if err != nil || count <= 0 {
+		count = defaultPageCount
+	}
+	if count > maxPageCount {
+		count = maxPageCount
+	}
+	return page, count
 }
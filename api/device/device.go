@@ -0,0 +1,106 @@
+package device
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// FCM device token 등록(POST), 해제(DELETE). 로그아웃할 때 해제한다.
+	deviceTokenUrl = "/api/device/token"
+
+	PlatformIos     = "ios"
+	PlatformAndroid = "android"
+
+	maxTokenLength = 4096
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(deviceTokenUrl, "POST"), registerToken)
+	route.AddRoute(route.NewRouteType(deviceTokenUrl, "DELETE"), unregisterToken)
+}
+
+func registerToken(ctx echo.Context) error {
+	resp := &protocol.DeviceTokenResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	token := ctx.FormValue("token")
+	platform := ctx.FormValue("platform")
+	if token == "" || len(token) > maxTokenLength || (platform != PlatformIos && platform != PlatformAndroid) {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.UpsertDeviceToken, token, uniqueId, platform); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 등록하지 않았거나 이미 해제된 token 이어도 성공으로 처리한다.
+func unregisterToken(ctx echo.Context) error {
+	resp := &protocol.DeviceTokenResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	token := ctx.FormValue("token")
+	if token == "" {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteDeviceToken, token, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
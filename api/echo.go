@@ -2,27 +2,57 @@ package api
 
 import (
 	"fmt"
+	_ "github.com/4538cgy/backend-second/api/address"
+	_ "github.com/4538cgy/backend-second/api/admin"
+	"github.com/4538cgy/backend-second/api/alert"
+	"github.com/4538cgy/backend-second/api/asset"
 	_ "github.com/4538cgy/backend-second/api/auth"
+	_ "github.com/4538cgy/backend-second/api/category"
+	_ "github.com/4538cgy/backend-second/api/channel"
+	_ "github.com/4538cgy/backend-second/api/claim"
 	"github.com/4538cgy/backend-second/api/context"
+	_ "github.com/4538cgy/backend-second/api/device"
+	"github.com/4538cgy/backend-second/api/engagement"
+	_ "github.com/4538cgy/backend-second/api/feed"
 	"github.com/4538cgy/backend-second/api/firebase"
+	_ "github.com/4538cgy/backend-second/api/inbox"
+	"github.com/4538cgy/backend-second/api/inventory"
+	_ "github.com/4538cgy/backend-second/api/live"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/order"
+	"github.com/4538cgy/backend-second/api/payment"
+	_ "github.com/4538cgy/backend-second/api/product"
+	_ "github.com/4538cgy/backend-second/api/promotion"
+	_ "github.com/4538cgy/backend-second/api/purchase"
+	_ "github.com/4538cgy/backend-second/api/review"
 	"github.com/4538cgy/backend-second/api/route"
 	_ "github.com/4538cgy/backend-second/api/sale"
+	_ "github.com/4538cgy/backend-second/api/search"
 	_ "github.com/4538cgy/backend-second/api/seller"
 	"github.com/4538cgy/backend-second/api/session"
+	"github.com/4538cgy/backend-second/api/settlement"
+	"github.com/4538cgy/backend-second/api/shipment"
+	_ "github.com/4538cgy/backend-second/api/stats"
 	_ "github.com/4538cgy/backend-second/api/user"
+	_ "github.com/4538cgy/backend-second/api/wishlist"
 	"github.com/4538cgy/backend-second/config"
 	"github.com/4538cgy/backend-second/database"
 	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/metrics"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/4538cgy/backend-second/validation"
 	"github.com/labstack/echo/v4"
-	"net/http"
+	"github.com/labstack/echo/v4/middleware"
+	"time"
 )
 
 type apiManager struct {
-	echo        *echo.Echo
-	config      *config.Config
-	dbManager   database.Manager
-	fbManager   firebase.Firebase
-	sessManager session.Session
+	echo         *echo.Echo
+	config       *config.Config
+	dbManager    database.Manager
+	fbManager    firebase.Firebase
+	sessManager  session.Session
+	assetHandler asset.Asset
 }
 
 func StartAPI(cfg *config.Config, dbManager database.Manager) {
@@ -31,25 +61,86 @@ func StartAPI(cfg *config.Config, dbManager database.Manager) {
 		log.Fatal("firebase manager create failed!!! ", err.Error())
 	}
 
+	verifier, err := validation.NewVerifier(cfg)
+	if err != nil {
+		log.Fatal("seller verifier create failed!!! ", err.Error())
+	}
+
+	paymentProvider, err := payment.NewProvider(cfg)
+	if err != nil {
+		log.Fatal("payment provider create failed!!! ", err.Error())
+	}
+
 	sessionHandler := session.NewSessionHandler(dbManager)
+	assetHandler, err := asset.NewAssetHandler(cfg)
+	if err != nil {
+		log.Fatal("asset handler create failed!!! ", err.Error())
+	}
+
+	settlement.NewSettlementHandler(cfg, dbManager).Start()
+
+	engagementHandler := engagement.NewEngagementHandler(cfg, dbManager)
+	engagementHandler.Start()
+
+	notificationHandler, err := notification.NewNotificationHandler(cfg, dbManager, fbManager)
+	if err != nil {
+		log.Fatal("notification handler create failed!!! ", err.Error())
+	}
+	notificationHandler.Start()
+
+	shipmentHandler, err := shipment.NewShipmentHandler(cfg, dbManager, notificationHandler)
+	if err != nil {
+		log.Fatal("shipment handler create failed!!! ", err.Error())
+	}
+	shipmentHandler.Start()
+
+	alertHandler := alert.NewAlertHandler(dbManager, notificationHandler)
+	alertHandler.Start()
+
+	inventoryHandler := inventory.NewInventoryHandler(cfg, dbManager, order.ExpireFunc(dbManager, notificationHandler, alertHandler))
+	inventoryHandler.Start()
 
 	api := &apiManager{
-		echo:        echo.New(),
-		config:      cfg,
-		dbManager:   dbManager,
-		fbManager:   fbManager,
-		sessManager: sessionHandler,
+		echo:         echo.New(),
+		config:       cfg,
+		dbManager:    dbManager,
+		fbManager:    fbManager,
+		sessManager:  sessionHandler,
+		assetHandler: assetHandler,
 	}
 
+	// 요청 header 의 X-Request-ID 를 그대로 쓰고, 없으면 만들어서 응답 header 에 넣는다.
+	api.echo.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: util.RandString,
+	}))
 	api.echo.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			requestId := c.Response().Header().Get(echo.HeaderXRequestID)
 			cc := &context.CustomContext{
-				Context:  c,
-				Manager:  dbManager,
-				Firebase: fbManager,
-				Session:  sessionHandler,
+				Context:    c,
+				Manager:    database.WithRequestId(dbManager, requestId),
+				Firebase:   fbManager,
+				Session:    sessionHandler,
+				Asset:      assetHandler,
+				Verifier:   verifier,
+				Engagement: engagementHandler,
+				Notifier:   notificationHandler,
+				Provider:   paymentProvider,
+				Inventory:  inventoryHandler,
+				Watcher:    alertHandler,
+				Log:        log.WithRequestId(requestId),
 			}
-			return next(cc)
+			start := time.Now()
+			err := next(cc)
+			if err != nil {
+				c.Error(err)
+			}
+			elapsed := time.Since(start)
+			metrics.ObserveRequest(c.Request().Method, c.Path(), c.Response().Status, elapsed)
+			// query string 에 session_token 이 실려 오므로 uri 대신 route pattern 을 남긴다.
+			cc.Log.WithField(log.FieldDuration, elapsed.Milliseconds()).
+				Infof("%s %s %d", c.Request().Method, c.Path(), c.Response().Status)
+			return nil
 		}
 	})
 
@@ -67,22 +158,35 @@ func StartAPI(cfg *config.Config, dbManager database.Manager) {
 		case "DELETE":
 			log.Infof("DELETE: %s", routeUri)
 			api.echo.DELETE(routeUri, fun)
+		case "PATCH":
+			log.Infof("PATCH: %s", routeUri)
+			api.echo.PATCH(routeUri, fun)
 		default:
 			log.Panic("wrong route types: ", routeType)
 		}
 		return true
 	})
 
-	api.echo.GET("/health", func(context echo.Context) error {
-		return context.String(http.StatusOK, "")
-	})
+	// /health 는 이전 배포 설정을 위해 남겨 둔다.
+	api.echo.GET("/health", api.live)
+	api.echo.GET(healthLiveUri, api.live)
+	api.echo.GET(healthReadyUri, api.ready)
 
-	fs := http.FileServer(http.Dir(cfg.Asset.UserProfileImageSavePath))
-	api.echo.GET("/assets/profile/*", echo.WrapHandler(http.StripPrefix("/assets/profile", fs)))
+	// profile, media 는 public. seller 서류는 서명된 url 로만 접근 가능.
+	api.echo.GET(asset.AssetUri, assetHandler.ServeAsset)
 
 	go func() {
 		address := fmt.Sprintf(":%d", api.config.Echo.Port)
 		log.Fatal(api.echo.Start(address))
 	}()
 
+	// route, 에러 코드별 수치가 드러나므로 API port 가 아닌 내부 주소에서만 연다.
+	if api.config.Metrics.Address == "" {
+		log.Warning("metrics address is empty. /metrics is disabled.")
+		return
+	}
+	go func() {
+		log.Fatal(metrics.Serve(api.config.Metrics.Address))
+	}()
+
 }
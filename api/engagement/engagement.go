@@ -0,0 +1,197 @@
+package engagement
+
+import (
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"sync"
+	"time"
+)
+
+// 영상 이벤트 종류
+const (
+	EventImpression = "impression" // 피드 등에서 화면에 보임
+	EventPlay       = "play"       // 재생 시작
+	EventWatch      = "watch"      // 시청 구간 도달. milestone 이 필요하다.
+	EventTap        = "tap"        // 영상에서 상품 페이지로 이동
+)
+
+const (
+	hourFormat              = "2006-01-02 15:00:00"
+	flushTimeout            = 30 * time.Second
+	defaultFlushIntervalSec = 10
+	defaultMaxPendingKeys   = 5000
+	defaultMaxUserEvents    = 1000
+)
+
+// 시청 구간. 영상 길이의 % 이다.
+var milestones = []int{25, 50, 75, 100}
+
+type Engagement interface {
+	Start()
+	// 상품에 속한 영상인지는 호출한 쪽에서 확인한다. 받아들인 이벤트 수를 돌려준다.
+	RecordEvents(uniqueId string, events []types.VideoEvent) int
+}
+
+type counterKey struct {
+	videoId   string
+	productId string
+	hour      string
+}
+
+type counter struct {
+	impressions int64
+	plays       int64
+	watches     [4]int64 // milestones 순서
+	taps        int64
+}
+
+// 이벤트마다 db 에 쓰지 않고 영상, 상품, 시간 단위로 메모리에 더해 두었다가 주기적으로 한 번에 쓴다.
+type engagementHandler struct {
+	dbManager      database.Manager
+	flushInterval  time.Duration
+	maxPendingKeys int
+	maxUserEvents  int
+
+	lock    sync.Mutex
+	pending map[counterKey]*counter
+	flushCh chan struct{}
+	// userHour 동안 사용자별로 받아들인 이벤트 수
+	userHour   string
+	userEvents map[string]int
+}
+
+func NewEngagementHandler(cfg *config.Config, dbManager database.Manager) Engagement {
+	flushIntervalSec := cfg.Engagement.FlushIntervalSec
+	if flushIntervalSec <= 0 {
+		flushIntervalSec = defaultFlushIntervalSec
+	}
+	maxPendingKeys := cfg.Engagement.MaxPendingKeys
+	if maxPendingKeys <= 0 {
+		maxPendingKeys = defaultMaxPendingKeys
+	}
+	maxUserEvents := cfg.Engagement.MaxUserEventsPerHour
+	if maxUserEvents <= 0 {
+		maxUserEvents = defaultMaxUserEvents
+	}
+	return &engagementHandler{
+		dbManager:      dbManager,
+		flushInterval:  time.Duration(flushIntervalSec) * time.Second,
+		maxPendingKeys: maxPendingKeys,
+		maxUserEvents:  maxUserEvents,
+		pending:        map[counterKey]*counter{},
+		flushCh:        make(chan struct{}, 1),
+		userEvents:     map[string]int{},
+	}
+}
+
+func (e *engagementHandler) Start() {
+	go func() {
+		ticker := time.NewTicker(e.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-e.flushCh:
+			}
+			e.flush()
+		}
+	}()
+}
+
+func milestoneIndex(milestone int) int {
+	for index, m := range milestones {
+		if m == milestone {
+			return index
+		}
+	}
+	return -1
+}
+
+func (e *engagementHandler) RecordEvents(uniqueId string, events []types.VideoEvent) int {
+	hour := time.Now().Format(hourFormat)
+
+	e.lock.Lock()
+	if hour != e.userHour {
+		e.userHour = hour
+		e.userEvents = map[string]int{}
+	}
+	remaining := e.maxUserEvents - e.userEvents[uniqueId]
+	accepted := 0
+	for _, event := range events {
+		if accepted >= remaining {
+			break
+		}
+		if event.VideoId == "" || event.ProductId == "" {
+			continue
+		}
+		key := counterKey{videoId: event.VideoId, productId: event.ProductId, hour: hour}
+		c, ok := e.pending[key]
+		if !ok {
+			// 쓰기 전에는 메모리가 늘지 않도록 새 key 는 버린다.
+			if len(e.pending) >= e.maxPendingKeys {
+				continue
+			}
+			c = &counter{}
+		}
+		switch event.Type {
+		case EventImpression:
+			c.impressions++
+		case EventPlay:
+			c.plays++
+		case EventTap:
+			c.taps++
+		case EventWatch:
+			index := milestoneIndex(event.Milestone)
+			if index < 0 {
+				continue
+			}
+			c.watches[index]++
+		default:
+			continue
+		}
+		e.pending[key] = c
+		accepted++
+	}
+	e.userEvents[uniqueId] += accepted
+	full := len(e.pending) >= e.maxPendingKeys
+	e.lock.Unlock()
+
+	if full {
+		select {
+		case e.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return accepted
+}
+
+// 모아둔 값을 video_stat_hourly 에 더한다. 영상이 상품에 속하지 않으면 버려진다.
+// 통계는 정확도보다 가용성이 중요하므로 실패한 값은 다시 시도하지 않는다.
+func (e *engagementHandler) flush() {
+	e.lock.Lock()
+	pending := e.pending
+	e.pending = map[counterKey]*counter{}
+	e.lock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	timer := time.NewTimer(flushTimeout)
+	defer timer.Stop()
+
+	failed := 0
+	for key, c := range pending {
+		if _, err := database.Exec(e.dbManager, timer, query.UpsertVideoStat,
+			key.videoId, key.hour, c.impressions, c.plays,
+			c.watches[0], c.watches[1], c.watches[2], c.watches[3], c.taps,
+			key.productId, key.videoId); err != nil {
+			failed++
+			log.Error("video stat flush failed. video_id: ", key.videoId, ", err: ", err)
+		}
+	}
+	log.Debug("video stat flushed. keys: ", len(pending), ", failed: ", failed)
+}
@@ -0,0 +1,82 @@
+package engagement
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/config"
+	"testing"
+)
+
+func newTestHandler(maxPendingKeys, maxUserEvents int) *engagementHandler {
+	cfg := &config.Config{}
+	cfg.Engagement.MaxPendingKeys = maxPendingKeys
+	cfg.Engagement.MaxUserEventsPerHour = maxUserEvents
+	return NewEngagementHandler(cfg, nil).(*engagementHandler)
+}
+
+func impressions(count int, productId string) []types.VideoEvent {
+	events := make([]types.VideoEvent, 0, count)
+	for index := 0; index < count; index++ {
+		events = append(events, types.VideoEvent{VideoId: fmt.Sprint("video", index), ProductId: productId, Type: EventImpression})
+	}
+	return events
+}
+
+func TestRecordEventsCounts(t *testing.T) {
+	handler := newTestHandler(100, 100)
+	events := []types.VideoEvent{
+		{VideoId: "video", ProductId: "product", Type: EventImpression},
+		{VideoId: "video", ProductId: "product", Type: EventPlay},
+		{VideoId: "video", ProductId: "product", Type: EventWatch, Milestone: 50},
+		{VideoId: "video", ProductId: "product", Type: EventWatch, Milestone: 30},
+		{VideoId: "video", ProductId: "product", Type: "unknown"},
+		{VideoId: "", ProductId: "product", Type: EventTap},
+	}
+	if accepted := handler.RecordEvents("user", events); accepted != 3 {
+		t.Fatal("accepted: ", accepted)
+	}
+	for key, c := range handler.pending {
+		if key.videoId != "video" || c.impressions != 1 || c.plays != 1 || c.watches[1] != 1 || c.taps != 0 {
+			t.Fatalf("key: %+v, counter: %+v", key, c)
+		}
+	}
+}
+
+// 쓰기 전에는 새 key 를 더 만들지 않는다. 이미 있는 key 의 값은 계속 더한다.
+func TestRecordEventsPendingKeyLimit(t *testing.T) {
+	handler := newTestHandler(3, 100)
+	if accepted := handler.RecordEvents("user", impressions(5, "product")); accepted != 3 {
+		t.Fatal("accepted: ", accepted)
+	}
+	if len(handler.pending) != 3 {
+		t.Fatal("pending keys: ", len(handler.pending))
+	}
+	if accepted := handler.RecordEvents("other", impressions(1, "product")); accepted != 1 {
+		t.Fatal("accepted existing key: ", accepted)
+	}
+	if len(handler.pending) != 3 {
+		t.Fatal("pending keys: ", len(handler.pending))
+	}
+}
+
+func TestRecordEventsUserLimit(t *testing.T) {
+	handler := newTestHandler(100, 10)
+	if accepted := handler.RecordEvents("user", impressions(6, "a")); accepted != 6 {
+		t.Fatal("accepted: ", accepted)
+	}
+	if accepted := handler.RecordEvents("user", impressions(6, "b")); accepted != 4 {
+		t.Fatal("accepted over limit: ", accepted)
+	}
+	if accepted := handler.RecordEvents("user", impressions(1, "c")); accepted != 0 {
+		t.Fatal("accepted after limit: ", accepted)
+	}
+	if accepted := handler.RecordEvents("other", impressions(6, "c")); accepted != 6 {
+		t.Fatal("accepted other user: ", accepted)
+	}
+
+	// 시간이 바뀌면 다시 받는다.
+	handler.userHour = ""
+	if accepted := handler.RecordEvents("user", impressions(1, "d")); accepted != 1 {
+		t.Fatal("accepted next hour: ", accepted)
+	}
+}
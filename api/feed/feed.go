@@ -0,0 +1,206 @@
+package feed
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/media"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// 홈 화면의 영상 피드. 첫 page 는 cursor 없이, 다음 page 는 응답의 next_cursor 로 요청한다.
+	// session_token 이 있으면 팔로우한 채널의 상품을 먼저 보여준다.
+	feedUrl = "/api/feed"
+
+	// 정렬 대상이 되는 최근 상품 수
+	candidateLimit = 500
+
+	datetimeFormat = "2006-01-02 15:04:05"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(feedUrl, "GET"), getFeed)
+}
+
+func getFeed(ctx echo.Context) error {
+	resp := &protocol.FeedResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId := ""
+	if token := ctx.QueryParam("session_token"); token != "" {
+		var err error
+		if uniqueId, err = customContext.ValidateSession(token, timer); err != nil {
+			customContext.Log.Error("validate session failed. err: ", err)
+			resp.Status = vcomError.SessionValidationFailed
+			resp.Detail = vcomError.MessageInvalidSession
+			return ctx.JSON(http.StatusUnauthorized, resp)
+		}
+	}
+
+	now := time.Now()
+	asOf := now.Truncate(time.Second)
+	var previous *cursor
+	if value := ctx.QueryParam("cursor"); value != "" {
+		c, found := decodeCursor(value)
+		if !found || now.Sub(c.asOf) > cursorTTL {
+			resp.Status = vcomError.FeedCursorExpired
+			resp.Detail = vcomError.MessageFeedCursorExpired
+			return ctx.JSON(http.StatusGone, resp)
+		}
+		asOf = c.asOf
+		previous = &c
+	}
+
+	candidates, err := loadCandidates(customContext.Manager, timer, uniqueId, asOf)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	ranked := Rank(candidates)
+	if previous != nil {
+		ranked = after(ranked, previous.last)
+	}
+
+	_, count := customContext.Paging()
+	if len(ranked) > count {
+		ranked = ranked[:count]
+		resp.NextCursor = encodeCursor(cursor{asOf: asOf, last: ranked[count-1]})
+	}
+	ids := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		ids = append(ids, r.ProductId)
+	}
+
+	products, err := loadProducts(customContext.Manager, timer, ids)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Products = products
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// asOf 이전에 올라온 최근 상품 중 재생 가능한 영상이 하나라도 있는 상품
+func loadCandidates(m database.Manager, timer *time.Timer, uniqueId string, asOf time.Time) ([]Candidate, error) {
+	followed := map[string]bool{}
+	if uniqueId != "" {
+		rows, err := database.Select(m, timer, query.SelectFollowingSellers, uniqueId)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var sellerId string
+			if err := rows.Scan(&sellerId); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			followed[sellerId] = true
+		}
+		rows.Close()
+	}
+
+	rows, err := database.Select(m, timer, query.SelectFeedCandidates,
+		asOf.Format(datetimeFormat), asOf.Format(datetimeFormat), candidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]Candidate, 0)
+	videoIndices := make([][]string, 0)
+	allVideos := make([]string, 0)
+	for rows.Next() {
+		c := Candidate{}
+		var videoListJson string
+		if err := rows.Scan(&c.ProductId, &c.SellerId, &videoListJson, &c.AgeMinutes,
+			&c.ReviewStar, &c.ReviewCount, &c.Engagement, &c.Followers); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Followed = followed[c.SellerId]
+		indices := media.ParseIndices(videoListJson)
+		candidates = append(candidates, c)
+		videoIndices = append(videoIndices, indices)
+		allVideos = append(allVideos, indices...)
+	}
+	rows.Close()
+
+	infos, err := media.Expand(m, timer, allVideos)
+	if err != nil {
+		return nil, err
+	}
+	playable := make([]Candidate, 0, len(candidates))
+	for index, c := range candidates {
+		if len(serveReady(media.Pick(infos, videoIndices[index]))) > 0 {
+			playable = append(playable, c)
+		}
+	}
+	return playable, nil
+}
+
+// ids 순서대로 상품을 읽는다. 그 사이 삭제된 상품은 빠진다.
+func loadProducts(m database.Manager, timer *time.Timer, ids []string) ([]protocol.ProductInfo, error) {
+	if len(ids) == 0 {
+		return make([]protocol.ProductInfo, 0), nil
+	}
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := database.Select(m, timer, fmt.Sprintf(query.SelectProductsByIds, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	products, videoIndices, err := product.Scan(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := product.ExpandVideos(m, timer, products, videoIndices); err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]protocol.ProductInfo, len(products))
+	for _, p := range products {
+		p.Videos = serveReady(p.Videos)
+		byId[p.ProductId] = p
+	}
+	ordered := make([]protocol.ProductInfo, 0, len(ids))
+	for _, id := range ids {
+		if p, ok := byId[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered, nil
+}
+
+func serveReady(infos []types.MediaInfo) []types.MediaInfo {
+	ready := make([]types.MediaInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.ServeReady == 1 && info.Kind == types.VideoType.String() {
+			ready = append(ready, info)
+		}
+	}
+	return ready
+}
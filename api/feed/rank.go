@@ -0,0 +1,124 @@
+package feed
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 점수 가중치. 합이 1 이 되도록 맞추고 팔로우한 채널은 따로 더한다.
+const (
+	recencyWeight    = 0.4
+	reviewWeight     = 0.25
+	engagementWeight = 0.2
+	followerWeight   = 0.15
+	followedBonus    = 0.3
+
+	recencyHalfLifeMinutes = 48 * 60
+	reviewConfidentCount   = 10   // 리뷰가 이만큼 쌓이면 평점을 그대로 믿는다.
+	engagementSaturation   = 1000 // 이 이상은 같은 점수
+	followerSaturation     = 10000
+)
+
+// 피드 후보 상품 하나의 점수 재료
+type Candidate struct {
+	ProductId   string
+	SellerId    string
+	AgeMinutes  int64
+	ReviewStar  float64 // 평균 별점 0~5
+	ReviewCount int64
+	Engagement  int64 // 리뷰 도움돼요 수와 최근 영상 재생, 상품 이동 수
+	Followers   int64 // 판매자 채널 팔로워 수
+	Followed    bool  // 요청한 사용자가 팔로우한 채널
+}
+
+func saturate(value, limit int64) float64 {
+	if value <= 0 {
+		return 0
+	}
+	return math.Min(1, math.Log1p(float64(value))/math.Log1p(float64(limit)))
+}
+
+// 0 이상의 점수. 새 상품일수록, 평점과 반응이 좋을수록, 팔로우한 채널일수록 높다.
+func Score(c Candidate) float64 {
+	recency := math.Exp2(-float64(c.AgeMinutes) / recencyHalfLifeMinutes)
+	confidence := math.Min(1, float64(c.ReviewCount)/reviewConfidentCount)
+	review := c.ReviewStar / 5 * confidence
+
+	score := recencyWeight*recency +
+		reviewWeight*review +
+		engagementWeight*saturate(c.Engagement, engagementSaturation) +
+		followerWeight*saturate(c.Followers, followerSaturation)
+	if c.Followed {
+		score += followedBonus
+	}
+	return score
+}
+
+// 정렬된 상품 하나와 점수
+type Ranked struct {
+	ProductId string
+	Score     float64
+}
+
+// a 가 b 보다 앞에 오는가. 점수가 같으면 product id 순이라 항상 같은 결과가 나온다.
+func before(a, b Ranked) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.ProductId < b.ProductId
+}
+
+// 점수가 높은 순으로 정렬한다.
+func Rank(candidates []Candidate) []Ranked {
+	ranked := make([]Ranked, 0, len(candidates))
+	for _, c := range candidates {
+		ranked = append(ranked, Ranked{ProductId: c.ProductId, Score: Score(c)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return before(ranked[i], ranked[j])
+	})
+	return ranked
+}
+
+// 정렬된 목록에서 last 다음부터. last 가 목록에서 빠졌어도 그 자리 다음부터 이어진다.
+func after(ranked []Ranked, last Ranked) []Ranked {
+	index := sort.Search(len(ranked), func(i int) bool {
+		return before(last, ranked[i])
+	})
+	return ranked[index:]
+}
+
+// 다음 page 는 첫 page 의 기준 시각으로 다시 정렬한 뒤 이전 page 의 마지막 상품 다음부터 자른다.
+// 기준 시각 뒤에 올라온 상품은 빠지고 등록 후 지난 시간도 같으므로, 그 사이 반응이 바뀌지 않았다면
+// 같은 상품이 두 번 나오거나 빠지지 않는다. 서버에 저장하는 것이 없어 어느 instance 에서도 이어진다.
+type cursor struct {
+	asOf time.Time
+	last Ranked
+}
+
+const cursorTTL = 30 * time.Minute
+
+// cursor 는 "기준 시각(unix):점수:product id" 이다.
+func encodeCursor(c cursor) string {
+	return strconv.FormatInt(c.asOf.Unix(), 10) + ":" +
+		strconv.FormatFloat(c.last.Score, 'g', -1, 64) + ":" + c.last.ProductId
+}
+
+func decodeCursor(value string) (cursor, bool) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return cursor{}, false
+	}
+	asOf, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || asOf <= 0 {
+		return cursor{}, false
+	}
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+		return cursor{}, false
+	}
+	return cursor{asOf: time.Unix(asOf, 0), last: Ranked{ProductId: parts[2], Score: score}}, true
+}
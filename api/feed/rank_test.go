@@ -0,0 +1,148 @@
+package feed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	base := Candidate{ProductId: "p", AgeMinutes: 60, ReviewStar: 4, ReviewCount: 5, Engagement: 100, Followers: 100}
+	better := []struct {
+		name   string
+		modify func(c *Candidate)
+	}{
+		{"newer", func(c *Candidate) { c.AgeMinutes = 0 }},
+		{"higher star", func(c *Candidate) { c.ReviewStar = 5 }},
+		{"more reviews", func(c *Candidate) { c.ReviewCount = 10 }},
+		{"more engagement", func(c *Candidate) { c.Engagement = 500 }},
+		{"more followers", func(c *Candidate) { c.Followers = 5000 }},
+		{"followed", func(c *Candidate) { c.Followed = true }},
+	}
+	for _, test := range better {
+		c := base
+		test.modify(&c)
+		if Score(c) <= Score(base) {
+			t.Errorf("%s: %f <= %f", test.name, Score(c), Score(base))
+		}
+	}
+
+	// 포화된 값은 더 늘어도 같은 점수
+	saturated := base
+	saturated.Engagement = engagementSaturation
+	more := saturated
+	more.Engagement = engagementSaturation * 10
+	if Score(saturated) != Score(more) {
+		t.Errorf("saturated: %f != %f", Score(saturated), Score(more))
+	}
+	if score := Score(Candidate{AgeMinutes: 1 << 40, Engagement: -5}); score < 0 {
+		t.Errorf("negative score: %f", score)
+	}
+}
+
+func TestRankTieBreak(t *testing.T) {
+	candidates := []Candidate{
+		{ProductId: "c", AgeMinutes: 10},
+		{ProductId: "a", AgeMinutes: 10},
+		{ProductId: "new", AgeMinutes: 0},
+		{ProductId: "b", AgeMinutes: 10},
+	}
+	want := []string{"new", "a", "b", "c"}
+	for round := 0; round < 10; round++ {
+		ranked := Rank(candidates)
+		for index, r := range ranked {
+			if r.ProductId != want[index] {
+				t.Fatalf("ranked: %v", ranked)
+			}
+		}
+		// 입력 순서가 달라도 같은 결과
+		candidates = append(candidates[1:], candidates[0])
+	}
+}
+
+func TestAfter(t *testing.T) {
+	ranked := []Ranked{{"a", 3}, {"b", 2}, {"c", 2}, {"d", 1}}
+	tests := []struct {
+		name  string
+		last  Ranked
+		first string // 비어 있으면 남은 상품이 없다.
+	}{
+		{"first", Ranked{"a", 3}, "b"},
+		{"tie", Ranked{"b", 2}, "c"},
+		{"last", Ranked{"d", 1}, ""},
+		// 이전 page 의 마지막 상품이 그 사이 삭제되었다.
+		{"removed", Ranked{"bb", 2}, "c"},
+		{"removed lower", Ranked{"x", 1.5}, "d"},
+	}
+	for _, test := range tests {
+		rest := after(ranked, test.last)
+		if test.first == "" {
+			if len(rest) != 0 {
+				t.Errorf("%s: %v", test.name, rest)
+			}
+			continue
+		}
+		if len(rest) == 0 || rest[0].ProductId != test.first {
+			t.Errorf("%s: %v", test.name, rest)
+		}
+	}
+}
+
+// page 를 이어 붙이면 중복이나 빠진 상품 없이 전체 정렬 결과와 같다.
+func TestPagingCoversAll(t *testing.T) {
+	candidates := make([]Candidate, 0)
+	for index := 0; index < 23; index++ {
+		candidates = append(candidates, Candidate{ProductId: string(rune('a' + index)), AgeMinutes: int64(index % 4)})
+	}
+	all := Rank(candidates)
+
+	seen := make([]string, 0)
+	ranked := all
+	for len(ranked) > 0 {
+		page := ranked
+		if len(page) > 5 {
+			page = page[:5]
+		}
+		for _, r := range page {
+			seen = append(seen, r.ProductId)
+		}
+		c, ok := decodeCursor(encodeCursor(cursor{asOf: time.Now(), last: page[len(page)-1]}))
+		if !ok {
+			t.Fatal("cursor decode failed")
+		}
+		ranked = after(Rank(candidates), c.last)
+	}
+	if len(seen) != len(all) {
+		t.Fatalf("seen: %v", seen)
+	}
+	for index, r := range all {
+		if seen[index] != r.ProductId {
+			t.Fatalf("seen: %v", seen)
+		}
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	asOf := time.Unix(1618800000, 0)
+	last := Ranked{ProductId: "product:1", Score: 0.123456789012345}
+	c, ok := decodeCursor(encodeCursor(cursor{asOf: asOf, last: last}))
+	if !ok || !c.asOf.Equal(asOf) || c.last != last {
+		t.Fatalf("cursor: %+v", c)
+	}
+
+	for _, value := range []string{
+		"",
+		"1618800000",
+		"1618800000:0.5",
+		"1618800000:0.5:",
+		"x:0.5:product",
+		"0:0.5:product",
+		"-1:0.5:product",
+		"1618800000:x:product",
+		"1618800000:NaN:product",
+		"1618800000:Inf:product",
+	} {
+		if _, ok := decodeCursor(value); ok {
+			t.Errorf("%q decoded", value)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package firebase
+
+import (
+	"sync"
+)
+
+// 보낸 것으로 기록만 한다.
+type SentPush struct {
+	Tokens  []string
+	Topic   string
+	Message PushMessage
+}
+
+// FCM 없이 push 발송을 확인할 때 쓴다. MarkInvalid 로 지정한 token 은 유효하지 않다고 응답한다.
+type FakeSender struct {
+	lock    sync.Mutex
+	sent    []SentPush
+	invalid map[string]bool
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{invalid: map[string]bool{}}
+}
+
+func (f *FakeSender) MarkInvalid(tokens ...string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, token := range tokens {
+		f.invalid[token] = true
+	}
+}
+
+func (f *FakeSender) Sent() []SentPush {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]SentPush{}, f.sent...)
+}
+
+func (f *FakeSender) SendMulticast(tokens []string, message PushMessage) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	valid := make([]string, 0, len(tokens))
+	invalid := make([]string, 0)
+	for _, token := range tokens {
+		if f.invalid[token] {
+			invalid = append(invalid, token)
+		} else {
+			valid = append(valid, token)
+		}
+	}
+	f.sent = append(f.sent, SentPush{Tokens: valid, Message: message})
+	return invalid, nil
+}
+
+func (f *FakeSender) SendTopic(topic string, message PushMessage) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.sent = append(f.sent, SentPush{Topic: topic, Message: message})
+	return nil
+}
@@ -5,37 +5,69 @@ import (
 	"errors"
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
+	"firebase.google.com/go/messaging"
 	"fmt"
 	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/metrics"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
+	"io/ioutil"
+	"time"
 )
 
 type Firebase interface {
 	CreateCustomToken(uniqueId string) (string, error)
 	VerifyIDToken(idToken string) (string, error)
 	GetUserEmail(idToken string) (string, string, error)
+	CheckCredentials() error // service account key 를 읽었는지 확인한다.
+	Sender
 }
 
+var ErrCredentialsNotLoaded = errors.New("firebase credentials not loaded")
+
 type manager struct {
-	conf *config.Config
-	app  *firebase.App
+	conf      *config.Config
+	app       *firebase.App
+	messaging *messaging.Client
+	projectId string // service account key 의 project_id
 }
 
 func NewManager(conf *config.Config) (Firebase, error) {
 	m := &manager{
 		conf: conf,
 	}
-	opt := option.WithCredentialsFile(conf.Firebase.ServiceAccountKeyPath)
-	var err error
+	key, err := ioutil.ReadFile(conf.Firebase.ServiceAccountKeyPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("firebase service account key read failed. err: %s", err))
+	}
+	creds, err := google.CredentialsFromJSON(context.Background(), key)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("firebase service account key parse failed. err: %s", err))
+	}
+	m.projectId = creds.ProjectID
+
+	opt := option.WithCredentialsJSON(key)
 	m.app, err = firebase.NewApp(context.Background(), nil, opt)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("firebase NewApp failed. err: %s", err))
 	}
+	m.messaging, err = m.app.Messaging(context.Background())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("firebase Messaging failed. err: %s", err))
+	}
 
 	return m, nil
 }
 
+func (m *manager) CheckCredentials() error {
+	if m.app == nil || m.projectId == "" {
+		return ErrCredentialsNotLoaded
+	}
+	return nil
+}
+
 func (m *manager) CreateCustomToken(uniqueId string) (string, error) {
+	defer metrics.ObserveFirebase("create_custom_token", time.Now())
 	client, err := m.app.Auth(context.Background())
 	if err != nil {
 		return "", err
@@ -63,6 +95,7 @@ func (m *manager) getAuthToken(idToken string) (*auth.Client, *auth.Token, error
 }
 
 func (m *manager) VerifyIDToken(idToken string) (string, error) {
+	defer metrics.ObserveFirebase("verify_id_token", time.Now())
 	_, token, err := m.getAuthToken(idToken)
 	if err != nil {
 		return "", err
@@ -71,6 +104,7 @@ func (m *manager) VerifyIDToken(idToken string) (string, error) {
 }
 
 func (m *manager) GetUserEmail(idToken string) (string, string, error) {
+	defer metrics.ObserveFirebase("get_user_email", time.Now())
 	client, token, err := m.getAuthToken(idToken)
 	if err != nil {
 		return "", "", err
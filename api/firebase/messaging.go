@@ -0,0 +1,87 @@
+package firebase
+
+import (
+	"context"
+	"firebase.google.com/go/messaging"
+	"github.com/4538cgy/backend-second/metrics"
+	"time"
+)
+
+const (
+	// FCM 한 번의 multicast 요청에 담을 수 있는 token 수
+	MaxMulticastTokens = 500
+	sendTimeout        = 10 * time.Second
+)
+
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string // 앱이 화면 이동 등에 쓰는 값. title, body 가 비어 있으면 data 만 보낸다.
+}
+
+type Sender interface {
+	// token 마다 보낸다. FCM 이 더 이상 유효하지 않다고 알려준 token 을 돌려준다.
+	SendMulticast(tokens []string, message PushMessage) ([]string, error)
+	SendTopic(topic string, message PushMessage) error
+}
+
+func notificationOf(message PushMessage) *messaging.Notification {
+	if message.Title == "" && message.Body == "" {
+		return nil
+	}
+	return &messaging.Notification{Title: message.Title, Body: message.Body}
+}
+
+// FCM 한 번의 요청에 담을 수 있도록 size 개씩 나눈다.
+func chunkTokens(tokens []string, size int) [][]string {
+	chunks := make([][]string, 0, (len(tokens)+size-1)/size)
+	for start := 0; start < len(tokens); start += size {
+		end := start + size
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunks = append(chunks, tokens[start:end])
+	}
+	return chunks
+}
+
+func (m *manager) SendMulticast(tokens []string, message PushMessage) ([]string, error) {
+	invalid := make([]string, 0)
+	for _, chunk := range chunkTokens(tokens, MaxMulticastTokens) {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		start := time.Now()
+		resp, err := m.messaging.SendMulticast(ctx, &messaging.MulticastMessage{
+			Tokens:       chunk,
+			Notification: notificationOf(message),
+			Data:         message.Data,
+		})
+		cancel()
+		metrics.ObserveFirebase("send_multicast", start)
+		if err != nil {
+			return invalid, err
+		}
+		for i, result := range resp.Responses {
+			if result.Success {
+				continue
+			}
+			// invalid argument 는 payload 가 잘못된 경우에도 오므로 token 을 지우는 근거로 쓰지 않는다.
+			if messaging.IsRegistrationTokenNotRegistered(result.Error) {
+				invalid = append(invalid, chunk[i])
+			}
+		}
+	}
+	return invalid, nil
+}
+
+func (m *manager) SendTopic(topic string, message PushMessage) error {
+	defer metrics.ObserveFirebase("send_topic", time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	_, err := m.messaging.Send(ctx, &messaging.Message{
+		Topic:        topic,
+		Notification: notificationOf(message),
+		Data:         message.Data,
+	})
+	return err
+}
@@ -0,0 +1,43 @@
+package firebase
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestChunkTokens(t *testing.T) {
+	tokens := make([]string, 0, 1001)
+	for index := 0; index < 1001; index++ {
+		tokens = append(tokens, fmt.Sprint("token", index))
+	}
+	tests := []struct {
+		count int
+		want  []int
+	}{
+		{0, []int{}},
+		{1, []int{1}},
+		{MaxMulticastTokens, []int{MaxMulticastTokens}},
+		{MaxMulticastTokens + 1, []int{MaxMulticastTokens, 1}},
+		{1001, []int{MaxMulticastTokens, MaxMulticastTokens, 1}},
+	}
+	for _, test := range tests {
+		chunks := chunkTokens(tokens[:test.count], MaxMulticastTokens)
+		if len(chunks) != len(test.want) {
+			t.Errorf("count %d: chunks %d. want %d", test.count, len(chunks), len(test.want))
+			continue
+		}
+		next := 0
+		for index, chunk := range chunks {
+			if len(chunk) != test.want[index] {
+				t.Errorf("count %d: chunk %d size %d. want %d", test.count, index, len(chunk), test.want[index])
+			}
+			// 순서대로 빠짐없이 나뉘어야 한다.
+			for _, token := range chunk {
+				if token != tokens[next] {
+					t.Fatalf("count %d: token %s. want %s", test.count, token, tokens[next])
+				}
+				next++
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 프로세스가 살아 있는지만 본다. 실패하면 재시작 대상이다.
+	healthLiveUri = "/health/live"
+	// 요청을 받을 수 있는지 본다. 실패하면 트래픽에서 빠진다.
+	healthReadyUri = "/health/ready"
+
+	healthPingTimeout = time.Second
+	healthOk          = "ok"
+)
+
+func (api *apiManager) live(ctx echo.Context) error {
+	return ctx.String(http.StatusOK, "")
+}
+
+func (api *apiManager) ready(ctx echo.Context) error {
+	resp := &protocol.HealthResponse{
+		Checks: map[string]string{
+			"database": healthOk,
+			"storage":  healthOk,
+			"firebase": healthOk,
+		},
+	}
+	failed := false
+	check := func(name string, err error) {
+		if err != nil {
+			log.Warning("readiness check failed. ", name, ": ", err)
+			resp.Checks[name] = err.Error()
+			failed = true
+		}
+	}
+	check("database", api.dbManager.Ping(healthPingTimeout))
+	check("storage", api.assetHandler.CheckWritable())
+	check("firebase", api.fbManager.CheckCredentials())
+
+	if failed {
+		resp.Status = vcomError.ServiceNotReady
+		resp.Detail = vcomError.MessageServiceNotReady
+		return ctx.JSON(http.StatusServiceUnavailable, resp)
+	}
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
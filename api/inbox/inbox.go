@@ -0,0 +1,274 @@
+package inbox
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 앱 내 알림 목록(GET), 읽음 처리(PATCH)
+	notificationUrl = "/api/notification"
+	// 분류별 수신 설정
+	notificationSettingUrl = "/api/notification/setting"
+
+	maxReadIds = 100
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(notificationUrl, "GET"), getNotifications)
+	route.AddRoute(route.NewRouteType(notificationUrl, "PATCH"), readNotifications)
+	route.AddRoute(route.NewRouteType(notificationSettingUrl, "GET"), getSettings)
+	route.AddRoute(route.NewRouteType(notificationSettingUrl, "PUT"), updateSetting)
+}
+
+func unreadCount(m database.Manager, timer *time.Timer, uniqueId string) (int, error) {
+	rows, err := database.Select(m, timer, query.SelectUnreadNotificationCount, uniqueId)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
+}
+
+// unread=1 이면 읽지 않은 알림만 준다.
+func getNotifications(ctx echo.Context) error {
+	resp := &protocol.NotificationResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	condition := ""
+	if ctx.QueryParam("unread") == "1" {
+		condition = "AND is_read=0"
+	}
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, fmt.Sprintf(query.SelectNotifications, condition),
+		uniqueId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Notifications = make([]protocol.NotificationInfo, 0)
+	for rows.Next() {
+		n := protocol.NotificationInfo{}
+		if err := rows.Scan(&n.NotificationId, &n.Category, &n.Title, &n.Body, &n.ReferenceId, &n.Read, &n.Created); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Notifications = append(resp.Notifications, n)
+	}
+	if len(resp.Notifications) > count {
+		resp.Notifications = resp.Notifications[:count]
+		resp.HasNext = true
+	}
+
+	if resp.UnreadCount, err = unreadCount(customContext.Manager, timer, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// notification_ids(쉼표로 구분)를 읽음 처리한다. all=1 이면 모두 읽음 처리한다.
+func readNotifications(ctx echo.Context) error {
+	resp := &protocol.NotificationReadResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	readQuery := query.UpdateAllNotificationsRead
+	args := []interface{}{uniqueId}
+	if ctx.FormValue("all") != "1" {
+		ids := make([]string, 0)
+		for _, id := range strings.Split(ctx.FormValue("notification_ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 || len(ids) > maxReadIds {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+		readQuery = fmt.Sprintf(query.UpdateNotificationsRead, strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	// 이미 읽었거나 다른 사용자의 알림은 무시한다.
+	if _, err := database.Exec(customContext.Manager, timer, readQuery, args...); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if resp.UnreadCount, err = unreadCount(customContext.Manager, timer, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func settingInfos(settings []notification.Setting) []protocol.NotificationSettingInfo {
+	infos := make([]protocol.NotificationSettingInfo, 0, len(settings))
+	for _, setting := range settings {
+		infos = append(infos, protocol.NotificationSettingInfo{
+			Category: setting.Category,
+			InApp:    setting.InApp,
+			Push:     setting.Push,
+			Email:    setting.Email,
+		})
+	}
+	return infos
+}
+
+func getSettings(ctx echo.Context) error {
+	resp := &protocol.NotificationSettingResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	settings, err := notification.Settings(customContext.Manager, timer, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Settings = settingInfos(settings)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// category 의 in_app, push, email 중 보낸 값만 바꾼다.
+func updateSetting(ctx echo.Context) error {
+	resp := &protocol.NotificationSettingResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	category := ctx.FormValue("category")
+	if !notification.ValidCategory(category) {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	setting, err := notification.FindSetting(customContext.Manager, timer, uniqueId, category)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	for name, value := range map[string]*bool{"in_app": &setting.InApp, "push": &setting.Push, "email": &setting.Email} {
+		form := ctx.FormValue(name)
+		if form == "" {
+			continue
+		}
+		if *value, err = strconv.ParseBool(form); err != nil {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.UpsertNotificationSetting,
+		uniqueId, category, setting.InApp, setting.Push, setting.Email); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	settings, err := notification.Settings(customContext.Manager, timer, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	resp.Settings = settingInfos(settings)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
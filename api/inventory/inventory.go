@@ -0,0 +1,273 @@
+package inventory
+
+import (
+	"errors"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"time"
+)
+
+type Status int
+
+const (
+	StatusReserved  = Status(0) // 주문 생성. 재고를 잡아두고 결제를 기다린다.
+	StatusCommitted = Status(1) // 결제 완료. 잡아둔 재고가 팔렸다.
+	StatusReleased  = Status(2) // 결제 시간 초과 혹은 취소. 재고를 돌려주었다.
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusReserved:
+		return "Reserved"
+	case StatusCommitted:
+		return "Committed"
+	case StatusReleased:
+		return "Released"
+	}
+	return "Unknown"
+}
+
+const (
+	defaultReserveTtlSec    = 900
+	defaultSweepIntervalSec = 60
+	sweepBatchSize          = 100
+	sweepTimeout            = 30 * time.Second
+	compensateTimeout       = 10 * time.Second
+)
+
+var (
+	ErrOutOfStock  = errors.New("out of stock")
+	ErrNotReserved = errors.New("stock is not reserved")
+	ErrNoHold      = errors.New("no stock reservation for order")
+)
+
+type Item struct {
+	SkuId    string
+	Quantity int
+}
+
+// 주문 하나의 재고 예약. 재고는 예약할 때 빠지고, status 가 Released 로 바뀔 때 한 번만 돌아온다.
+type Hold struct {
+	OrderId   string
+	Items     []Item
+	Status    Status
+	ExpiresAt time.Time
+}
+
+// 재고와 예약 저장소. 모든 변경은 조건을 확인하고 바꾸는 한 번의 동작이어야 한다.
+// 여러 database pump goroutine 이 동시에 실행해도 재고가 음수가 되거나 같은 예약이 두 번 바뀌지 않는다.
+type Store interface {
+	// 재고가 quantity 이상일 때만 뺀다. 모자라면 ErrOutOfStock 이다.
+	Take(timer *time.Timer, skuId string, quantity int) error
+	Put(timer *time.Timer, skuId string, quantity int) error
+	CreateHold(timer *time.Timer, hold Hold) error
+	// 없으면 nil 을 돌려준다.
+	FindHold(timer *time.Timer, orderId string) (*Hold, error)
+	// status 가 from 일 때만 to 로 바꾼다. 바꿨으면 true 이다.
+	SetStatus(timer *time.Timer, orderId string, from, to Status) (bool, error)
+	// now 전에 만료된 Reserved 예약들
+	Expired(timer *time.Timer, now time.Time, limit int) ([]Hold, error)
+}
+
+// 결제 시간이 지나 예약이 풀린 주문을 처리한다. 주문 취소, 쿠폰 반환 등
+type ExpireFunc func(timer *time.Timer, orderId string) error
+
+type Inventory interface {
+	Start()
+	// items 를 모두 예약하거나 하나도 예약하지 않는다.
+	ReserveStock(timer *time.Timer, orderId string, items []Item) error
+	// 결제가 끝난 주문의 예약을 확정한다. 이미 풀린 예약이면 ErrNotReserved 이다.
+	CommitStock(timer *time.Timer, orderId string) error
+	// 예약 혹은 확정된 재고를 돌려준다. 이미 돌려주었으면 ErrNotReserved, 예약이 없는 주문이면 ErrNoHold 이다.
+	ReleaseStock(timer *time.Timer, orderId string) error
+}
+
+type inventoryHandler struct {
+	store    Store
+	ttl      time.Duration
+	interval time.Duration
+	onExpire ExpireFunc
+	now      func() time.Time
+}
+
+func NewInventoryHandler(cfg *config.Config, dbManager database.Manager, onExpire ExpireFunc) Inventory {
+	ttlSec := cfg.Inventory.ReserveTtlSec
+	if ttlSec <= 0 {
+		ttlSec = defaultReserveTtlSec
+	}
+	intervalSec := cfg.Inventory.SweepIntervalSec
+	if intervalSec <= 0 {
+		intervalSec = defaultSweepIntervalSec
+	}
+	return NewInventoryHandlerWith(&dbStore{dbManager: dbManager},
+		time.Duration(ttlSec)*time.Second, time.Duration(intervalSec)*time.Second, onExpire)
+}
+
+// 저장소를 직접 지정한다. test 에 사용한다.
+func NewInventoryHandlerWith(store Store, ttl, interval time.Duration, onExpire ExpireFunc) Inventory {
+	return &inventoryHandler{
+		store:    store,
+		ttl:      ttl,
+		interval: interval,
+		onExpire: onExpire,
+		now:      time.Now,
+	}
+}
+
+// 주기적으로 만료된 예약을 풀어 재고를 돌려준다.
+func (i *inventoryHandler) Start() {
+	go func() {
+		ticker := time.NewTicker(i.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			i.sweep()
+		}
+	}()
+}
+
+func (i *inventoryHandler) ReserveStock(timer *time.Timer, orderId string, items []Item) error {
+	for index, item := range items {
+		if err := i.store.Take(timer, item.SkuId, item.Quantity); err != nil {
+			i.compensate(items[:index])
+			// 응답을 기다리다 타임아웃이 나면 UPDATE 가 실행되었는지 알 수 없다.
+			// 돌려주면 없던 재고가 생길 수 있으므로 대사를 위해 남기기만 한다.
+			if err == database.ErrQueryResponseTimeout {
+				log.Error("stock take result unknown. order_id: ", orderId,
+					", sku_id: ", item.SkuId, ", quantity: ", item.Quantity)
+			}
+			return err
+		}
+	}
+	if err := i.store.CreateHold(timer, Hold{
+		OrderId:   orderId,
+		Items:     items,
+		Status:    StatusReserved,
+		ExpiresAt: i.now().Add(i.ttl),
+	}); err != nil {
+		i.discardHold(orderId, items)
+		return err
+	}
+	return nil
+}
+
+// 요청 timer 는 이미 만료되었을 수 있으므로 새 timer 로 재고를 돌려준다.
+func (i *inventoryHandler) compensate(items []Item) {
+	timer := time.NewTimer(compensateTimeout)
+	defer timer.Stop()
+	i.put(timer, items)
+}
+
+// 예약 저장이 실패했지만 실제로는 저장되었을 수 있다. 예약이 있으면 Released 로 바꾼 쪽만 재고를 돌려준다.
+func (i *inventoryHandler) discardHold(orderId string, items []Item) {
+	timer := time.NewTimer(compensateTimeout)
+	defer timer.Stop()
+
+	ok, err := i.store.SetStatus(timer, orderId, StatusReserved, StatusReleased)
+	if err == nil && !ok {
+		var hold *Hold
+		hold, err = i.store.FindHold(timer, orderId)
+		// 다른 쪽이 이미 풀었다면 재고도 그쪽이 돌려주었다.
+		ok = err == nil && hold == nil
+	}
+	if err != nil {
+		// TODO rollback needed
+		log.Error("stock reservation discard failed. order_id: ", orderId, ", err: ", err)
+		return
+	}
+	if ok {
+		i.put(timer, items)
+	}
+}
+
+// 만료 시각이 지났더라도 아직 풀리지 않았다면 확정한다.
+func (i *inventoryHandler) CommitStock(timer *time.Timer, orderId string) error {
+	ok, err := i.store.SetStatus(timer, orderId, StatusReserved, StatusCommitted)
+	if err != nil || ok {
+		return err
+	}
+	hold, err := i.store.FindHold(timer, orderId)
+	if err != nil {
+		return err
+	}
+	// 예약 없이 주문할 때 재고를 바로 뺀 이전 주문
+	if hold == nil {
+		return nil
+	}
+	return ErrNotReserved
+}
+
+func (i *inventoryHandler) ReleaseStock(timer *time.Timer, orderId string) error {
+	for _, from := range []Status{StatusReserved, StatusCommitted} {
+		ok, err := i.store.SetStatus(timer, orderId, from, StatusReleased)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return i.restore(timer, orderId)
+		}
+	}
+	hold, err := i.store.FindHold(timer, orderId)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return ErrNoHold
+	}
+	return ErrNotReserved
+}
+
+// Released 로 바꾼 쪽만 부른다. status 가 한 번만 바뀌므로 재고도 한 번만 돌아온다.
+func (i *inventoryHandler) restore(timer *time.Timer, orderId string) error {
+	hold, err := i.store.FindHold(timer, orderId)
+	if err != nil {
+		return err
+	}
+	if hold != nil {
+		i.put(timer, hold.Items)
+	}
+	return nil
+}
+
+func (i *inventoryHandler) put(timer *time.Timer, items []Item) {
+	for _, item := range items {
+		if err := i.store.Put(timer, item.SkuId, item.Quantity); err != nil {
+			log.Error("stock restore failed. sku_id: ", item.SkuId, ", quantity: ", item.Quantity, ", err: ", err)
+		}
+	}
+}
+
+// 만료된 예약을 풀고 주문을 정리한다. 결제와 동시에 실행되어도 status 를 먼저 바꾼 쪽만 반영된다.
+func (i *inventoryHandler) sweep() int {
+	timer := time.NewTimer(sweepTimeout)
+	defer timer.Stop()
+
+	holds, err := i.store.Expired(timer, i.now(), sweepBatchSize)
+	if err != nil {
+		log.Error("inventory sweep failed. err: ", err)
+		return 0
+	}
+	released := 0
+	for _, hold := range holds {
+		ok, err := i.store.SetStatus(timer, hold.OrderId, StatusReserved, StatusReleased)
+		if err != nil {
+			log.Error("inventory release failed. order_id: ", hold.OrderId, ", err: ", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		i.put(timer, hold.Items)
+		released++
+		if i.onExpire == nil {
+			continue
+		}
+		if err := i.onExpire(timer, hold.OrderId); err != nil {
+			log.Error("expired order handling failed. order_id: ", hold.OrderId, ", err: ", err)
+		}
+	}
+	if released > 0 {
+		log.Info("inventory released expired reservations: ", released)
+	}
+	return released
+}
@@ -0,0 +1,258 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/database"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestHandler(store Store, onExpire ExpireFunc) *inventoryHandler {
+	return NewInventoryHandlerWith(store, time.Minute, time.Hour, onExpire).(*inventoryHandler)
+}
+
+func newTimer() *time.Timer {
+	return time.NewTimer(time.Second)
+}
+
+func TestReserveConcurrentDoesNotOversell(t *testing.T) {
+	store := NewMemoryStore()
+	store.SetStock("sku", 10)
+	handler := newTestHandler(store, nil)
+
+	var reserved, outOfStock int32
+	var wg sync.WaitGroup
+	for index := 0; index < 100; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			err := handler.ReserveStock(newTimer(), fmt.Sprint("order", index), []Item{{SkuId: "sku", Quantity: 1}})
+			switch err {
+			case nil:
+				atomic.AddInt32(&reserved, 1)
+			case ErrOutOfStock:
+				atomic.AddInt32(&outOfStock, 1)
+			default:
+				t.Error("unexpected error: ", err)
+			}
+		}(index)
+	}
+	wg.Wait()
+
+	if reserved != 10 || outOfStock != 90 {
+		t.Fatalf("reserved: %d, out of stock: %d", reserved, outOfStock)
+	}
+	if stock := store.Stock("sku"); stock != 0 {
+		t.Fatalf("stock: %d", stock)
+	}
+}
+
+// 여러 sku 를 예약하다 하나라도 모자라면 앞서 뺀 재고를 돌려준다.
+func TestReserveIsAllOrNothing(t *testing.T) {
+	store := NewMemoryStore()
+	store.SetStock("a", 5)
+	store.SetStock("b", 1)
+	handler := newTestHandler(store, nil)
+
+	var reserved int32
+	var wg sync.WaitGroup
+	for index := 0; index < 20; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			items := []Item{{SkuId: "a", Quantity: 1}, {SkuId: "b", Quantity: 1}}
+			if handler.ReserveStock(newTimer(), fmt.Sprint("order", index), items) == nil {
+				atomic.AddInt32(&reserved, 1)
+			}
+		}(index)
+	}
+	wg.Wait()
+
+	if reserved != 1 {
+		t.Fatalf("reserved: %d", reserved)
+	}
+	if a, b := store.Stock("a"), store.Stock("b"); a != 4 || b != 0 {
+		t.Fatalf("stock a: %d, b: %d", a, b)
+	}
+}
+
+// 결제 확정, 취소, 만료 처리가 동시에 일어나도 하나만 반영되고 재고는 한 번만 돌아온다.
+func TestCommitReleaseSweepRace(t *testing.T) {
+	for round := 0; round < 50; round++ {
+		store := NewMemoryStore()
+		store.SetStock("sku", 3)
+		var expired int32
+		handler := newTestHandler(store, func(timer *time.Timer, orderId string) error {
+			atomic.AddInt32(&expired, 1)
+			return nil
+		})
+		if err := handler.ReserveStock(newTimer(), "order", []Item{{SkuId: "sku", Quantity: 2}}); err != nil {
+			t.Fatal(err)
+		}
+		handler.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+		var committed, released int32
+		var wg sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				if handler.CommitStock(newTimer(), "order") == nil {
+					atomic.AddInt32(&committed, 1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if handler.ReleaseStock(newTimer(), "order") == nil {
+					atomic.AddInt32(&released, 1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				atomic.AddInt32(&released, int32(handler.sweep()))
+			}()
+		}
+		wg.Wait()
+
+		// 취소는 확정된 예약도 풀기 때문에 마지막에는 항상 풀려 있다.
+		hold, _ := store.FindHold(newTimer(), "order")
+		if stock := store.Stock("sku"); hold.Status != StatusReleased || released != 1 || committed > 1 || stock != 3 {
+			t.Fatalf("status: %v, committed: %d, released: %d, stock: %d", hold.Status, committed, released, stock)
+		}
+		if expired > 1 {
+			t.Fatalf("expired handled: %d", expired)
+		}
+	}
+}
+
+func TestSweepReleasesOnlyExpired(t *testing.T) {
+	store := NewMemoryStore()
+	store.SetStock("sku", 10)
+	expiredOrders := make(chan string, 10)
+	handler := newTestHandler(store, func(timer *time.Timer, orderId string) error {
+		expiredOrders <- orderId
+		return nil
+	})
+
+	now := time.Now()
+	handler.now = func() time.Time { return now }
+	if err := handler.ReserveStock(newTimer(), "old", []Item{{SkuId: "sku", Quantity: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.ReserveStock(newTimer(), "paid", []Item{{SkuId: "sku", Quantity: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.CommitStock(newTimer(), "paid"); err != nil {
+		t.Fatal(err)
+	}
+	handler.now = func() time.Time { return now.Add(30 * time.Second) }
+	if err := handler.ReserveStock(newTimer(), "new", []Item{{SkuId: "sku", Quantity: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler.now = func() time.Time { return now.Add(61 * time.Second) }
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.sweep()
+		}()
+	}
+	wg.Wait()
+	close(expiredOrders)
+
+	orders := make([]string, 0)
+	for orderId := range expiredOrders {
+		orders = append(orders, orderId)
+	}
+	if len(orders) != 1 || orders[0] != "old" {
+		t.Fatalf("expired orders: %v", orders)
+	}
+	if stock := store.Stock("sku"); stock != 5 {
+		t.Fatalf("stock: %d", stock)
+	}
+	if err := handler.CommitStock(newTimer(), "old"); err != ErrNotReserved {
+		t.Fatalf("commit after expire: %v", err)
+	}
+}
+
+// 예약 없이 재고를 바로 뺀 이전 주문은 확정에 실패하지 않는다.
+func TestCommitWithoutHold(t *testing.T) {
+	handler := newTestHandler(NewMemoryStore(), nil)
+	if err := handler.CommitStock(newTimer(), "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.ReleaseStock(newTimer(), "legacy"); err != ErrNoHold {
+		t.Fatal(err)
+	}
+}
+
+// failSku 의 Take 가 err 로 실패한다. taken 이면 UPDATE 는 실행된 뒤 응답만 잃은 것이다.
+// 요청 timer 로는 Put 도 실패해서, 만료된 timer 를 그대로 쓰면 재고가 돌아오지 않는다.
+type failingStore struct {
+	*MemoryStore
+	failSku string
+	err     error
+	taken   bool
+	expired *time.Timer
+}
+
+func (s *failingStore) Take(timer *time.Timer, skuId string, quantity int) error {
+	if skuId != s.failSku {
+		return s.MemoryStore.Take(timer, skuId, quantity)
+	}
+	if s.taken {
+		if err := s.MemoryStore.Take(timer, skuId, quantity); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func (s *failingStore) Put(timer *time.Timer, skuId string, quantity int) error {
+	if timer == s.expired {
+		return database.ErrQueryResponseTimeout
+	}
+	return s.MemoryStore.Put(timer, skuId, quantity)
+}
+
+func TestReserveTakeFailure(t *testing.T) {
+	items := []Item{{SkuId: "a", Quantity: 2}, {SkuId: "b", Quantity: 3}, {SkuId: "c", Quantity: 1}}
+	tests := []struct {
+		name  string
+		err   error
+		taken bool
+		// 실패한 sku 를 제외한 재고는 모두 돌아와야 한다.
+		stockB int
+	}{
+		// UPDATE 가 보내지지 않았다.
+		{name: "request timeout", err: database.ErrQueryRequestTimeout, stockB: 5},
+		{name: "sql error", err: errors.New("deadlock found"), stockB: 5},
+		// 실행 여부를 알 수 없으므로 돌려주지 않고 대사에 맡긴다.
+		{name: "response timeout", err: database.ErrQueryResponseTimeout, taken: true, stockB: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &failingStore{MemoryStore: NewMemoryStore(), failSku: "b", err: test.err,
+				taken: test.taken, expired: newTimer()}
+			store.SetStock("a", 5)
+			store.SetStock("b", 5)
+			store.SetStock("c", 5)
+			handler := newTestHandler(store, nil)
+
+			if err := handler.ReserveStock(store.expired, "order", items); err != test.err {
+				t.Fatal("unexpected error: ", err)
+			}
+			if a, b, c := store.Stock("a"), store.Stock("b"), store.Stock("c"); a != 5 || b != test.stockB || c != 5 {
+				t.Fatalf("stock a: %d, b: %d, c: %d", a, b, c)
+			}
+			if hold, _ := store.FindHold(newTimer(), "order"); hold != nil {
+				t.Fatal("unexpected hold: ", hold)
+			}
+		})
+	}
+}
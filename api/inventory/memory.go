@@ -0,0 +1,95 @@
+package inventory
+
+import (
+	"sync"
+	"time"
+)
+
+// database 없이 재고 예약을 확인하기 위한 저장소. 잠금 하나로 Store 의 원자성을 흉내낸다.
+type MemoryStore struct {
+	lock   sync.Mutex
+	stocks map[string]int // sku id -> 재고
+	holds  map[string]*Hold
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		stocks: map[string]int{},
+		holds:  map[string]*Hold{},
+	}
+}
+
+func (s *MemoryStore) SetStock(skuId string, stock int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stocks[skuId] = stock
+}
+
+func (s *MemoryStore) Stock(skuId string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stocks[skuId]
+}
+
+func (s *MemoryStore) Take(timer *time.Timer, skuId string, quantity int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stock := s.stocks[skuId]
+	if stock < quantity {
+		return ErrOutOfStock
+	}
+	s.stocks[skuId] = stock - quantity
+	return nil
+}
+
+func (s *MemoryStore) Put(timer *time.Timer, skuId string, quantity int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stocks[skuId] += quantity
+	return nil
+}
+
+func (s *MemoryStore) CreateHold(timer *time.Timer, hold Hold) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	hold.Items = append([]Item(nil), hold.Items...)
+	s.holds[hold.OrderId] = &hold
+	return nil
+}
+
+func (s *MemoryStore) FindHold(timer *time.Timer, orderId string) (*Hold, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	hold, ok := s.holds[orderId]
+	if !ok {
+		return nil, nil
+	}
+	found := *hold
+	return &found, nil
+}
+
+func (s *MemoryStore) SetStatus(timer *time.Timer, orderId string, from, to Status) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	hold, ok := s.holds[orderId]
+	if !ok || hold.Status != from {
+		return false, nil
+	}
+	hold.Status = to
+	return true, nil
+}
+
+func (s *MemoryStore) Expired(timer *time.Timer, now time.Time, limit int) ([]Hold, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	holds := make([]Hold, 0)
+	for _, hold := range s.holds {
+		if len(holds) >= limit {
+			break
+		}
+		if hold.Status == StatusReserved && hold.ExpiresAt.Before(now) {
+			holds = append(holds, *hold)
+		}
+	}
+	return holds, nil
+}
@@ -0,0 +1,96 @@
+package inventory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/query"
+	"time"
+)
+
+const datetimeFormat = "2006-01-02 15:04:05"
+
+// 모든 변경은 조건이 걸린 UPDATE 한 문장이라 어느 pump goroutine 에서 실행되어도 원자적이다.
+type dbStore struct {
+	dbManager database.Manager
+}
+
+// 상품의 base_amount 도 함께 바뀐다.
+func (d *dbStore) Take(timer *time.Timer, skuId string, quantity int) error {
+	res, err := database.Exec(d.dbManager, timer, query.DecreaseProductSkuStock, quantity, quantity, skuId, quantity)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrOutOfStock
+	}
+	return nil
+}
+
+func (d *dbStore) Put(timer *time.Timer, skuId string, quantity int) error {
+	_, err := database.Exec(d.dbManager, timer, query.IncreaseProductSkuStock, quantity, quantity, skuId)
+	return err
+}
+
+func (d *dbStore) CreateHold(timer *time.Timer, hold Hold) error {
+	items, err := json.Marshal(hold.Items)
+	if err != nil {
+		return err
+	}
+	_, err = database.Exec(d.dbManager, timer, query.InsertInventoryHold,
+		hold.OrderId, string(items), hold.Status, hold.ExpiresAt.Format(datetimeFormat))
+	return err
+}
+
+func (d *dbStore) FindHold(timer *time.Timer, orderId string) (*Hold, error) {
+	rows, err := database.Select(d.dbManager, timer, query.SelectInventoryHold, orderId)
+	if err != nil {
+		return nil, err
+	}
+	holds, err := scanHolds(rows)
+	if err != nil || len(holds) == 0 {
+		return nil, err
+	}
+	return &holds[0], nil
+}
+
+func (d *dbStore) SetStatus(timer *time.Timer, orderId string, from, to Status) (bool, error) {
+	res, err := database.Exec(d.dbManager, timer, query.UpdateInventoryHoldStatus, to, orderId, from)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (d *dbStore) Expired(timer *time.Timer, now time.Time, limit int) ([]Hold, error) {
+	rows, err := database.Select(d.dbManager, timer, query.SelectExpiredInventoryHolds,
+		StatusReserved, now.Format(datetimeFormat), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanHolds(rows)
+}
+
+// column 순서: order_id, items_json, status, expires_at. rows 는 닫힌다.
+func scanHolds(rows *sql.Rows) ([]Hold, error) {
+	defer rows.Close()
+
+	holds := make([]Hold, 0)
+	for rows.Next() {
+		hold := Hold{}
+		var items, expiresAt string
+		if err := rows.Scan(&hold.OrderId, &items, &hold.Status, &expiresAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(items), &hold.Items); err != nil {
+			return nil, err
+		}
+		var err error
+		if hold.ExpiresAt, err = time.ParseInLocation(datetimeFormat, expiresAt, time.Local); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
@@ -0,0 +1,510 @@
+package live
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/seller"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// 방송 예약(POST), 상태별 목록(GET)
+	liveUrl = "/api/live"
+	// 방송 정보와 상품
+	broadcastUrl = "/api/live/:broadcast_id"
+	// 판매자 본인 방송의 상품 추가(POST), 삭제(DELETE)
+	liveProductUrl       = "/api/live/:broadcast_id/product"
+	liveProductDeleteUrl = "/api/live/:broadcast_id/product/:product_id"
+	// 방송 시작, 종료, 상품 고정, vod 등록
+	liveStartUrl = "/api/live/:broadcast_id/start"
+	liveEndUrl   = "/api/live/:broadcast_id/end"
+	livePinUrl   = "/api/live/:broadcast_id/pin"
+	liveVodUrl   = "/api/live/:broadcast_id/vod"
+
+	datetimeFormat = "2006-01-02 15:04:05"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(liveUrl, "POST"), scheduleBroadcast)
+	route.AddRoute(route.NewRouteType(liveUrl, "GET"), getBroadcasts)
+	route.AddRoute(route.NewRouteType(broadcastUrl, "GET"), getBroadcast)
+	route.AddRoute(route.NewRouteType(liveProductUrl, "POST"), addLiveProduct)
+	route.AddRoute(route.NewRouteType(liveProductDeleteUrl, "DELETE"), deleteLiveProduct)
+	route.AddRoute(route.NewRouteType(liveStartUrl, "POST"), startBroadcast)
+	route.AddRoute(route.NewRouteType(liveEndUrl, "POST"), endBroadcast)
+	route.AddRoute(route.NewRouteType(livePinUrl, "POST"), pinProduct)
+	route.AddRoute(route.NewRouteType(liveVodUrl, "PUT"), updateVod)
+}
+
+func scheduleBroadcast(ctx echo.Context) error {
+	resp := &protocol.LiveCreateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	authentication, registered, err := seller.Authentication(customContext.Manager, timer, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if !registered || authentication != seller.SellerAuthenticated {
+		resp.Status = vcomError.SellerNotApproved
+		resp.Detail = vcomError.MessageSellerNotApproved
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+
+	title := ctx.FormValue("title")
+	scheduledAt, err := time.ParseInLocation(datetimeFormat, ctx.FormValue("scheduled_at"), time.Local)
+	if title == "" || err != nil {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	broadcastId := util.RandString()
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertLiveBroadcast,
+		broadcastId, uniqueId, title, scheduledAt.Format(datetimeFormat), StatusScheduled); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.BroadcastId = broadcastId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// status 로 거른 방송 목록. 예정 방송은 가까운 순, 나머지는 최근 순이다.
+func getBroadcasts(ctx echo.Context) error {
+	resp := &protocol.LiveListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	status := StatusLive
+	orderBy := "b.started_at DESC, b.broadcast_id"
+	switch ctx.QueryParam("status") {
+	case "scheduled":
+		status = StatusScheduled
+		orderBy = "b.scheduled_at ASC, b.broadcast_id"
+	case "ended":
+		status = StatusEnded
+		orderBy = "b.ended_at DESC, b.broadcast_id"
+	}
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, fmt.Sprintf(query.SelectLiveBroadcasts, orderBy),
+		status, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Broadcasts = make([]protocol.LiveBroadcastInfo, 0)
+	for rows.Next() {
+		broadcast := protocol.LiveBroadcastInfo{}
+		if err := scanBroadcast(rows, &broadcast); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Broadcasts = append(resp.Broadcasts, broadcast)
+	}
+	if len(resp.Broadcasts) > count {
+		resp.Broadcasts = resp.Broadcasts[:count]
+		resp.HasNext = true
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getBroadcast(ctx echo.Context) error {
+	resp := &protocol.LiveResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcastId := ctx.Param("broadcast_id")
+	broadcast, err := Find(customContext.Manager, timer, broadcastId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if broadcast == nil {
+		resp.Status = vcomError.LiveNotFound
+		resp.Detail = vcomError.MessageLiveNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectLiveProducts, broadcastId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Products = make([]protocol.LiveProductInfo, 0)
+	for rows.Next() {
+		p := protocol.LiveProductInfo{}
+		if err := rows.Scan(&p.ProductId, &p.Title, &p.BasePrice, &p.LivePrice, &p.LivePriceMinutes, &p.LivePriceActive); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		p.Pinned = p.ProductId == broadcast.PinnedProductId
+		resp.Products = append(resp.Products, p)
+	}
+
+	resp.Broadcast = *broadcast
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 판매자 본인의 방송인지 확인한다. 실패하면 응답할 http status code 를 돌려준다.
+func validateOwner(customContext *context.CustomContext, resp *protocol.BaseResponse, timer *time.Timer) (*protocol.LiveBroadcastInfo, int) {
+	uniqueId, err := customContext.ValidateSession(customContext.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return nil, http.StatusUnauthorized
+	}
+
+	broadcast, err := Find(customContext.Manager, timer, customContext.Param("broadcast_id"))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return nil, context.SetQueryError(resp, err)
+	}
+	if broadcast == nil {
+		resp.Status = vcomError.LiveNotFound
+		resp.Detail = vcomError.MessageLiveNotFound
+		return nil, http.StatusNotFound
+	}
+	if broadcast.SellerId != uniqueId {
+		resp.Status = vcomError.PermissionDenied
+		resp.Detail = vcomError.MessagePermissionDenied
+		return nil, http.StatusForbidden
+	}
+	return broadcast, http.StatusOK
+}
+
+// 방송 상태가 맞지 않아 바뀐 row 가 없으면 LiveInvalidStatus 로 응답한다.
+func execTransition(ctx echo.Context, resp *protocol.BaseResponse, timer *time.Timer, transitionQuery string, args ...interface{}) (bool, error) {
+	customContext := ctx.(*context.CustomContext)
+	res, err := database.Exec(customContext.Manager, timer, transitionQuery, args...)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return false, ctx.JSON(context.SetQueryError(resp, err), resp)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		resp.Status = vcomError.LiveInvalidStatus
+		resp.Detail = vcomError.MessageLiveInvalidStatus
+		return false, ctx.JSON(http.StatusConflict, resp)
+	}
+	return true, nil
+}
+
+// 라이브 가격은 선택이다. live_price_minutes 가 0 이면 방송 내내 라이브 가격이 적용된다.
+func addLiveProduct(ctx echo.Context) error {
+	resp := &protocol.LiveUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcast, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+	if Status(broadcast.Status) == StatusEnded {
+		resp.Status = vcomError.LiveInvalidStatus
+		resp.Detail = vcomError.MessageLiveInvalidStatus
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	livePrice, minutes := 0, 0
+	var err error
+	if value := ctx.FormValue("live_price"); value != "" {
+		if livePrice, err = strconv.Atoi(value); err != nil || livePrice < 0 {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+	if value := ctx.FormValue("live_price_minutes"); value != "" {
+		if minutes, err = strconv.Atoi(value); err != nil || minutes < 0 {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+
+	// 본인의 상품만 방송에 올릴 수 있다.
+	productId := ctx.FormValue("product_id")
+
+	// 라이브 가격에도 옵션 추가 금액이 붙는다. 어느 옵션을 골라도 0 원 아래로 내려가면 안 된다.
+	if livePrice > 0 {
+		skus, err := product.Skus(customContext.Manager, timer, productId)
+		if err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		for _, sku := range skus {
+			if livePrice+sku.PriceDelta < 0 {
+				resp.Status = vcomError.InvalidParameter
+				resp.Detail = vcomError.MessageInvalidParameter
+				return ctx.JSON(http.StatusBadRequest, resp)
+			}
+		}
+	}
+
+	res, err := database.Exec(customContext.Manager, timer, query.UpsertLiveProduct,
+		broadcast.BroadcastId, livePrice, minutes, productId, broadcast.SellerId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		resp.Status = vcomError.ProductNotFound
+		resp.Detail = vcomError.MessageProductNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func deleteLiveProduct(ctx echo.Context) error {
+	resp := &protocol.LiveUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcast, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+
+	productId := ctx.Param("product_id")
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteLiveProduct, broadcast.BroadcastId, productId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if broadcast.PinnedProductId == productId {
+		if _, err := database.Exec(customContext.Manager, timer, query.UpdateLivePin, "", broadcast.BroadcastId); err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		Publish(broadcast.BroadcastId, protocol.LiveChatMessage{Type: MessagePin})
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// playback_url 은 송출 서버가 만든 HLS 주소이다.
+func startBroadcast(ctx echo.Context) error {
+	resp := &protocol.LiveUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcast, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+	playbackUrl := ctx.FormValue("playback_url")
+	if playbackUrl == "" {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	if ok, err := execTransition(ctx, &resp.BaseResponse, timer, query.UpdateLiveStart,
+		StatusLive, playbackUrl, broadcast.BroadcastId, StatusScheduled); !ok {
+		return err
+	}
+
+	customContext.Log.Info("live started. broadcast_id: ", broadcast.BroadcastId, ", seller: ", broadcast.SellerId)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// vod_url 은 종료할 때 주거나 나중에 vod api 로 등록한다.
+func endBroadcast(ctx echo.Context) error {
+	resp := &protocol.LiveUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcast, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+
+	if ok, err := execTransition(ctx, &resp.BaseResponse, timer, query.UpdateLiveEnd,
+		StatusEnded, ctx.FormValue("vod_url"), broadcast.BroadcastId, StatusLive); !ok {
+		return err
+	}
+	Publish(broadcast.BroadcastId, protocol.LiveChatMessage{Type: MessageEnd})
+
+	customContext.Log.Info("live ended. broadcast_id: ", broadcast.BroadcastId, ", seller: ", broadcast.SellerId)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 방송중에 상품을 화면에 고정한다. product_id 가 비어 있으면 고정을 푼다.
+func pinProduct(ctx echo.Context) error {
+	resp := &protocol.LiveUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcast, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+	if Status(broadcast.Status) != StatusLive {
+		resp.Status = vcomError.LiveNotOnAir
+		resp.Detail = vcomError.MessageLiveNotOnAir
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	productId := ctx.FormValue("product_id")
+	var err error
+	if productId == "" {
+		_, err = database.Exec(customContext.Manager, timer, query.UpdateLivePin, "", broadcast.BroadcastId)
+	} else {
+		var res sql.Result
+		res, err = database.Exec(customContext.Manager, timer, query.UpdateLivePinProduct,
+			productId, broadcast.BroadcastId, productId)
+		if err == nil {
+			if affected, _ := res.RowsAffected(); affected == 0 && broadcast.PinnedProductId != productId {
+				resp.Status = vcomError.ProductNotFound
+				resp.Detail = vcomError.MessageProductNotFound
+				return ctx.JSON(http.StatusNotFound, resp)
+			}
+		}
+	}
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	Publish(broadcast.BroadcastId, protocol.LiveChatMessage{Type: MessagePin, ProductId: productId})
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func updateVod(ctx echo.Context) error {
+	resp := &protocol.LiveUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	broadcast, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+	vodUrl := ctx.FormValue("vod_url")
+	if vodUrl == "" {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	if ok, err := execTransition(ctx, &resp.BaseResponse, timer, query.UpdateLiveVod,
+		vodUrl, broadcast.BroadcastId, StatusEnded); !ok {
+		return err
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
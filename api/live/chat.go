@@ -0,0 +1,192 @@
+package live
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 방송 채팅 websocket. session_token 이 없으면 읽기만 할 수 있다.
+	chatUrl = "/api/live/:broadcast_id/chat"
+
+	// chat message 종류
+	MessageChat   = "chat"
+	MessagePin    = "pin"    // 판매자가 상품을 고정했다. product_id 가 비어 있으면 고정 해제.
+	MessageEnd    = "end"    // 방송이 끝났다. 이후 연결이 닫힌다.
+	MessageViewer = "viewer" // 접속자 수
+
+	maxChatLength    = 200
+	clientBufferSize = 64
+	writeTimeout     = 5 * time.Second
+	minChatInterval  = 500 * time.Millisecond
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(chatUrl, "GET"), joinChat)
+}
+
+type client struct {
+	uniqueId string
+	send     chan protocol.LiveChatMessage
+}
+
+type room struct {
+	clients map[*client]bool
+}
+
+// 방송별 채팅방. 서버 한 대 안에서만 메시지를 주고받는다.
+var roomLock = sync.Mutex{}
+var rooms = map[string]*room{}
+
+func join(broadcastId string, c *client) int {
+	roomLock.Lock()
+	defer roomLock.Unlock()
+	r, ok := rooms[broadcastId]
+	if !ok {
+		r = &room{clients: map[*client]bool{}}
+		rooms[broadcastId] = r
+	}
+	r.clients[c] = true
+	return len(r.clients)
+}
+
+func leave(broadcastId string, c *client) int {
+	roomLock.Lock()
+	defer roomLock.Unlock()
+	r, ok := rooms[broadcastId]
+	if !ok || !r.clients[c] {
+		return 0
+	}
+	delete(r.clients, c)
+	close(c.send)
+	if len(r.clients) == 0 {
+		delete(rooms, broadcastId)
+	}
+	return len(r.clients)
+}
+
+// 채팅방의 모든 접속자에게 보낸다. 받는 쪽이 밀려 있으면 그 접속자에게는 버린다.
+func Publish(broadcastId string, message protocol.LiveChatMessage) {
+	roomLock.Lock()
+	defer roomLock.Unlock()
+	r, ok := rooms[broadcastId]
+	if !ok {
+		return
+	}
+	if message.Created == "" {
+		message.Created = time.Now().Format("2006-01-02 15:04:05")
+	}
+	for c := range r.clients {
+		select {
+		case c.send <- message:
+		default:
+		}
+	}
+}
+
+func joinChat(ctx echo.Context) error {
+	resp := &protocol.BaseResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId := ""
+	if token := ctx.QueryParam("session_token"); token != "" {
+		var err error
+		if uniqueId, err = customContext.ValidateSession(token, timer); err != nil {
+			customContext.Log.Error("validate session failed. err: ", err)
+			resp.Status = vcomError.SessionValidationFailed
+			resp.Detail = vcomError.MessageInvalidSession
+			return ctx.JSON(http.StatusUnauthorized, resp)
+		}
+	}
+
+	broadcastId := ctx.Param("broadcast_id")
+	broadcast, err := Find(customContext.Manager, timer, broadcastId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(resp, err), resp)
+	}
+	if broadcast == nil || Status(broadcast.Status) != StatusLive {
+		resp.Status = vcomError.LiveNotOnAir
+		resp.Detail = vcomError.MessageLiveNotOnAir
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	// 앱은 Origin 을 보내지 않으므로 확인하지 않는다.
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			serveChat(ws, broadcastId, uniqueId)
+		},
+	}
+	server.ServeHTTP(ctx.Response(), ctx.Request())
+	return nil
+}
+
+func serveChat(ws *websocket.Conn, broadcastId, uniqueId string) {
+	defer ws.Close()
+
+	c := &client{uniqueId: uniqueId, send: make(chan protocol.LiveChatMessage, clientBufferSize)}
+	viewers := join(broadcastId, c)
+	Publish(broadcastId, protocol.LiveChatMessage{Type: MessageViewer, Viewers: viewers})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for message := range c.send {
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := websocket.JSON.Send(ws, message); err != nil {
+				return
+			}
+			if message.Type == MessageEnd {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		// 쓰기가 끝나면(방송 종료, 전송 실패) 읽기도 멈추도록 연결을 닫는다.
+		<-done
+		ws.Close()
+	}()
+
+	last := time.Time{}
+	for {
+		message := protocol.LiveChatMessage{}
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			break
+		}
+		// 로그인한 사용자의 일반 채팅만 받는다.
+		text := strings.TrimSpace(message.Message)
+		if uniqueId == "" || message.Type != MessageChat || text == "" || utf8.RuneCountInString(text) > maxChatLength {
+			continue
+		}
+		if now := time.Now(); now.Sub(last) >= minChatInterval {
+			last = now
+			Publish(broadcastId, protocol.LiveChatMessage{Type: MessageChat, UniqueId: uniqueId, Message: text})
+		}
+	}
+
+	viewers = leave(broadcastId, c)
+	Publish(broadcastId, protocol.LiveChatMessage{Type: MessageViewer, Viewers: viewers})
+	<-done
+}
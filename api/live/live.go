@@ -0,0 +1,75 @@
+package live
+
+import (
+	"database/sql"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"time"
+)
+
+type Status int
+
+const (
+	StatusScheduled = Status(0) // 방송 예정
+	StatusLive      = Status(1) // 방송중
+	StatusEnded     = Status(2) // 방송 종료. vod_url 이 있으면 다시 볼 수 있다.
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusScheduled:
+		return "Scheduled"
+	case StatusLive:
+		return "Live"
+	case StatusEnded:
+		return "Ended"
+	}
+	return "Unknown"
+}
+
+// 방송 하나를 읽는다. 없으면 nil 을 돌려준다.
+func Find(m database.Manager, timer *time.Timer, broadcastId string) (*protocol.LiveBroadcastInfo, error) {
+	rows, err := database.Select(m, timer, query.SelectLiveBroadcast, broadcastId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	broadcast := &protocol.LiveBroadcastInfo{}
+	if err := scanBroadcast(rows, broadcast); err != nil {
+		return nil, err
+	}
+	return broadcast, nil
+}
+
+// column 순서: broadcast_id, seller_id, channel_name, title, scheduled_at, status, playback_url, vod_url, started_at, ended_at, pinned_product_id
+func scanBroadcast(rows *sql.Rows, broadcast *protocol.LiveBroadcastInfo) error {
+	return rows.Scan(&broadcast.BroadcastId, &broadcast.SellerId, &broadcast.ChannelName, &broadcast.Title,
+		&broadcast.ScheduledAt, &broadcast.Status, &broadcast.PlaybackUrl, &broadcast.VodUrl,
+		&broadcast.StartedAt, &broadcast.EndedAt, &broadcast.PinnedProductId)
+}
+
+// 방송중인 방송에서 상품에 걸린 라이브 가격. 여러 방송에 걸려 있으면 가장 싼 가격이다.
+// 라이브 가격은 방송이 시작된 뒤 live_price_minutes 동안만 유효하고, 0 이면 방송이 끝날 때까지 유효하다.
+func Price(m database.Manager, timer *time.Timer, productId string) (int, bool, error) {
+	rows, err := database.Select(m, timer, query.SelectActiveLivePrice, productId, StatusLive)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	price := sql.NullInt64{}
+	if rows.Next() {
+		if err := rows.Scan(&price); err != nil {
+			return 0, false, err
+		}
+	}
+	if !price.Valid {
+		return 0, false, rows.Err()
+	}
+	return int(price.Int64), true, nil
+}
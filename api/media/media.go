@@ -0,0 +1,99 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/api/asset"
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrSaveFailed = errors.New("media save failed")
+
+// media_info_json, video_list_json 에 저장된 media id 목록을 꺼낸다.
+func ParseIndices(indicesJson string) []string {
+	indices := types.MediaIndices{}
+	if indicesJson == "" {
+		return indices.MediaIds
+	}
+	if err := json.Unmarshal([]byte(indicesJson), &indices); err != nil {
+		return nil
+	}
+	return indices.MediaIds
+}
+
+// media id 목록을 video_info 에서 찾아 MediaInfo 로 확장한다. 결과는 media id 로 찾을 수 있다.
+func Expand(m database.Manager, timer *time.Timer, mediaIds []string) (map[string]types.MediaInfo, error) {
+	infos := map[string]types.MediaInfo{}
+	if len(mediaIds) == 0 {
+		return infos, nil
+	}
+
+	args := make([]interface{}, 0, len(mediaIds))
+	for _, id := range mediaIds {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	rows, err := database.Select(m, timer, fmt.Sprintf(query.SelectVideoInfos, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		info := types.MediaInfo{}
+		if err := rows.Scan(&info.MediaId, &info.MediaUrl, &info.ServeReady); err != nil {
+			return nil, err
+		}
+		info.Kind = types.MediaTypeOf(info.MediaUrl).String()
+		infos[info.MediaId] = info
+	}
+	return infos, rows.Err()
+}
+
+// Expand 결과에서 indices 순서대로 MediaInfo 를 고른다. 찾지 못한 media 는 건너뛴다.
+func Pick(infos map[string]types.MediaInfo, mediaIds []string) []types.MediaInfo {
+	picked := make([]types.MediaInfo, 0, len(mediaIds))
+	for _, id := range mediaIds {
+		if info, ok := infos[id]; ok {
+			picked = append(picked, info)
+		}
+	}
+	return picked
+}
+
+// 업로드된 파일들을 media asset 으로 저장하고 video_info 에 등록한다. 리뷰, 상품과 같은 경로를 쓴다.
+// 파일 저장에 실패하면 ErrSaveFailed, 허용하지 않는 확장자면 asset.ErrUnsupportedType 을 돌려준다.
+func Save(a asset.Asset, m database.Manager, timer *time.Timer, files []*multipart.FileHeader) (types.MediaIndices, error) {
+	indices := types.MediaIndices{MediaIds: make([]string, 0, len(files))}
+	for _, file := range files {
+		src, err := file.Open()
+		if err != nil {
+			return indices, err
+		}
+		id := util.RandString()
+		name := id + filepath.Ext(file.Filename)
+		_, err = a.SaveAsset(asset.KindMedia, name, src)
+		src.Close()
+		if err == asset.ErrUnsupportedType {
+			return indices, err
+		}
+		if err != nil {
+			log.Error("media save failed. err: ", err)
+			return indices, ErrSaveFailed
+		}
+		if _, err := database.Exec(m, timer, query.InsertVideoList, id, a.AssetUrl(asset.KindMedia, name)); err != nil {
+			return indices, err
+		}
+		indices.MediaIds = append(indices.MediaIds, id)
+	}
+	return indices, nil
+}
@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/firebase"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Recipient struct {
+	UniqueId string
+	Email    string // email 채널에만 채워진다.
+}
+
+// push, email 등 알림을 밖으로 보내는 방법
+type Channel interface {
+	Send(recipient Recipient, n Notification) error
+}
+
+// 사용자 기기의 FCM token 저장소
+type TokenStore interface {
+	Tokens(timer *time.Timer, uniqueId string) ([]string, error)
+	Remove(timer *time.Timer, tokens []string) error
+}
+
+type dbTokenStore struct {
+	dbManager database.Manager
+}
+
+func (s *dbTokenStore) Tokens(timer *time.Timer, uniqueId string) ([]string, error) {
+	return deviceTokens(s.dbManager, timer, uniqueId)
+}
+
+func (s *dbTokenStore) Remove(timer *time.Timer, tokens []string) error {
+	return removeDeviceTokens(s.dbManager, timer, tokens)
+}
+
+// 사용자가 등록한 모든 기기로 보낸다. FCM 이 유효하지 않다고 알려준 token 은 지운다.
+type fcmChannel struct {
+	tokens TokenStore
+	sender firebase.Sender
+}
+
+func NewFcmChannel(dbManager database.Manager, sender firebase.Sender) Channel {
+	return NewFcmChannelWith(&dbTokenStore{dbManager: dbManager}, sender)
+}
+
+// token 저장소를 직접 지정한다. test 에 사용한다.
+func NewFcmChannelWith(tokens TokenStore, sender firebase.Sender) Channel {
+	return &fcmChannel{tokens: tokens, sender: sender}
+}
+
+func (c *fcmChannel) Send(recipient Recipient, n Notification) error {
+	timer := time.NewTimer(deliverTimeout)
+	defer timer.Stop()
+
+	tokens, err := c.tokens.Tokens(timer, recipient.UniqueId)
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	invalid, err := c.sender.SendMulticast(tokens, firebase.PushMessage{
+		Title: n.Title,
+		Body:  n.Body,
+		Data: map[string]string{
+			"notification_id": n.NotificationId,
+			"category":        n.Category,
+			"reference_id":    n.ReferenceId,
+		},
+	})
+	if len(invalid) > 0 {
+		log.Info("remove invalid device tokens. unique_id: ", recipient.UniqueId, ", count: ", len(invalid))
+		if removeErr := c.tokens.Remove(timer, invalid); removeErr != nil {
+			log.Error("device token remove failed. err: ", removeErr)
+		}
+	}
+	return err
+}
+
+func deviceTokens(m database.Manager, timer *time.Timer, uniqueId string) ([]string, error) {
+	rows, err := database.Select(m, timer, query.SelectDeviceTokens, uniqueId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]string, 0)
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func removeDeviceTokens(m database.Manager, timer *time.Timer, tokens []string) error {
+	args := make([]interface{}, 0, len(tokens))
+	for _, token := range tokens {
+		args = append(args, token)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tokens)), ",")
+	_, err := database.Exec(m, timer, fmt.Sprintf(query.DeleteDeviceTokens, placeholders), args...)
+	return err
+}
+
+type smtpChannel struct {
+	address string
+	auth    smtp.Auth
+	from    string
+}
+
+func NewSmtpChannel(address, user, password, from string) Channel {
+	c := &smtpChannel{address: address, from: from}
+	if user != "" {
+		host, _, _ := net.SplitHostPort(address)
+		c.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return c
+}
+
+func (c *smtpChannel) Send(recipient Recipient, n Notification) error {
+	// 제목에 줄바꿈이 들어가면 header 가 깨진다.
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(n.Title)
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		c.from, recipient.Email, subject, n.Body)
+	return smtp.SendMail(c.address, c.auth, c.from, []string{recipient.Email}, []byte(body))
+}
+
+// 보내지 않고 로그만 남긴다. 개발 환경과 test 에 사용한다.
+type logChannel struct {
+	name string
+}
+
+func NewLogChannel(name string) Channel {
+	return &logChannel{name: name}
+}
+
+func (c *logChannel) Send(recipient Recipient, n Notification) error {
+	log.Infof("%s notification. unique_id: %s, email: %s, category: %s, title: %s", c.name,
+		recipient.UniqueId, recipient.Email, n.Category, n.Title)
+	return nil
+}
@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"github.com/4538cgy/backend-second/api/firebase"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryTokenStore struct {
+	lock   sync.Mutex
+	tokens map[string][]string // unique id -> token
+}
+
+func (s *memoryTokenStore) Tokens(timer *time.Timer, uniqueId string) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.tokens[uniqueId]...), nil
+}
+
+func (s *memoryTokenStore) Remove(timer *time.Timer, tokens []string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	removed := map[string]bool{}
+	for _, token := range tokens {
+		removed[token] = true
+	}
+	for uniqueId, saved := range s.tokens {
+		kept := make([]string, 0, len(saved))
+		for _, token := range saved {
+			if !removed[token] {
+				kept = append(kept, token)
+			}
+		}
+		s.tokens[uniqueId] = kept
+	}
+	return nil
+}
+
+func TestFcmChannelSendsToAllDevices(t *testing.T) {
+	store := &memoryTokenStore{tokens: map[string][]string{
+		"user":  {"phone", "tablet"},
+		"other": {"other-phone"},
+	}}
+	sender := firebase.NewFakeSender()
+	channel := NewFcmChannelWith(store, sender)
+
+	n := Notification{NotificationId: "n1", Category: CategoryOrder, Title: "title", Body: "body", ReferenceId: "order"}
+	if err := channel.Send(Recipient{UniqueId: "user"}, n); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := sender.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent: %d", len(sent))
+	}
+	tokens := append([]string{}, sent[0].Tokens...)
+	sort.Strings(tokens)
+	if len(tokens) != 2 || tokens[0] != "phone" || tokens[1] != "tablet" {
+		t.Fatalf("tokens: %v", tokens)
+	}
+	message := sent[0].Message
+	if message.Title != "title" || message.Data["reference_id"] != "order" || message.Data["category"] != CategoryOrder {
+		t.Fatalf("message: %+v", message)
+	}
+}
+
+func TestFcmChannelRemovesInvalidTokens(t *testing.T) {
+	store := &memoryTokenStore{tokens: map[string][]string{
+		"user":  {"phone", "old-phone", "tablet"},
+		"other": {"other-phone"},
+	}}
+	sender := firebase.NewFakeSender()
+	sender.MarkInvalid("old-phone")
+	channel := NewFcmChannelWith(store, sender)
+
+	if err := channel.Send(Recipient{UniqueId: "user"}, Notification{Category: CategoryOrder}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, _ := store.Tokens(nil, "user")
+	if len(tokens) != 2 || tokens[0] != "phone" || tokens[1] != "tablet" {
+		t.Fatalf("user tokens: %v", tokens)
+	}
+	if tokens, _ := store.Tokens(nil, "other"); len(tokens) != 1 {
+		t.Fatalf("other tokens: %v", tokens)
+	}
+}
+
+func TestFcmChannelWithoutDevices(t *testing.T) {
+	sender := firebase.NewFakeSender()
+	channel := NewFcmChannelWith(&memoryTokenStore{tokens: map[string][]string{}}, sender)
+
+	if err := channel.Send(Recipient{UniqueId: "user"}, Notification{Category: CategoryOrder}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := sender.Sent(); len(sent) != 0 {
+		t.Fatalf("sent: %v", sent)
+	}
+}
+
+// email 을 끈 알림은 database 없이 push 채널로만 보낸다.
+func TestHandlerDeliversPush(t *testing.T) {
+	store := &memoryTokenStore{tokens: map[string][]string{"user": {"phone", "old-phone"}}}
+	sender := firebase.NewFakeSender()
+	sender.MarkInvalid("old-phone")
+	handler := NewNotificationHandlerWith(nil, NewFcmChannelWith(store, sender), NewLogChannel(channelSmtp), 1).(*notificationHandler)
+
+	handler.deliver(delivery{
+		notification: Notification{UniqueId: "user", Category: CategoryWishlist, Title: "restock"},
+		push:         true,
+	})
+
+	sent := sender.Sent()
+	if len(sent) != 1 || len(sent[0].Tokens) != 1 || sent[0].Tokens[0] != "phone" {
+		t.Fatalf("sent: %+v", sent)
+	}
+	if tokens, _ := store.Tokens(nil, "user"); len(tokens) != 1 {
+		t.Fatalf("tokens: %v", tokens)
+	}
+}
@@ -0,0 +1,245 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/api/firebase"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"time"
+)
+
+// 알림 분류. 사용자는 분류별로 앱 내 알림, push, email 을 끌 수 있다.
+const (
+	CategorySeller   = "seller"   // 판매자 등록 심사 결과, 받은 반품, 교환 요청
+	CategoryOrder    = "order"    // 주문 상태 변경
+	CategoryReview   = "review"   // 내 리뷰에 판매자 답글
+	CategoryWishlist = "wishlist" // 찜한 상품 가격 인하, 재입고
+)
+
+var Categories = []string{CategorySeller, CategoryOrder, CategoryReview, CategoryWishlist}
+
+const (
+	channelFcm  = "fcm"
+	channelSmtp = "smtp"
+	channelLog  = "log"
+	channelFake = "fake"
+
+	defaultQueueSize = 1000
+	deliverTimeout   = 10 * time.Second
+)
+
+var ErrUnknownCategory = errors.New("unknown notification category")
+
+type Notification struct {
+	NotificationId string
+	UniqueId       string // 받는 사용자
+	Category       string
+	Title          string
+	Body           string
+	ReferenceId    string // 알림을 누르면 이동할 대상. order_id, review_id, product_id 등
+}
+
+// 분류별 수신 설정. 설정한 적이 없으면 모두 받는다.
+type Setting struct {
+	Category string
+	InApp    bool
+	Push     bool
+	Email    bool
+}
+
+func ValidCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+type Notifier interface {
+	Start()
+	// 앱 내 알림을 저장하고 push, email 발송을 대기열에 넣는다. 발송 결과는 기다리지 않는다.
+	Notify(n Notification) error
+}
+
+type delivery struct {
+	notification Notification
+	push         bool
+	email        bool
+}
+
+type notificationHandler struct {
+	dbManager database.Manager
+	push      Channel
+	email     Channel
+	queue     chan delivery
+}
+
+func NewNotificationHandler(cfg *config.Config, dbManager database.Manager, fb firebase.Firebase) (Notifier, error) {
+	h := &notificationHandler{dbManager: dbManager}
+	switch cfg.Notification.PushChannel {
+	case channelFcm:
+		h.push = NewFcmChannel(dbManager, fb)
+	case channelFake:
+		h.push = NewFcmChannel(dbManager, firebase.NewFakeSender())
+	case channelLog, "":
+		h.push = NewLogChannel(channelFcm)
+	default:
+		return nil, fmt.Errorf("wrong push channel: %s", cfg.Notification.PushChannel)
+	}
+
+	switch cfg.Notification.EmailChannel {
+	case channelSmtp:
+		if cfg.Notification.SmtpAddress == "" {
+			return nil, errors.New("smtp address is empty")
+		}
+		h.email = NewSmtpChannel(cfg.Notification.SmtpAddress, cfg.Notification.SmtpUser,
+			cfg.Notification.SmtpPassword, cfg.Notification.SmtpFrom)
+	case channelLog, "":
+		h.email = NewLogChannel(channelSmtp)
+	default:
+		return nil, fmt.Errorf("wrong email channel: %s", cfg.Notification.EmailChannel)
+	}
+
+	queueSize := cfg.Notification.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	h.queue = make(chan delivery, queueSize)
+	return h, nil
+}
+
+// 발송 채널을 직접 지정한다. test 에 사용한다.
+func NewNotificationHandlerWith(dbManager database.Manager, push, email Channel, queueSize int) Notifier {
+	return &notificationHandler{dbManager: dbManager, push: push, email: email, queue: make(chan delivery, queueSize)}
+}
+
+func (h *notificationHandler) Start() {
+	go func() {
+		for d := range h.queue {
+			h.deliver(d)
+		}
+	}()
+}
+
+func (h *notificationHandler) Notify(n Notification) error {
+	if !ValidCategory(n.Category) {
+		return ErrUnknownCategory
+	}
+
+	timer := time.NewTimer(deliverTimeout)
+	defer timer.Stop()
+
+	setting, err := FindSetting(h.dbManager, timer, n.UniqueId, n.Category)
+	if err != nil {
+		return err
+	}
+
+	if setting.InApp {
+		n.NotificationId = util.RandString()
+		if _, err := database.Exec(h.dbManager, timer, query.InsertNotification,
+			n.NotificationId, n.UniqueId, n.Category, n.Title, n.Body, n.ReferenceId); err != nil {
+			return err
+		}
+	}
+	if !setting.Push && !setting.Email {
+		return nil
+	}
+
+	select {
+	case h.queue <- delivery{notification: n, push: setting.Push, email: setting.Email}:
+	default:
+		log.Warning("notification queue is full. unique_id: ", n.UniqueId, ", category: ", n.Category)
+	}
+	return nil
+}
+
+func (h *notificationHandler) deliver(d delivery) {
+	n := d.notification
+	recipient := Recipient{UniqueId: n.UniqueId}
+	if d.email {
+		timer := time.NewTimer(deliverTimeout)
+		email, err := userEmail(h.dbManager, timer, n.UniqueId)
+		timer.Stop()
+		if err != nil {
+			log.Error("user email read failed. unique_id: ", n.UniqueId, ", err: ", err)
+		}
+		recipient.Email = email
+	}
+
+	if d.push {
+		if err := h.push.Send(recipient, n); err != nil {
+			log.Error("push failed. unique_id: ", n.UniqueId, ", category: ", n.Category, ", err: ", err)
+		}
+	}
+	if d.email && recipient.Email != "" {
+		if err := h.email.Send(recipient, n); err != nil {
+			log.Error("email failed. unique_id: ", n.UniqueId, ", category: ", n.Category, ", err: ", err)
+		}
+	}
+}
+
+func userEmail(m database.Manager, timer *time.Timer, uniqueId string) (string, error) {
+	rows, err := database.Select(m, timer, query.SelectUserEmail, uniqueId)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var email string
+	if rows.Next() {
+		if err := rows.Scan(&email); err != nil {
+			return "", err
+		}
+	}
+	return email, rows.Err()
+}
+
+// 분류의 수신 설정. 저장된 설정이 없으면 모두 켠 상태이다.
+func FindSetting(m database.Manager, timer *time.Timer, uniqueId, category string) (Setting, error) {
+	settings, err := Settings(m, timer, uniqueId)
+	if err != nil {
+		return Setting{}, err
+	}
+	for _, setting := range settings {
+		if setting.Category == category {
+			return setting, nil
+		}
+	}
+	return Setting{}, ErrUnknownCategory
+}
+
+// 모든 분류의 수신 설정을 Categories 순서로 돌려준다.
+func Settings(m database.Manager, timer *time.Timer, uniqueId string) ([]Setting, error) {
+	rows, err := database.Select(m, timer, query.SelectNotificationSettings, uniqueId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := map[string]Setting{}
+	for rows.Next() {
+		setting := Setting{}
+		if err := rows.Scan(&setting.Category, &setting.InApp, &setting.Push, &setting.Email); err != nil {
+			return nil, err
+		}
+		saved[setting.Category] = setting
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	settings := make([]Setting, 0, len(Categories))
+	for _, category := range Categories {
+		setting, ok := saved[category]
+		if !ok {
+			setting = Setting{Category: category, InApp: true, Push: true, Email: true}
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
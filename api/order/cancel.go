@@ -0,0 +1,169 @@
+package order
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/inventory"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/payment"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 구매자의 주문 취소. 배송이 시작된 line 이 있으면 취소할 수 없다.
+	orderCancelUrl = "/api/order/:order_id/cancel"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(orderCancelUrl, "POST"), cancelOrder)
+}
+
+func cancelOrder(ctx echo.Context) error {
+	resp := &protocol.OrderCancelResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	orderId := ctx.Param("order_id")
+	lines, err := FindLines(customContext.Manager, timer, orderId, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if len(lines) == 0 {
+		resp.Status = vcomError.OrderNotFound
+		resp.Detail = vcomError.MessageOrderNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	// 이전 취소에서 일부만 취소되었을 수 있으니 이미 취소된 line 은 건너뛴다.
+	paid := false
+	for _, line := range lines {
+		if line.Status == StatusCancelled {
+			continue
+		}
+		paid = paid || line.Status == StatusPaid
+		if line.Status != StatusOrdered && line.Status != StatusPaid {
+			resp.Status = vcomError.OrderNotCancellable
+			resp.Detail = vcomError.MessageOrderNotCancellable
+			return ctx.JSON(http.StatusConflict, resp)
+		}
+	}
+
+	// 확인한 뒤 판매자가 송장을 등록했을 수 있으니 취소된 line 의 재고만 되돌린다.
+	cancelled := make([]cartItem, 0, len(lines))
+	resp.CancelledLineIds = make([]string, 0, len(lines))
+	remaining := 0
+	for index, line := range lines {
+		if line.Status == StatusCancelled {
+			continue
+		}
+		res, err := database.Exec(customContext.Manager, timer, query.UpdateOrderLineCancelled,
+			StatusCancelled, line.OrderLineId, StatusOrdered, StatusPaid)
+		if err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			remaining++
+			continue
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			remaining++
+			continue
+		}
+		lines[index].Status = StatusCancelled
+		cancelled = append(cancelled, cartItem{productId: line.ProductId, skuId: line.SkuId, quantity: line.Quantity})
+		resp.CancelledLineIds = append(resp.CancelledLineIds, line.OrderLineId)
+	}
+	// 결제된 주문은 예약이 이미 확정되었으므로 취소된 line 의 재고만 돌려준다.
+	// 결제 전이면 예약을 그대로 두었다가 모두 취소되거나 예약이 만료될 때 한 번에 돌려준다.
+	if paid {
+		restoreStock(customContext, timer, cancelled)
+	} else if len(cancelled) > 0 && remaining == 0 {
+		switch err := customContext.ReleaseStock(timer, orderId); err {
+		case nil:
+			checkWishlist(customContext, cancelled)
+		case inventory.ErrNoHold:
+			// 예약 없이 재고를 바로 뺀 이전 주문
+			restoreStock(customContext, timer, cancelled)
+		default:
+			customContext.Log.Error("stock release failed. order_id: ", orderId, ", err: ", err)
+		}
+	}
+	if err := SyncStatus(customContext.Manager, timer, orderId); err != nil {
+		customContext.Log.Error("order status sync failed. order_id: ", orderId, ", err: ", err)
+	}
+
+	// 결제된 주문이면 취소된 line 마다 환불한다. line 단위로 한 번만 환불되므로 이전에 실패한 환불도 다시 시도한다.
+	if err := refundCancelled(customContext, timer, orderId, lines); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("order cancel refund failed. order_id: ", orderId, ", err: ", err)
+		return ctx.JSON(SetPaymentError(&resp.BaseResponse, err), resp)
+	}
+	if len(cancelled) == 0 && remaining > 0 {
+		resp.Status = vcomError.OrderNotCancellable
+		resp.Detail = vcomError.MessageOrderNotCancellable
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+	if remaining > 0 {
+		// 쿠폰 할인은 주문 전체에 나눠져 있으므로 일부만 취소되면 쿠폰을 돌려주지 않는다.
+		customContext.Log.Warning("order partially cancelled. order_id: ", orderId, ", cancelled: ", len(cancelled), ", remaining: ", remaining)
+	} else {
+		releaseCoupons(customContext, timer, orderId)
+	}
+
+	if len(cancelled) > 0 {
+		if err := customContext.Notify(notification.Notification{
+			UniqueId:    uniqueId,
+			Category:    notification.CategoryOrder,
+			Title:       "주문이 취소되었습니다.",
+			Body:        fmt.Sprintf("취소된 상품 %d개", len(cancelled)),
+			ReferenceId: orderId,
+		}); err != nil {
+			customContext.Log.Error("notify order cancel failed. order_id: ", orderId, ", err: ", err)
+		}
+	}
+
+	resp.OrderId = orderId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 취소된 line 의 결제 금액을 환불한다. 결제하지 않은 주문이면 아무것도 하지 않는다.
+func refundCancelled(customContext *context.CustomContext, timer *time.Timer, orderId string, lines []Line) error {
+	p, err := payment.Find(customContext.Manager, timer, orderId)
+	if err != nil || p == nil {
+		return err
+	}
+	for _, line := range lines {
+		if line.Status != StatusCancelled {
+			continue
+		}
+		if err := payment.Refund(customContext.Manager, timer, customContext.Provider, orderId, line.OrderLineId,
+			int(line.PaidAmount()), "주문 취소"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
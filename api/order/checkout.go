@@ -0,0 +1,369 @@
+package order
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/address"
+	"github.com/4538cgy/backend-second/api/category"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/inventory"
+	"github.com/4538cgy/backend-second/api/live"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/promotion"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// cart 에 담긴 상품들로 주문을 만든다. cart_ids 와 user_coupon_ids 는 , 로 구분한다.
+	checkoutUrl = "/api/order/checkout"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(checkoutUrl, "POST"), checkout)
+}
+
+type cartItem struct {
+	cartId    string
+	productId string
+	skuId     string
+	quantity  int
+	sellerId  string
+	basePrice int
+	unitPrice int
+	sku       *product.Sku
+
+	categoryIds    []string
+	discount       int // 쿠폰 할인 합
+	sellerDiscount int // 그 중 판매자 쿠폰 몫
+}
+
+func checkout(ctx echo.Context) error {
+	resp := &protocol.CheckoutResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	cartIds := splitIds(ctx.FormValue("cart_ids"))
+	if len(cartIds) == 0 {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	// address_id 가 없으면 기본 배송지로 보낸다.
+	destination, err := address.Find(customContext.Manager, timer, uniqueId, ctx.FormValue("address_id"))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if destination == nil {
+		resp.Status = vcomError.AddressNotFound
+		resp.Detail = vcomError.MessageAddressNotFound
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	items, err := selectCartItems(customContext.Manager, timer, uniqueId, cartIds)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if len(items) != len(cartIds) {
+		resp.Status = vcomError.CartItemNotFound
+		resp.Detail = vcomError.MessageCartItemNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	// cart 에 담은 뒤 옵션이 바뀌었거나 재고가 줄었을 수 있으니 다시 확인한다.
+	for index := range items {
+		item := &items[index]
+		sku, err := product.FindSku(customContext.Manager, timer, item.skuId)
+		if err == nil && sku.ProductId != item.productId {
+			err = product.ErrOptionNotFound
+		}
+		if err == nil && sku.Stock < item.quantity {
+			err = product.ErrOutOfStock
+		}
+		if err != nil {
+			return ctx.JSON(setSkuError(&resp.BaseResponse, err), resp)
+		}
+		item.sku = sku
+
+		// 방송중인 라이브 가격이 있으면 상품 가격 대신 쓴다.
+		price, onAir, err := live.Price(customContext.Manager, timer, item.productId)
+		if err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		if !onAir {
+			price = item.basePrice
+		}
+		item.unitPrice = price + sku.PriceDelta
+		// 음수 금액의 line 은 같은 주문의 다른 판매자 금액을 깎는다.
+		if item.unitPrice < 0 {
+			customContext.Log.Error("negative unit price. sku_id: ", item.skuId, ", price: ", price,
+				", price_delta: ", sku.PriceDelta)
+			resp.Status = vcomError.InvalidOption
+			resp.Detail = vcomError.MessageInvalidOption
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+
+	coupons, err := applyCoupons(customContext, timer, uniqueId, items, splitIds(ctx.FormValue("user_coupon_ids")))
+	if err != nil {
+		return ctx.JSON(promotion.SetCouponError(&resp.BaseResponse, err), resp)
+	}
+
+	// 결제할 때까지 재고를 잡아둔다. 결제하지 않으면 만료된 뒤 풀린다.
+	orderId := util.RandString()
+	stockItems := make([]inventory.Item, 0, len(items))
+	for _, item := range items {
+		stockItems = append(stockItems, inventory.Item{SkuId: item.skuId, Quantity: item.quantity})
+	}
+	if err := customContext.ReserveStock(timer, orderId, stockItems); err != nil {
+		return ctx.JSON(setSkuError(&resp.BaseResponse, err), resp)
+	}
+
+	// 다른 주문에 동시에 쓰였을 수 있으니 주문을 만들기 전에 쿠폰을 먼저 사용 처리한다.
+	for _, userCouponId := range coupons {
+		if err := promotion.Use(customContext.Manager, timer, userCouponId, uniqueId, orderId); err != nil {
+			releaseCoupons(customContext, timer, orderId)
+			releaseStock(customContext, timer, orderId, items)
+			return ctx.JSON(promotion.SetCouponError(&resp.BaseResponse, err), resp)
+		}
+	}
+
+	totalPrice, discount := 0, 0
+	for _, item := range items {
+		totalPrice += item.unitPrice*item.quantity - item.discount
+		discount += item.discount
+	}
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertOrder,
+		orderId, uniqueId, totalPrice, StatusOrdered, destination.Recipient, destination.Phone,
+		destination.Zipcode, destination.RoadAddress, destination.DetailAddress); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		releaseCoupons(customContext, timer, orderId)
+		releaseStock(customContext, timer, orderId, items)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	for _, item := range items {
+		if _, err := database.Exec(customContext.Manager, timer, query.InsertOrderLine,
+			util.RandString(), orderId, uniqueId, item.productId, item.skuId, item.sellerId,
+			item.quantity, item.unitPrice, item.discount, item.sellerDiscount, StatusOrdered); err != nil {
+			// TODO rollback needed
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+	}
+
+	args := append([]interface{}{uniqueId}, toArgs(cartIds)...)
+	if _, err := database.Exec(customContext.Manager, timer,
+		fmt.Sprintf(query.DeleteCartItems, placeholders(len(cartIds))), args...); err != nil {
+		// 주문은 만들어졌으니 cart 가 남아 있어도 실패로 돌려주지 않는다.
+		customContext.Log.Error("cart delete failed. err: ", err)
+	}
+
+	checkWishlist(customContext, items)
+	if err := customContext.Notify(notification.Notification{
+		UniqueId:    uniqueId,
+		Category:    notification.CategoryOrder,
+		Title:       "주문이 접수되었습니다.",
+		Body:        fmt.Sprintf("결제 금액 %d원", totalPrice),
+		ReferenceId: orderId,
+	}); err != nil {
+		customContext.Log.Error("notify order failed. order_id: ", orderId, ", err: ", err)
+	}
+
+	resp.OrderId = orderId
+	resp.TotalPrice = totalPrice
+	resp.Discount = discount
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func selectCartItems(m database.Manager, timer *time.Timer, uniqueId string, cartIds []string) ([]cartItem, error) {
+	args := append([]interface{}{uniqueId}, toArgs(cartIds)...)
+	rows, err := database.Select(m, timer, fmt.Sprintf(query.SelectCartItems, placeholders(len(cartIds))), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]cartItem, 0)
+	for rows.Next() {
+		item := cartItem{}
+		if err := rows.Scan(&item.cartId, &item.productId, &item.skuId, &item.quantity,
+			&item.sellerId, &item.basePrice); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// 쿠폰을 순서대로 적용한다. 뒤의 쿠폰은 앞의 쿠폰 할인을 뺀 금액을 기준으로 계산된다.
+// 쿠폰 하나는 주문에 한 장만 쓸 수 있다. 적용할 user_coupon_id 들을 돌려준다.
+func applyCoupons(customContext *context.CustomContext, timer *time.Timer, uniqueId string, items []cartItem, userCouponIds []string) ([]string, error) {
+	if len(userCouponIds) == 0 {
+		return nil, nil
+	}
+
+	var tree *category.Tree
+	applied := map[string]bool{}
+	now := time.Now()
+	for _, userCouponId := range userCouponIds {
+		uc, err := promotion.FindUnused(customContext.Manager, timer, userCouponId, uniqueId)
+		if err != nil {
+			return nil, err
+		}
+		c := uc.Coupon
+		if applied[c.CouponId] {
+			return nil, promotion.ErrCouponNotApplicable
+		}
+		applied[c.CouponId] = true
+
+		if c.Scope == promotion.ScopeCategory {
+			if tree == nil {
+				if tree, err = loadCategories(customContext, timer, items); err != nil {
+					return nil, err
+				}
+			}
+			c.ExpandCategories(tree)
+		}
+
+		lines := make([]promotion.Line, 0, len(items))
+		for _, item := range items {
+			lines = append(lines, promotion.Line{
+				ProductId:   item.productId,
+				SellerId:    item.sellerId,
+				CategoryIds: item.categoryIds,
+				Amount:      item.unitPrice*item.quantity - item.discount,
+			})
+		}
+		discounts, err := promotion.Apply(c, lines, now)
+		if err != nil {
+			return nil, err
+		}
+		for index := range items {
+			items[index].discount += discounts[index]
+			if c.IssuerId != "" {
+				items[index].sellerDiscount += discounts[index]
+			}
+		}
+	}
+	return userCouponIds, nil
+}
+
+// 카테고리 쿠폰을 적용하기 위해 상품들의 카테고리를 읽는다.
+func loadCategories(customContext *context.CustomContext, timer *time.Timer, items []cartItem) (*category.Tree, error) {
+	tree, err := category.Load(customContext.Manager, timer)
+	if err != nil {
+		return nil, err
+	}
+	for index := range items {
+		if items[index].categoryIds, err = category.OfProduct(customContext.Manager, timer, items[index].productId); err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+func releaseCoupons(customContext *context.CustomContext, timer *time.Timer, orderId string) {
+	if err := promotion.Release(customContext.Manager, timer, orderId); err != nil {
+		customContext.Log.Error("coupon release failed. order_id: ", orderId, ", err: ", err)
+	}
+}
+
+// 주문의 재고 예약을 풀어 재고를 돌려준다.
+func releaseStock(customContext *context.CustomContext, timer *time.Timer, orderId string, items []cartItem) {
+	if err := customContext.ReleaseStock(timer, orderId); err != nil {
+		customContext.Log.Error("stock release failed. order_id: ", orderId, ", err: ", err)
+		return
+	}
+	checkWishlist(customContext, items)
+}
+
+// 예약 없이 sku 재고를 직접 돌려준다.
+func restoreStock(customContext *context.CustomContext, timer *time.Timer, items []cartItem) {
+	for _, item := range items {
+		if err := product.IncreaseStock(customContext.Manager, timer, item.skuId, item.quantity); err != nil {
+			customContext.Log.Error("stock restore failed. sku_id: ", item.skuId, ", quantity: ", item.quantity, ", err: ", err)
+		}
+	}
+	checkWishlist(customContext, items)
+}
+
+// 품절되거나 다시 재고가 생긴 상품을 찜한 사용자에게 알린다. 알림은 background 에서 보낸다.
+func checkWishlist(customContext *context.CustomContext, items []cartItem) {
+	for _, item := range items {
+		customContext.ProductChanged(item.productId)
+	}
+}
+
+// product 의 sku 관련 에러를 응답 status 로 변환한다. http status code 를 돌려준다.
+func setSkuError(resp *protocol.BaseResponse, err error) int {
+	switch err {
+	case product.ErrOptionNotFound:
+		resp.Status = vcomError.OptionNotFound
+		resp.Detail = vcomError.MessageOptionNotFound
+		return http.StatusBadRequest
+	case product.ErrOutOfStock, inventory.ErrOutOfStock:
+		resp.Status = vcomError.OutOfStock
+		resp.Detail = vcomError.MessageOutOfStock
+		return http.StatusConflict
+	}
+	log.Error("database operation failed. err: ", err)
+	return context.SetQueryError(resp, err)
+}
+
+// , 로 구분된 id 목록. 빈 값과 중복은 뺀다.
+func splitIds(ids string) []string {
+	result := make([]string, 0)
+	seen := map[string]bool{}
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+func toArgs(ids []string) []interface{} {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return args
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", count), ",")
+}
@@ -0,0 +1,46 @@
+package order
+
+import (
+	"github.com/4538cgy/backend-second/api/alert"
+	"github.com/4538cgy/backend-second/api/inventory"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/promotion"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"time"
+)
+
+// 결제 전에 재고 예약이 만료된 주문을 취소한다. 재고는 inventory sweeper 가 이미 돌려주었다.
+func ExpireFunc(m database.Manager, notifier notification.Notifier, watcher alert.Watcher) inventory.ExpireFunc {
+	return func(timer *time.Timer, orderId string) error {
+		if _, err := database.Exec(m, timer, query.UpdateOrderLinesStatus, StatusCancelled, orderId, StatusOrdered); err != nil {
+			return err
+		}
+		if err := SyncStatus(m, timer, orderId); err != nil {
+			return err
+		}
+		if err := promotion.Release(m, timer, orderId); err != nil {
+			log.Error("coupon release failed. order_id: ", orderId, ", err: ", err)
+		}
+
+		buyer, err := Buyer(m, timer, orderId)
+		if err != nil || buyer == "" {
+			return err
+		}
+		lines, err := FindLines(m, timer, orderId, buyer)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			watcher.ProductChanged(line.ProductId)
+		}
+
+		return notifier.Notify(notification.Notification{
+			UniqueId:    buyer,
+			Category:    notification.CategoryOrder,
+			Title:       "결제 시간이 지나 주문이 취소되었습니다.",
+			ReferenceId: orderId,
+		})
+	}
+}
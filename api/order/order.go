@@ -0,0 +1,147 @@
+package order
+
+import (
+	"database/sql"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/query"
+	"time"
+)
+
+type Status int
+
+const (
+	StatusOrdered   = Status(0) // 주문 생성. 결제 대기
+	StatusPaid      = Status(1) // 결제 완료
+	StatusShipping  = Status(2) // 배송중
+	StatusDelivered = Status(3) // 배송 완료
+	StatusCancelled = Status(4) // 취소
+	StatusReturned  = Status(5) // 반품 완료. 환불되었다.
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOrdered:
+		return "Ordered"
+	case StatusPaid:
+		return "Paid"
+	case StatusShipping:
+		return "Shipping"
+	case StatusDelivered:
+		return "Delivered"
+	case StatusCancelled:
+		return "Cancelled"
+	case StatusReturned:
+		return "Returned"
+	}
+	return "Unknown"
+}
+
+// 주문의 상품 한 줄. 상품, 옵션, 수량 단위로 배송과 정산이 이루어진다.
+type Line struct {
+	OrderLineId string
+	OrderId     string
+	UniqueId    string // 구매자
+	ProductId   string
+	SkuId       string
+	SellerId    string // 판매자 unique_id
+	Quantity    int
+	UnitPrice   int64 // 원 단위
+	// 쿠폰 할인 합과 그 중 판매자 쿠폰 몫
+	Discount       int64
+	SellerDiscount int64
+	Status         Status
+}
+
+// 구매자가 낸 금액
+func (l *Line) PaidAmount() int64 {
+	return l.UnitPrice*int64(l.Quantity) - l.Discount
+}
+
+// 판매자의 판매 금액. 플랫폼 쿠폰 할인은 플랫폼이 부담한다.
+func (l *Line) SaleAmount() int64 {
+	return l.UnitPrice*int64(l.Quantity) - l.SellerDiscount
+}
+
+// 구매자의 주문 line 을 찾는다. 없으면 nil 을 돌려준다.
+func FindLine(m database.Manager, timer *time.Timer, orderLineId, uniqueId string) (*Line, error) {
+	rows, err := database.Select(m, timer, query.SelectOrderLine, orderLineId, uniqueId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	line := &Line{}
+	if err := scanLine(rows, line); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// 구매자의 주문 line 들. 주문이 없으면 빈 slice 를 돌려준다.
+func FindLines(m database.Manager, timer *time.Timer, orderId, uniqueId string) ([]Line, error) {
+	rows, err := database.Select(m, timer, query.SelectOrderLines, orderId, uniqueId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]Line, 0)
+	for rows.Next() {
+		line := Line{}
+		if err := scanLine(rows, &line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// 판매자 입장에서 주문 line 을 찾는다. 없으면 nil 을 돌려준다.
+func FindSellerLine(m database.Manager, timer *time.Timer, orderLineId, sellerId string) (*Line, error) {
+	rows, err := database.Select(m, timer, query.SelectSellerOrderLine, orderLineId, sellerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	line := &Line{}
+	if err := scanLine(rows, line); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// column 순서: order_line_id, order_id, unique_id, product_id, sku_id, seller_id, quantity, unit_price, discount, seller_discount, status
+func scanLine(rows *sql.Rows, line *Line) error {
+	return rows.Scan(&line.OrderLineId, &line.OrderId, &line.UniqueId, &line.ProductId, &line.SkuId,
+		&line.SellerId, &line.Quantity, &line.UnitPrice, &line.Discount, &line.SellerDiscount, &line.Status)
+}
+
+// 주문한 사용자. 주문이 없으면 빈 문자열을 돌려준다.
+func Buyer(m database.Manager, timer *time.Timer, orderId string) (string, error) {
+	rows, err := database.Select(m, timer, query.SelectOrderBuyer, orderId)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var buyer string
+	if rows.Next() {
+		if err := rows.Scan(&buyer); err != nil {
+			return "", err
+		}
+	}
+	return buyer, rows.Err()
+}
+
+// line status 가 바뀐 뒤 주문 status 를 다시 계산한다.
+func SyncStatus(m database.Manager, timer *time.Timer, orderId string) error {
+	_, err := database.Exec(m, timer, query.UpdateOrderStatusFromLines, orderId, StatusCancelled, StatusCancelled, orderId)
+	return err
+}
@@ -0,0 +1,144 @@
+package order
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/inventory"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/payment"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 주문 결제. payment_token 은 PG 결제창에서 받은 값이다.
+	orderPayUrl = "/api/order/:order_id/pay"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(orderPayUrl, "POST"), payOrder)
+}
+
+func payOrder(ctx echo.Context) error {
+	resp := &protocol.OrderPayResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	orderId := ctx.Param("order_id")
+	lines, err := FindLines(customContext.Manager, timer, orderId, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if len(lines) == 0 {
+		resp.Status = vcomError.OrderNotFound
+		resp.Detail = vcomError.MessageOrderNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	amount := 0
+	for _, line := range lines {
+		if line.Status != StatusOrdered {
+			resp.Status = vcomError.OrderNotPayable
+			resp.Detail = vcomError.MessageOrderNotPayable
+			return ctx.JSON(http.StatusConflict, resp)
+		}
+		amount += int(line.PaidAmount())
+	}
+
+	paymentKey, err := customContext.ApprovePayment(orderId, amount, ctx.FormValue("payment_token"))
+	if err != nil {
+		customContext.Log.Warning("payment approve failed. order_id: ", orderId, ", err: ", err)
+		return ctx.JSON(SetPaymentError(&resp.BaseResponse, err), resp)
+	}
+	// 결제를 기다리는 동안 예약이 만료되어 재고가 풀렸다면 결제를 취소한다.
+	if err := customContext.CommitStock(timer, orderId); err != nil {
+		if _, refundErr := customContext.RefundPayment(paymentKey, amount, "재고 예약 만료"); refundErr != nil {
+			// TODO rollback needed
+			customContext.Log.Error("payment cancel failed. order_id: ", orderId, ", payment_key: ", paymentKey, ", err: ", refundErr)
+		}
+		if err == inventory.ErrNotReserved {
+			resp.Status = vcomError.OrderExpired
+			resp.Detail = vcomError.MessageOrderExpired
+			return ctx.JSON(http.StatusConflict, resp)
+		}
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := payment.Record(customContext.Manager, timer, orderId, paymentKey, amount); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("payment record failed. order_id: ", orderId, ", payment_key: ", paymentKey, ", err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if _, err := database.Exec(customContext.Manager, timer, query.UpdateOrderLinesStatus,
+		StatusPaid, orderId, StatusOrdered); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := SyncStatus(customContext.Manager, timer, orderId); err != nil {
+		customContext.Log.Error("order status sync failed. order_id: ", orderId, ", err: ", err)
+	}
+
+	if err := customContext.Notify(notification.Notification{
+		UniqueId:    uniqueId,
+		Category:    notification.CategoryOrder,
+		Title:       "결제가 완료되었습니다.",
+		Body:        fmt.Sprintf("결제 금액 %d원", amount),
+		ReferenceId: orderId,
+	}); err != nil {
+		customContext.Log.Error("notify order paid failed. order_id: ", orderId, ", err: ", err)
+	}
+
+	resp.OrderId = orderId
+	resp.Amount = amount
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// payment 의 에러를 응답 status 로 변환한다. http status code 를 돌려준다.
+func SetPaymentError(resp *protocol.BaseResponse, err error) int {
+	switch err {
+	case payment.ErrPaymentDeclined:
+		resp.Status = vcomError.PaymentDeclined
+		resp.Detail = vcomError.MessagePaymentDeclined
+		return http.StatusBadRequest
+	case payment.ErrPaymentNotFound:
+		resp.Status = vcomError.PaymentNotFound
+		resp.Detail = vcomError.MessagePaymentNotFound
+		return http.StatusConflict
+	case payment.ErrRefundExceeded, payment.ErrProviderRejected:
+		resp.Status = vcomError.RefundFailed
+		resp.Detail = vcomError.MessageRefundFailed
+		return http.StatusBadGateway
+	case payment.ErrRefundPending:
+		resp.Status = vcomError.RefundPending
+		resp.Detail = vcomError.MessageRefundPending
+		return http.StatusConflict
+	}
+	log.Error("database operation failed. err: ", err)
+	return context.SetQueryError(resp, err)
+}
@@ -0,0 +1,51 @@
+package payment
+
+import (
+	"github.com/4538cgy/backend-second/util"
+	"sync"
+)
+
+// PG 없이 결제를 확인하기 위한 구현.
+// Decline 으로 지정한 token 이 아니면 모두 승인하고, 환불은 승인된 금액 안에서만 허용한다.
+type FakeProvider struct {
+	lock     sync.Mutex
+	declined map[string]bool
+	payments map[string]int // payment key -> 남은 금액
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		declined: map[string]bool{},
+		payments: map[string]int{},
+	}
+}
+
+func (f *FakeProvider) Decline(paymentToken string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.declined[paymentToken] = true
+}
+
+func (f *FakeProvider) ApprovePayment(orderId string, amount int, paymentToken string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if paymentToken == "" || f.declined[paymentToken] {
+		return "", ErrPaymentDeclined
+	}
+	paymentKey := "fake_" + util.RandString()
+	f.payments[paymentKey] = amount
+	return paymentKey, nil
+}
+
+// 서버가 재시작되어 모르는 결제 key 는 승인된 결제로 간주한다.
+func (f *FakeProvider) RefundPayment(paymentKey string, amount int, reason string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if remain, ok := f.payments[paymentKey]; ok {
+		if amount > remain {
+			return "", ErrRefundExceeded
+		}
+		f.payments[paymentKey] = remain - amount
+	}
+	return "fake_refund_" + util.RandString(), nil
+}
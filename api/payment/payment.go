@@ -0,0 +1,166 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"time"
+)
+
+const (
+	providerFake = "fake"
+)
+
+var (
+	ErrPaymentDeclined  = errors.New("payment declined")
+	ErrPaymentNotFound  = errors.New("payment not found")
+	ErrRefundExceeded   = errors.New("refund amount exceeds paid amount")
+	ErrProviderRejected = errors.New("refund rejected by payment provider")
+	ErrRefundPending    = errors.New("previous refund result is unknown")
+)
+
+// PG 연동. payment_token 은 client 가 PG 결제창에서 받은 값이다.
+type Provider interface {
+	// 결제를 승인한다. PG 의 결제 key 를 돌려준다.
+	ApprovePayment(orderId string, amount int, paymentToken string) (string, error)
+	// 결제의 일부 혹은 전부를 취소한다. PG 의 취소 key 를 돌려준다.
+	RefundPayment(paymentKey string, amount int, reason string) (string, error)
+}
+
+// fake 는 어떤 token 이든 승인하므로 설정에 직접 적었을 때만 쓴다.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	switch cfg.Payment.Provider {
+	case providerFake:
+		log.Warning("fake payment provider is used. every payment token will be approved.")
+		return NewFakeProvider(), nil
+	case "":
+		return nil, errors.New("payment provider is not configured")
+	}
+	// TODO PG 업체가 정해지면 추가.
+	return nil, fmt.Errorf("wrong payment provider: %s", cfg.Payment.Provider)
+}
+
+// 주문의 결제 정보
+type Payment struct {
+	OrderId    string
+	PaymentKey string
+	Amount     int
+	Refunded   int // 지금까지 환불한 금액
+}
+
+// 승인된 결제를 기록한다.
+func Record(m database.Manager, timer *time.Timer, orderId, paymentKey string, amount int) error {
+	_, err := database.Exec(m, timer, query.InsertPayment, orderId, paymentKey, amount)
+	return err
+}
+
+// 주문의 결제 정보. 결제하지 않은 주문이면 nil 을 돌려준다.
+func Find(m database.Manager, timer *time.Timer, orderId string) (*Payment, error) {
+	rows, err := database.Select(m, timer, query.SelectPayment, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	p := &Payment{}
+	if err := rows.Scan(&p.OrderId, &p.PaymentKey, &p.Amount, &p.Refunded); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type RefundStatus int
+
+const (
+	RefundStatusPending = RefundStatus(0) // PG 취소를 요청하기 전이거나 결과를 기록하지 못했다.
+	RefundStatusDone    = RefundStatus(1) // PG 취소 완료
+)
+
+// 주문 결제에서 amount 만큼 환불한다. referenceId 는 환불 사유가 된 주문 취소 line 이나 반품 요청이다.
+// 같은 referenceId 로 이미 환불했다면 다시 환불하지 않는다. pending 으로 남은 환불이 있으면 ErrRefundPending 이다.
+func Refund(m database.Manager, timer *time.Timer, provider Provider, orderId, referenceId string, amount int, reason string) error {
+	if amount <= 0 {
+		return nil
+	}
+	rows, err := database.Select(m, timer, query.SelectPaymentRefund, referenceId)
+	if err != nil {
+		return err
+	}
+	var refundId string
+	var status RefundStatus
+	found := rows.Next()
+	if found {
+		err = rows.Scan(&refundId, &status)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if found {
+		if status == RefundStatusDone {
+			return nil
+		}
+		return ErrRefundPending
+	}
+
+	p, err := Find(m, timer, orderId)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return ErrPaymentNotFound
+	}
+	if p.Refunded+amount > p.Amount {
+		return ErrRefundExceeded
+	}
+
+	// PG 취소 뒤에 기록이 실패해도 다시 환불되지 않도록 pending 기록을 먼저 남긴다.
+	// reference_id 가 unique 이므로 동시에 들어온 같은 환불은 하나만 넣을 수 있다.
+	refundId = util.RandString()
+	if _, err := database.Exec(m, timer, query.InsertPaymentRefund,
+		refundId, orderId, referenceId, amount, reason, RefundStatusPending); err != nil {
+		return err
+	}
+	// 다른 환불과 겹치지 않도록 환불 금액을 먼저 잡아두고, PG 취소가 실패하면 되돌린다.
+	res, err := database.Exec(m, timer, query.UpdatePaymentRefunded, amount, orderId, amount)
+	if err == nil {
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			err = ErrRefundExceeded
+		}
+	}
+	if err != nil {
+		discardRefund(m, timer, refundId)
+		return err
+	}
+	refundKey, err := provider.RefundPayment(p.PaymentKey, amount, reason)
+	if err != nil {
+		log.Error("payment refund failed. order_id: ", orderId, ", amount: ", amount, ", err: ", err)
+		if _, err := database.Exec(m, timer, query.UpdatePaymentRefunded, -amount, orderId, -amount); err != nil {
+			// TODO rollback needed
+			log.Error("payment refunded amount restore failed. order_id: ", orderId, ", err: ", err)
+			return ErrProviderRejected
+		}
+		discardRefund(m, timer, refundId)
+		return ErrProviderRejected
+	}
+	if _, err := database.Exec(m, timer, query.UpdatePaymentRefundDone, refundKey, RefundStatusDone, refundId); err != nil {
+		// PG 취소는 끝났으므로 실패로 돌려주지 않는다. pending 으로 남아 있어 다시 환불되지 않는다.
+		log.Error("payment refund record failed. order_id: ", orderId, ", refund_key: ", refundKey, ", err: ", err)
+	}
+	return nil
+}
+
+// PG 를 부르지 않았거나 PG 가 거절한 pending 환불을 지워 다시 시도할 수 있게 한다.
+func discardRefund(m database.Manager, timer *time.Timer, refundId string) {
+	if _, err := database.Exec(m, timer, query.DeletePaymentRefund, refundId, RefundStatusPending); err != nil {
+		// 남은 pending 기록은 확인 전까지 같은 환불을 막는다.
+		log.Error("pending refund delete failed. refund_id: ", refundId, ", err: ", err)
+	}
+}
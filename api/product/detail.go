@@ -0,0 +1,133 @@
+package product
+
+import (
+	"encoding/json"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// 상품 상세. 옵션과 sku 별 가격, 재고, 찜한 사용자 수를 함께 준다.
+	productUrl = "/api/product/:product_id"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(productUrl, "GET"), getProduct)
+}
+
+func getProduct(ctx echo.Context) error {
+	resp := &protocol.ProductDetailResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	productId := ctx.Param("product_id")
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProduct, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	products, videoIndices, err := Scan(rows)
+	if err != nil {
+		customContext.Log.Error("scan failed. err: ", err)
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+	if len(products) == 0 {
+		resp.Status = vcomError.ProductNotFound
+		resp.Detail = vcomError.MessageProductNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	if err := ExpandVideos(customContext.Manager, timer, products, videoIndices); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	resp.Product = products[0]
+
+	groups, err := optionGroups(customContext.Manager, timer, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	skus, err := Skus(customContext.Manager, timer, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	rows, err = database.Select(customContext.Manager, timer, query.SelectWishlistCount, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if rows.Next() {
+		rows.Scan(&resp.LikeCount)
+	}
+	rows.Close()
+
+	resp.Options = groups
+	resp.Skus = skuInfos(skus, resp.Product.BasePrice)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// option_json 에 저장된 옵션 그룹
+func optionGroups(m database.Manager, timer *time.Timer, productId string) ([]types.OptionGroup, error) {
+	rows, err := database.Select(m, timer, query.SelectProductOption, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := types.ProductOptions{Groups: make([]types.OptionGroup, 0)}
+	if !rows.Next() {
+		return options.Groups, rows.Err()
+	}
+	var optionJson string
+	if err := rows.Scan(&optionJson); err != nil {
+		return nil, err
+	}
+	if optionJson != "" {
+		if err := json.Unmarshal([]byte(optionJson), &options); err != nil {
+			log.Warning("option json broken. product_id: ", productId, ", err: ", err)
+		}
+	}
+	return options.Groups, nil
+}
+
+func skuInfos(skus []Sku, basePrice int) []protocol.SkuInfo {
+	infos := make([]protocol.SkuInfo, 0, len(skus))
+	for _, sku := range skus {
+		selected := map[string]string{}
+		values, _ := url.ParseQuery(sku.OptionKey)
+		for name := range values {
+			selected[name] = values.Get(name)
+		}
+		infos = append(infos, protocol.SkuInfo{
+			SkuId:    sku.SkuId,
+			Selected: selected,
+			Price:    basePrice + sku.PriceDelta,
+			Stock:    sku.Stock,
+		})
+	}
+	return infos
+}
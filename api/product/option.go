@@ -0,0 +1,181 @@
+package product
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrInvalidOption  = errors.New("invalid option json")
+	ErrOptionNotFound = errors.New("option not found")
+	ErrOutOfStock     = errors.New("out of stock")
+)
+
+// 상품의 옵션 조합 하나. 재고는 sku 단위로 관리한다.
+type Sku struct {
+	SkuId      string
+	ProductId  string
+	OptionKey  string
+	PriceDelta int
+	Stock      int
+}
+
+// 고른 옵션을 그룹 이름 순으로 정렬해 하나의 문자열로 만든다. 옵션이 없는 상품은 빈 문자열이다.
+func OptionKey(selected map[string]string) string {
+	values := url.Values{}
+	for name, value := range selected {
+		values.Set(name, value)
+	}
+	return values.Encode()
+}
+
+// postProduct 의 option_json 을 검증한다. 비어 있으면 base_amount 를 재고로 가진 옵션 없는 sku 하나를 만든다.
+// 모든 sku 는 그룹마다 정의된 값을 하나씩 골라야 하고 같은 조합이 두 번 나올 수 없다.
+func ParseOptions(optionJson string, baseAmount int) (*types.ProductOptions, error) {
+	options := &types.ProductOptions{Groups: make([]types.OptionGroup, 0), Skus: make([]types.OptionSku, 0)}
+	if optionJson == "" {
+		if baseAmount < 0 {
+			return nil, ErrInvalidOption
+		}
+		options.Skus = append(options.Skus, types.OptionSku{Selected: map[string]string{}, Stock: baseAmount})
+		return options, nil
+	}
+	if err := json.Unmarshal([]byte(optionJson), options); err != nil {
+		return nil, ErrInvalidOption
+	}
+
+	groups := map[string]map[string]bool{}
+	for _, group := range options.Groups {
+		if group.Name == "" || len(group.Values) == 0 || groups[group.Name] != nil {
+			return nil, ErrInvalidOption
+		}
+		values := map[string]bool{}
+		for _, value := range group.Values {
+			if value == "" || values[value] {
+				return nil, ErrInvalidOption
+			}
+			values[value] = true
+		}
+		groups[group.Name] = values
+	}
+
+	if len(options.Skus) == 0 {
+		return nil, ErrInvalidOption
+	}
+	keys := map[string]bool{}
+	for index := range options.Skus {
+		sku := &options.Skus[index]
+		if sku.Selected == nil {
+			sku.Selected = map[string]string{}
+		}
+		if sku.Stock < 0 || len(sku.Selected) != len(groups) {
+			return nil, ErrInvalidOption
+		}
+		for name, value := range sku.Selected {
+			if !groups[name][value] {
+				return nil, ErrInvalidOption
+			}
+		}
+		key := OptionKey(sku.Selected)
+		if keys[key] {
+			return nil, ErrInvalidOption
+		}
+		keys[key] = true
+	}
+	return options, nil
+}
+
+// cart 의 selected_json 을 읽는다. 옵션이 없는 상품은 비어 있어도 된다.
+func ParseSelected(selectedJson string) (map[string]string, error) {
+	selected := map[string]string{}
+	if selectedJson == "" {
+		return selected, nil
+	}
+	if err := json.Unmarshal([]byte(selectedJson), &selected); err != nil {
+		return nil, ErrOptionNotFound
+	}
+	return selected, nil
+}
+
+// 상품의 모든 sku 를 저장한다.
+func SaveSkus(m database.Manager, timer *time.Timer, productId string, options *types.ProductOptions) error {
+	for _, sku := range options.Skus {
+		if _, err := database.Exec(m, timer, query.InsertProductSku,
+			util.RandString(), productId, OptionKey(sku.Selected), sku.PriceDelta, sku.Stock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanSku(m database.Manager, timer *time.Timer, selectQuery string, args ...interface{}) (*Sku, error) {
+	rows, err := database.Select(m, timer, selectQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrOptionNotFound
+	}
+	sku := &Sku{}
+	if err := rows.Scan(&sku.SkuId, &sku.ProductId, &sku.OptionKey, &sku.PriceDelta, &sku.Stock); err != nil {
+		return nil, err
+	}
+	return sku, nil
+}
+
+// 고른 옵션이 상품에 있고 quantity 만큼 재고가 있는지 확인한다.
+func CheckSku(m database.Manager, timer *time.Timer, productId, selectedJson string, quantity int) (*Sku, error) {
+	selected, err := ParseSelected(selectedJson)
+	if err != nil {
+		return nil, err
+	}
+	sku, err := scanSku(m, timer, query.SelectProductSkuByOption, productId, OptionKey(selected))
+	if err != nil {
+		return nil, err
+	}
+	if sku.Stock < quantity {
+		return nil, ErrOutOfStock
+	}
+	return sku, nil
+}
+
+// sku id 로 찾는다. 삭제된 상품의 sku 는 찾지 않는다.
+func FindSku(m database.Manager, timer *time.Timer, skuId string) (*Sku, error) {
+	return scanSku(m, timer, query.SelectProductSku, skuId)
+}
+
+// 상품의 sku 목록
+func Skus(m database.Manager, timer *time.Timer, productId string) ([]Sku, error) {
+	rows, err := database.Select(m, timer, query.SelectProductSkus, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skus := make([]Sku, 0)
+	for rows.Next() {
+		sku := Sku{}
+		if err := rows.Scan(&sku.SkuId, &sku.ProductId, &sku.OptionKey, &sku.PriceDelta, &sku.Stock); err != nil {
+			return nil, err
+		}
+		skus = append(skus, sku)
+	}
+	return skus, rows.Err()
+}
+
+// 재고를 돌려준다. 상품의 base_amount 도 함께 늘린다. 재고를 빼는 것은 api/inventory 의 예약이다.
+func IncreaseStock(m database.Manager, timer *time.Timer, skuId string, quantity int) error {
+	_, err := database.Exec(m, timer, query.IncreaseProductSkuStock, quantity, quantity, skuId)
+	return err
+}
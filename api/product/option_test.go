@@ -0,0 +1,75 @@
+package product
+
+import (
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	const groups = `"groups": [{"name": "color", "values": ["red", "blue"]}, {"name": "size", "values": ["S", "M"]}]`
+	tests := []struct {
+		name       string
+		optionJson string
+		baseAmount int
+		skus       int
+		stock      int // 첫 sku 의 재고
+		err        error
+	}{
+		{name: "empty", optionJson: "", baseAmount: 10, skus: 1, stock: 10},
+		{name: "empty zero stock", optionJson: "", baseAmount: 0, skus: 1, stock: 0},
+		{name: "empty negative stock", optionJson: "", baseAmount: -1, err: ErrInvalidOption},
+		{name: "options", optionJson: `{` + groups + `, "skus": [
+			{"selected": {"color": "red", "size": "S"}, "price_delta": 1000, "stock": 3},
+			{"selected": {"color": "blue", "size": "M"}, "stock": 0}]}`, skus: 2, stock: 3},
+		// 그룹 없이 sku 하나만 있는 상품
+		{name: "no groups", optionJson: `{"skus": [{"stock": 5}]}`, skus: 1, stock: 5},
+		{name: "malformed", optionJson: `{"groups": [`, err: ErrInvalidOption},
+		{name: "wrong type", optionJson: `{"skus": [{"stock": "5"}]}`, err: ErrInvalidOption},
+		{name: "no skus", optionJson: `{` + groups + `, "skus": []}`, err: ErrInvalidOption},
+		{name: "negative stock", optionJson: `{` + groups + `, "skus": [
+			{"selected": {"color": "red", "size": "S"}, "stock": -1}]}`, err: ErrInvalidOption},
+		{name: "duplicate sku", optionJson: `{` + groups + `, "skus": [
+			{"selected": {"color": "red", "size": "S"}, "stock": 1},
+			{"selected": {"size": "S", "color": "red"}, "stock": 2}]}`, err: ErrInvalidOption},
+		{name: "duplicate group", optionJson: `{"groups": [{"name": "color", "values": ["red"]}, {"name": "color", "values": ["blue"]}],
+			"skus": [{"selected": {"color": "red"}, "stock": 1}]}`, err: ErrInvalidOption},
+		{name: "duplicate value", optionJson: `{"groups": [{"name": "color", "values": ["red", "red"]}],
+			"skus": [{"selected": {"color": "red"}, "stock": 1}]}`, err: ErrInvalidOption},
+		{name: "empty group name", optionJson: `{"groups": [{"name": "", "values": ["red"]}],
+			"skus": [{"selected": {"": "red"}, "stock": 1}]}`, err: ErrInvalidOption},
+		{name: "empty group values", optionJson: `{"groups": [{"name": "color", "values": []}],
+			"skus": [{"selected": {}, "stock": 1}]}`, err: ErrInvalidOption},
+		{name: "missing group", optionJson: `{` + groups + `, "skus": [
+			{"selected": {"color": "red"}, "stock": 1}]}`, err: ErrInvalidOption},
+		{name: "unknown value", optionJson: `{` + groups + `, "skus": [
+			{"selected": {"color": "green", "size": "S"}, "stock": 1}]}`, err: ErrInvalidOption},
+		{name: "unknown group", optionJson: `{` + groups + `, "skus": [
+			{"selected": {"color": "red", "fit": "S"}, "stock": 1}]}`, err: ErrInvalidOption},
+	}
+	for _, test := range tests {
+		options, err := ParseOptions(test.optionJson, test.baseAmount)
+		if err != test.err {
+			t.Errorf("%s: err: %v", test.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(options.Skus) != test.skus || options.Skus[0].Stock != test.stock {
+			t.Errorf("%s: skus: %+v", test.name, options.Skus)
+		}
+		for _, sku := range options.Skus {
+			if sku.Selected == nil {
+				t.Errorf("%s: nil selected", test.name)
+			}
+		}
+	}
+}
+
+func TestOptionKey(t *testing.T) {
+	if key := OptionKey(map[string]string{"size": "S", "color": "red"}); key != "color=red&size=S" {
+		t.Fatal("key: ", key)
+	}
+	if key := OptionKey(map[string]string{}); key != "" {
+		t.Fatal("empty key: ", key)
+	}
+}
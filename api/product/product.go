@@ -0,0 +1,64 @@
+package product
+
+import (
+	"database/sql"
+	"github.com/4538cgy/backend-second/api/media"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/protocol"
+	"time"
+)
+
+// 상품 목록 row 를 읽는다.
+// column 순서: product_id, unique_id, channel_name, title, base_price, base_amount, video_list_json, created
+// 각 상품의 video id 목록을 같은 순서로 함께 돌려준다.
+func Scan(rows *sql.Rows) ([]protocol.ProductInfo, [][]string, error) {
+	defer rows.Close()
+
+	products := make([]protocol.ProductInfo, 0)
+	videoIndices := make([][]string, 0)
+	for rows.Next() {
+		product := protocol.ProductInfo{}
+		var videoListJson string
+		if err := rows.Scan(&product.ProductId, &product.SellerId, &product.ChannelName, &product.Title,
+			&product.BasePrice, &product.BaseAmount, &videoListJson, &product.Created); err != nil {
+			return nil, nil, err
+		}
+		products = append(products, product)
+		videoIndices = append(videoIndices, media.ParseIndices(videoListJson))
+	}
+	return products, videoIndices, rows.Err()
+}
+
+// 한 번의 query 로 모든 상품의 video 를 확장한다.
+func ExpandVideos(m database.Manager, timer *time.Timer, products []protocol.ProductInfo, videoIndices [][]string) error {
+	all := make([]string, 0)
+	for _, ids := range videoIndices {
+		all = append(all, ids...)
+	}
+	infos, err := media.Expand(m, timer, all)
+	if err != nil {
+		return err
+	}
+	for index := range products {
+		products[index].Videos = media.Pick(infos, videoIndices[index])
+	}
+	return nil
+}
+
+// page 크기보다 하나 더 읽은 목록을 받아 video 까지 채운 page 를 만든다. 다음 page 여부를 함께 돌려준다.
+func Page(m database.Manager, timer *time.Timer, rows *sql.Rows, count int) ([]protocol.ProductInfo, bool, error) {
+	products, videoIndices, err := Scan(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	hasNext := false
+	if len(products) > count {
+		products = products[:count]
+		videoIndices = videoIndices[:count]
+		hasNext = true
+	}
+	if err := ExpandVideos(m, timer, products, videoIndices); err != nil {
+		return nil, false, err
+	}
+	return products, hasNext, nil
+}
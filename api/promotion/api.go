@@ -0,0 +1,315 @@
+package promotion
+
+import (
+	"github.com/4538cgy/backend-second/api/category"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/seller"
+	"github.com/4538cgy/backend-second/config"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 판매자 쿠폰 생성(POST), 내가 받은 쿠폰 목록(GET). used=true 면 사용한 쿠폰
+	couponUrl = "/api/promotion/coupon"
+	// 쿠폰 정보
+	couponInfoUrl = "/api/promotion/coupon/:coupon_id"
+	// 쿠폰 받기
+	couponIssueUrl = "/api/promotion/coupon/:coupon_id/issue"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(couponUrl, "POST"), createSellerCoupon)
+	route.AddRoute(route.NewRouteType(couponUrl, "GET"), getUserCoupons)
+	route.AddRoute(route.NewRouteType(couponInfoUrl, "GET"), getCoupon)
+	route.AddRoute(route.NewRouteType(couponIssueUrl, "POST"), issueCoupon)
+}
+
+func createSellerCoupon(ctx echo.Context) error {
+	resp := &protocol.CouponResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	authentication, registered, err := seller.Authentication(customContext.Manager, timer, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if !registered || authentication != seller.SellerAuthenticated {
+		resp.Status = vcomError.SellerNotApproved
+		resp.Detail = vcomError.MessageSellerNotApproved
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+
+	return CreateCoupon(ctx, timer, uniqueId)
+}
+
+// form 으로 받은 쿠폰을 만든다. 판매자 api 와 관리자 api 가 같이 쓴다. issuerId 가 비어 있으면 플랫폼 쿠폰이다.
+func CreateCoupon(ctx echo.Context, timer *time.Timer, issuerId string) error {
+	resp := &protocol.CouponResponse{}
+	customContext := ctx.(*context.CustomContext)
+
+	c, ok := readCouponForm(ctx)
+	if !ok {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	c.IssuerId = issuerId
+
+	if c.Scope == ScopeCategory {
+		tree, err := category.Load(customContext.Manager, timer)
+		if err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		if c.ScopeIds, err = category.ParseIds(tree, strings.Join(c.ScopeIds, ",")); err != nil {
+			resp.Status = vcomError.CategoryNotFound
+			resp.Detail = vcomError.MessageCategoryNotFound
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+
+	couponId, err := Create(customContext.Manager, timer, *c)
+	if err == ErrInvalidCoupon {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	c.CouponId = couponId
+
+	customContext.Log.Info("coupon created. issuer: ", issuerId, ", coupon: ", couponId)
+	resp.Coupon = Info(c)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 숫자 값은 비어 있으면 0 이다. 값의 범위는 Coupon.Validate 가 확인한다.
+func readCouponForm(ctx echo.Context) (*Coupon, bool) {
+	c := &Coupon{
+		Name:         ctx.FormValue("name"),
+		DiscountType: ctx.FormValue("discount_type"),
+		Scope:        ctx.FormValue("scope"),
+		ScopeIds:     make([]string, 0),
+	}
+	numbers := map[string]*int{
+		"discount_value":   &c.DiscountValue,
+		"min_order_amount": &c.MinOrderAmount,
+		"max_discount":     &c.MaxDiscount,
+		"per_user_limit":   &c.PerUserLimit,
+	}
+	for key, dest := range numbers {
+		if value := ctx.FormValue(key); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, false
+			}
+			*dest = number
+		}
+	}
+	if c.PerUserLimit == 0 {
+		c.PerUserLimit = 1
+	}
+
+	var err error
+	if c.ValidFrom, err = time.ParseInLocation(DatetimeFormat, ctx.FormValue("valid_from"), time.Local); err != nil {
+		return nil, false
+	}
+	if c.ValidUntil, err = time.ParseInLocation(DatetimeFormat, ctx.FormValue("valid_until"), time.Local); err != nil {
+		return nil, false
+	}
+
+	seen := map[string]bool{}
+	for _, id := range strings.Split(ctx.FormValue("scope_ids"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		c.ScopeIds = append(c.ScopeIds, id)
+	}
+	if c.Scope == "" {
+		c.Scope = ScopeAll
+	}
+	return c, true
+}
+
+func Info(c *Coupon) protocol.CouponInfo {
+	scopeIds := c.ScopeIds
+	if scopeIds == nil {
+		scopeIds = make([]string, 0)
+	}
+	return protocol.CouponInfo{
+		CouponId:       c.CouponId,
+		IssuerId:       c.IssuerId,
+		Name:           c.Name,
+		DiscountType:   c.DiscountType,
+		DiscountValue:  c.DiscountValue,
+		MinOrderAmount: c.MinOrderAmount,
+		MaxDiscount:    c.MaxDiscount,
+		ValidFrom:      c.ValidFrom.Format(DatetimeFormat),
+		ValidUntil:     c.ValidUntil.Format(DatetimeFormat),
+		PerUserLimit:   c.PerUserLimit,
+		Scope:          c.Scope,
+		ScopeIds:       scopeIds,
+	}
+}
+
+// 쿠폰 정보는 로그인 없이 볼 수 있다.
+func getCoupon(ctx echo.Context) error {
+	resp := &protocol.CouponResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	c, err := Find(customContext.Manager, timer, ctx.Param("coupon_id"))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if c == nil {
+		resp.Status = vcomError.CouponNotFound
+		resp.Detail = vcomError.MessageCouponNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	resp.Coupon = Info(c)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func issueCoupon(ctx echo.Context) error {
+	resp := &protocol.CouponIssueResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	userCouponId, err := Issue(customContext.Manager, timer, ctx.Param("coupon_id"), uniqueId)
+	if err != nil {
+		return ctx.JSON(SetCouponError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.UserCouponId = userCouponId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getUserCoupons(ctx echo.Context) error {
+	resp := &protocol.UserCouponListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	status := UserCouponUnused
+	if ctx.QueryParam("used") == "true" {
+		status = UserCouponUsed
+	}
+	page, count := customContext.Paging()
+	coupons, err := UserCoupons(customContext.Manager, timer, uniqueId, status, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if len(coupons) > count {
+		coupons = coupons[:count]
+		resp.HasNext = true
+	}
+
+	resp.Coupons = make([]protocol.UserCouponInfo, 0, len(coupons))
+	for index := range coupons {
+		uc := &coupons[index]
+		resp.Coupons = append(resp.Coupons, protocol.UserCouponInfo{
+			UserCouponId: uc.UserCouponId,
+			Used:         uc.Status == UserCouponUsed,
+			OrderId:      uc.OrderId,
+			Coupon:       Info(&uc.Coupon),
+		})
+	}
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 쿠폰 관련 에러를 응답 status 로 변환한다. http status code 를 돌려준다.
+func SetCouponError(resp *protocol.BaseResponse, err error) int {
+	switch err {
+	case ErrCouponNotFound:
+		resp.Status = vcomError.CouponNotFound
+		resp.Detail = vcomError.MessageCouponNotFound
+		return http.StatusNotFound
+	case ErrCouponNotApplicable:
+		resp.Status = vcomError.CouponNotApplicable
+		resp.Detail = vcomError.MessageCouponNotApplicable
+		return http.StatusBadRequest
+	case ErrCouponLimitExceeded:
+		resp.Status = vcomError.CouponLimitExceeded
+		resp.Detail = vcomError.MessageCouponLimitExceeded
+		return http.StatusConflict
+	}
+	log.Error("database operation failed. err: ", err)
+	return context.SetQueryError(resp, err)
+}
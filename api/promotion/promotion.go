@@ -0,0 +1,172 @@
+package promotion
+
+import (
+	"errors"
+	"github.com/4538cgy/backend-second/api/category"
+	"sort"
+	"time"
+)
+
+// 할인 방식
+const (
+	DiscountFixed   = "fixed"   // discount_value 원
+	DiscountPercent = "percent" // discount_value %
+)
+
+// 쿠폰을 쓸 수 있는 상품 범위. scope_ids 에 대상 id 를 담는다.
+const (
+	ScopeAll      = "all"
+	ScopeProduct  = "product"
+	ScopeSeller   = "seller"
+	ScopeCategory = "category" // 하위 카테고리 상품도 포함한다.
+)
+
+var (
+	ErrInvalidCoupon       = errors.New("invalid coupon definition")
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponNotApplicable = errors.New("coupon is not applicable")
+	ErrCouponLimitExceeded = errors.New("coupon issue limit exceeded")
+)
+
+// 쿠폰 정의. 판매자 쿠폰은 그 판매자의 상품에만 쓸 수 있고 할인 금액은 판매자가 부담한다.
+type Coupon struct {
+	CouponId       string
+	IssuerId       string // 판매자 unique_id. 플랫폼 쿠폰은 빈 문자열이다.
+	Name           string
+	DiscountType   string
+	DiscountValue  int
+	MinOrderAmount int // 대상 상품 금액 합이 이 이상이어야 한다.
+	MaxDiscount    int // 0 이면 제한 없음
+	ValidFrom      time.Time
+	ValidUntil     time.Time
+	PerUserLimit   int // 한 사용자가 받을 수 있는 수
+	Scope          string
+	ScopeIds       []string
+}
+
+func (c *Coupon) Validate() error {
+	if c.Name == "" || c.DiscountValue <= 0 || c.MinOrderAmount < 0 || c.MaxDiscount < 0 || c.PerUserLimit <= 0 {
+		return ErrInvalidCoupon
+	}
+	if c.DiscountType != DiscountFixed && c.DiscountType != DiscountPercent {
+		return ErrInvalidCoupon
+	}
+	if c.DiscountType == DiscountPercent && c.DiscountValue > 100 {
+		return ErrInvalidCoupon
+	}
+	if !c.ValidUntil.After(c.ValidFrom) {
+		return ErrInvalidCoupon
+	}
+	switch c.Scope {
+	case ScopeAll:
+		c.ScopeIds = nil
+	case ScopeProduct, ScopeSeller, ScopeCategory:
+		if len(c.ScopeIds) == 0 {
+			return ErrInvalidCoupon
+		}
+	default:
+		return ErrInvalidCoupon
+	}
+	return nil
+}
+
+// 카테고리 범위를 하위 카테고리까지 넓힌다. 쿠폰을 적용하기 전에 한 번 부른다.
+func (c *Coupon) ExpandCategories(tree *category.Tree) {
+	if c.Scope != ScopeCategory {
+		return
+	}
+	expanded := make([]string, 0, len(c.ScopeIds))
+	for _, id := range c.ScopeIds {
+		expanded = append(expanded, tree.Descendants(id)...)
+	}
+	c.ScopeIds = expanded
+}
+
+// 주문의 상품 한 줄. Amount 는 앞서 적용된 쿠폰 할인을 뺀 금액이다.
+type Line struct {
+	ProductId   string
+	SellerId    string
+	CategoryIds []string
+	Amount      int
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Coupon) Eligible(line Line) bool {
+	if c.IssuerId != "" && line.SellerId != c.IssuerId {
+		return false
+	}
+	switch c.Scope {
+	case ScopeAll:
+		return true
+	case ScopeProduct:
+		return contains(c.ScopeIds, line.ProductId)
+	case ScopeSeller:
+		return contains(c.ScopeIds, line.SellerId)
+	case ScopeCategory:
+		for _, id := range line.CategoryIds {
+			if contains(c.ScopeIds, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 쿠폰 할인 금액을 line 별로 나눈다. 대상 line 의 금액 비율대로 나누고 남는 원 단위는
+// 소수점 아래가 큰 line 부터 1원씩 더한다. 환불할 때 line 별 할인 금액이 필요하다.
+func Apply(c Coupon, lines []Line, now time.Time) ([]int, error) {
+	if now.Before(c.ValidFrom) || !now.Before(c.ValidUntil) {
+		return nil, ErrCouponNotApplicable
+	}
+
+	subtotal := 0
+	for _, line := range lines {
+		if c.Eligible(line) {
+			subtotal += line.Amount
+		}
+	}
+	if subtotal <= 0 || subtotal < c.MinOrderAmount {
+		return nil, ErrCouponNotApplicable
+	}
+
+	discount := c.DiscountValue
+	if c.DiscountType == DiscountPercent {
+		discount = int(int64(subtotal) * int64(c.DiscountValue) / 100)
+	}
+	if c.MaxDiscount > 0 && discount > c.MaxDiscount {
+		discount = c.MaxDiscount
+	}
+	if discount > subtotal {
+		discount = subtotal
+	}
+
+	allocated := make([]int, len(lines))
+	remainders := make([]int, 0, len(lines))
+	rest := discount
+	for index, line := range lines {
+		if !c.Eligible(line) || line.Amount <= 0 {
+			continue
+		}
+		share := int64(discount) * int64(line.Amount)
+		allocated[index] = int(share / int64(subtotal))
+		rest -= allocated[index]
+		remainders = append(remainders, index)
+	}
+	sort.SliceStable(remainders, func(i, j int) bool {
+		a, b := remainders[i], remainders[j]
+		return int64(discount)*int64(lines[a].Amount)%int64(subtotal) > int64(discount)*int64(lines[b].Amount)%int64(subtotal)
+	})
+	for i := 0; rest > 0; i = (i + 1) % len(remainders) {
+		allocated[remainders[i]]++
+		rest--
+	}
+	return allocated, nil
+}
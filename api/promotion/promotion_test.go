@@ -0,0 +1,178 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	testFrom  = time.Date(2021, 4, 1, 0, 0, 0, 0, time.Local)
+	testUntil = time.Date(2021, 5, 1, 0, 0, 0, 0, time.Local)
+	testNow   = time.Date(2021, 4, 15, 12, 0, 0, 0, time.Local)
+)
+
+func testCoupon(discountType string, value int) Coupon {
+	return Coupon{
+		Name:          "coupon",
+		DiscountType:  discountType,
+		DiscountValue: value,
+		ValidFrom:     testFrom,
+		ValidUntil:    testUntil,
+		PerUserLimit:  1,
+		Scope:         ScopeAll,
+	}
+}
+
+func amountLines(amounts ...int) []Line {
+	lines := make([]Line, 0, len(amounts))
+	for _, amount := range amounts {
+		lines = append(lines, Line{ProductId: "product", SellerId: "seller", Amount: amount})
+	}
+	return lines
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon Coupon
+		lines  []Line
+		want   []int
+	}{
+		{"single line", testCoupon(DiscountFixed, 1000), amountLines(5000), []int{1000}},
+		{"proportional", testCoupon(DiscountFixed, 1000), amountLines(3000, 1000), []int{750, 250}},
+		// 333.33.. 씩 나눠 떨어지지 않는 1원은 첫 line 부터
+		{"even remainder", testCoupon(DiscountFixed, 1000), amountLines(1000, 1000, 1000), []int{334, 333, 333}},
+		// 1000 * 1000 / 7000 = 142.85.., 3000 * 1000 / 7000 = 428.57.. 소수점 아래가 큰 line 이 1원을 더 받는다.
+		{"largest remainder", testCoupon(DiscountFixed, 1000), amountLines(1000, 3000, 3000), []int{143, 429, 428}},
+		{"zero price line", testCoupon(DiscountFixed, 1000), amountLines(0, 2000, 2000), []int{0, 500, 500}},
+		{"discount over subtotal", testCoupon(DiscountFixed, 10000), amountLines(3000, 1000), []int{3000, 1000}},
+		{"percent", testCoupon(DiscountPercent, 10), amountLines(12345, 6789), []int{1234, 679}},
+		{"percent max discount", func() Coupon {
+			c := testCoupon(DiscountPercent, 50)
+			c.MaxDiscount = 1000
+			return c
+		}(), amountLines(4000, 4000), []int{500, 500}},
+		{"not eligible line", func() Coupon {
+			c := testCoupon(DiscountFixed, 1000)
+			c.Scope, c.ScopeIds = ScopeProduct, []string{"target"}
+			return c
+		}(), []Line{{ProductId: "target", Amount: 3000}, {ProductId: "other", Amount: 3000}}, []int{1000, 0}},
+	}
+	for _, test := range tests {
+		got, err := Apply(test.coupon, test.lines, testNow)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: %v. want %v", test.name, got, test.want)
+			continue
+		}
+		for index := range test.want {
+			if got[index] != test.want[index] {
+				t.Errorf("%s: %v. want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestApplyNotApplicable(t *testing.T) {
+	minOrder := testCoupon(DiscountFixed, 1000)
+	minOrder.MinOrderAmount = 5000
+	seller := testCoupon(DiscountFixed, 1000)
+	seller.IssuerId = "other-seller"
+
+	tests := []struct {
+		name   string
+		coupon Coupon
+		lines  []Line
+		now    time.Time
+	}{
+		{"below min order", minOrder, amountLines(2000, 2999), testNow},
+		{"before valid from", testCoupon(DiscountFixed, 1000), amountLines(5000), testFrom.Add(-time.Second)},
+		{"at valid until", testCoupon(DiscountFixed, 1000), amountLines(5000), testUntil},
+		{"after valid until", testCoupon(DiscountFixed, 1000), amountLines(5000), testUntil.Add(time.Hour)},
+		{"zero subtotal", testCoupon(DiscountFixed, 1000), amountLines(0, 0), testNow},
+		{"other seller coupon", seller, amountLines(5000), testNow},
+		{"no lines", testCoupon(DiscountFixed, 1000), nil, testNow},
+	}
+	for _, test := range tests {
+		if _, err := Apply(test.coupon, test.lines, test.now); err != ErrCouponNotApplicable {
+			t.Errorf("%s: err %v", test.name, err)
+		}
+	}
+
+	// 최소 주문 금액과 같으면 적용된다.
+	if _, err := Apply(minOrder, amountLines(2000, 3000), testNow); err != nil {
+		t.Errorf("min order boundary: %v", err)
+	}
+	if _, err := Apply(testCoupon(DiscountFixed, 1000), amountLines(5000), testFrom); err != nil {
+		t.Errorf("valid from boundary: %v", err)
+	}
+}
+
+func TestEligible(t *testing.T) {
+	line := Line{ProductId: "p1", SellerId: "s1", CategoryIds: []string{"shoes", "fashion"}}
+	tests := []struct {
+		name     string
+		issuer   string
+		scope    string
+		scopeIds []string
+		want     bool
+	}{
+		{"all", "", ScopeAll, nil, true},
+		{"product", "", ScopeProduct, []string{"p2", "p1"}, true},
+		{"other product", "", ScopeProduct, []string{"p2"}, false},
+		{"seller", "", ScopeSeller, []string{"s1"}, true},
+		{"other seller", "", ScopeSeller, []string{"s2"}, false},
+		{"category", "", ScopeCategory, []string{"fashion"}, true},
+		{"other category", "", ScopeCategory, []string{"food"}, false},
+		{"issuer's line", "s1", ScopeAll, nil, true},
+		{"other issuer", "s2", ScopeAll, nil, false},
+		{"other issuer product", "s2", ScopeProduct, []string{"p1"}, false},
+	}
+	for _, test := range tests {
+		c := Coupon{IssuerId: test.issuer, Scope: test.scope, ScopeIds: test.scopeIds}
+		if got := c.Eligible(line); got != test.want {
+			t.Errorf("%s: %v. want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Coupon)
+		valid  bool
+	}{
+		{"fixed", func(c *Coupon) {}, true},
+		{"percent", func(c *Coupon) { c.DiscountType, c.DiscountValue = DiscountPercent, 100 }, true},
+		{"percent over 100", func(c *Coupon) { c.DiscountType, c.DiscountValue = DiscountPercent, 101 }, false},
+		{"unknown type", func(c *Coupon) { c.DiscountType = "free" }, false},
+		{"no name", func(c *Coupon) { c.Name = "" }, false},
+		{"zero value", func(c *Coupon) { c.DiscountValue = 0 }, false},
+		{"negative min order", func(c *Coupon) { c.MinOrderAmount = -1 }, false},
+		{"negative max discount", func(c *Coupon) { c.MaxDiscount = -1 }, false},
+		{"zero per user limit", func(c *Coupon) { c.PerUserLimit = 0 }, false},
+		{"until before from", func(c *Coupon) { c.ValidUntil = c.ValidFrom.Add(-time.Hour) }, false},
+		{"until equals from", func(c *Coupon) { c.ValidUntil = c.ValidFrom }, false},
+		{"product scope", func(c *Coupon) { c.Scope, c.ScopeIds = ScopeProduct, []string{"p1"} }, true},
+		{"product scope without ids", func(c *Coupon) { c.Scope = ScopeProduct }, false},
+		{"unknown scope", func(c *Coupon) { c.Scope = "brand" }, false},
+	}
+	for _, test := range tests {
+		c := testCoupon(DiscountFixed, 1000)
+		test.modify(&c)
+		if err := c.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: err %v", test.name, err)
+		}
+	}
+
+	// 전체 범위 쿠폰은 scope id 를 버린다.
+	c := testCoupon(DiscountFixed, 1000)
+	c.ScopeIds = []string{"p1"}
+	if err := c.Validate(); err != nil || c.ScopeIds != nil {
+		t.Errorf("all scope ids: %v, err %v", c.ScopeIds, err)
+	}
+}
@@ -0,0 +1,171 @@
+package promotion
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"time"
+)
+
+// 발급받은 쿠폰 status
+const (
+	UserCouponUnused = 0
+	UserCouponUsed   = 1
+)
+
+const DatetimeFormat = "2006-01-02 15:04:05"
+
+// 사용자가 발급받은 쿠폰 한 장
+type UserCoupon struct {
+	UserCouponId string
+	Status       int
+	OrderId      string // 사용한 주문
+	Coupon       Coupon
+}
+
+func Create(m database.Manager, timer *time.Timer, c Coupon) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	scopeIds, err := json.Marshal(c.ScopeIds)
+	if err != nil {
+		return "", err
+	}
+	couponId := util.RandString()
+	_, err = database.Exec(m, timer, query.InsertCoupon, couponId, c.IssuerId, c.Name, c.DiscountType,
+		c.DiscountValue, c.MinOrderAmount, c.MaxDiscount, c.ValidFrom.Format(DatetimeFormat),
+		c.ValidUntil.Format(DatetimeFormat), c.PerUserLimit, c.Scope, string(scopeIds))
+	return couponId, err
+}
+
+// 쿠폰 하나를 읽는다. 없으면 nil 을 돌려준다.
+func Find(m database.Manager, timer *time.Timer, couponId string) (*Coupon, error) {
+	rows, err := database.Select(m, timer, query.SelectCoupon, couponId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	c := &Coupon{}
+	if err := scan(rows, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 사용자에게 쿠폰을 한 장 발급한다.
+func Issue(m database.Manager, timer *time.Timer, couponId, uniqueId string) (string, error) {
+	userCouponId := util.RandString()
+	res, err := database.Exec(m, timer, query.IssueCoupon, userCouponId, uniqueId, UserCouponUnused, couponId, uniqueId)
+	if err != nil {
+		return "", err
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return userCouponId, nil
+	}
+
+	c, err := Find(m, timer, couponId)
+	if err != nil {
+		return "", err
+	}
+	if c == nil {
+		return "", ErrCouponNotFound
+	}
+	if !time.Now().Before(c.ValidUntil) {
+		return "", ErrCouponNotApplicable
+	}
+	return "", ErrCouponLimitExceeded
+}
+
+// 아직 쓰지 않은 쿠폰이면 돌려준다. 없거나 이미 썼으면 ErrCouponNotFound 이다.
+func FindUnused(m database.Manager, timer *time.Timer, userCouponId, uniqueId string) (*UserCoupon, error) {
+	rows, err := database.Select(m, timer, query.SelectUserCoupon, userCouponId, uniqueId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrCouponNotFound
+	}
+	uc := &UserCoupon{}
+	if err := scanUserCoupon(rows, uc); err != nil {
+		return nil, err
+	}
+	if uc.Status != UserCouponUnused {
+		return nil, ErrCouponNotFound
+	}
+	return uc, nil
+}
+
+// 사용자가 받은 쿠폰 목록. 유효 기간이 먼저 끝나는 것부터 돌려준다.
+func UserCoupons(m database.Manager, timer *time.Timer, uniqueId string, status, limit, offset int) ([]UserCoupon, error) {
+	rows, err := database.Select(m, timer, query.SelectUserCoupons, uniqueId, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := make([]UserCoupon, 0)
+	for rows.Next() {
+		uc := UserCoupon{}
+		if err := scanUserCoupon(rows, &uc); err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, uc)
+	}
+	return coupons, rows.Err()
+}
+
+// 쿠폰을 주문에 쓴 것으로 표시한다. 동시에 다른 주문에 쓰였다면 ErrCouponNotFound 이다.
+func Use(m database.Manager, timer *time.Timer, userCouponId, uniqueId, orderId string) error {
+	res, err := database.Exec(m, timer, query.UpdateUserCouponUsed, UserCouponUsed, orderId, userCouponId, uniqueId, UserCouponUnused)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrCouponNotFound
+	}
+	return nil
+}
+
+// 주문에 쓴 쿠폰을 모두 돌려준다.
+func Release(m database.Manager, timer *time.Timer, orderId string) error {
+	_, err := database.Exec(m, timer, query.UpdateUserCouponsReleased, UserCouponUnused, orderId, UserCouponUsed)
+	return err
+}
+
+func scanUserCoupon(rows *sql.Rows, uc *UserCoupon) error {
+	return scan(rows, &uc.Coupon, &uc.UserCouponId, &uc.Status, &uc.OrderId)
+}
+
+// prefix 는 couponSelect 의 column 들 앞에 오는 column 이다.
+func scan(rows *sql.Rows, c *Coupon, prefix ...interface{}) error {
+	var validFrom, validUntil, scopeIds string
+	dest := append(prefix, &c.CouponId, &c.IssuerId, &c.Name, &c.DiscountType, &c.DiscountValue,
+		&c.MinOrderAmount, &c.MaxDiscount, &validFrom, &validUntil, &c.PerUserLimit, &c.Scope, &scopeIds)
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	var err error
+	if c.ValidFrom, err = time.ParseInLocation(DatetimeFormat, validFrom, time.Local); err != nil {
+		return err
+	}
+	if c.ValidUntil, err = time.ParseInLocation(DatetimeFormat, validUntil, time.Local); err != nil {
+		return err
+	}
+	c.ScopeIds = make([]string, 0)
+	if scopeIds != "" && scopeIds != "null" {
+		return json.Unmarshal([]byte(scopeIds), &c.ScopeIds)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package purchase
 
 import (
 	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
 	"github.com/4538cgy/backend-second/api/route"
 	"github.com/4538cgy/backend-second/config"
 	"github.com/4538cgy/backend-second/database"
@@ -37,7 +38,7 @@ func addCartItem(ctx echo.Context) error {
 	cartItemAddRequest := &protocol.CartItemAddRequest{}
 	err := ctx.Bind(cartItemAddRequest)
 	if err != nil {
-		log.Error("failed to bind register user request")
+		customContext.Log.Error("failed to bind register user request")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageBindFailed
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -46,43 +47,47 @@ func addCartItem(ctx echo.Context) error {
 	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
 	defer timer.Stop()
 
-	cart_id := util.RandString()
-	resultCh := make(chan database.CudQueryResult)
-	values := []interface{}{
-		cart_id,
-		cartItemAddRequest.UniqueId,
-		cartItemAddRequest.ProductId,
-		cartItemAddRequest.SelectedJson,
-	}
-	select {
-	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertCart, values, resultCh):
-	case <-timer.C:
-		log.Error("failed to exec query")
-		resp.Status = vcomError.ApiOperationRequestTimeout
-		resp.Detail = vcomError.MessageOperationTimeout
-		return ctx.JSON(http.StatusInternalServerError, resp)
+	quantity := cartItemAddRequest.Quantity
+	if quantity <= 0 {
+		quantity = 1
 	}
 
-	select {
-	case res := <-resultCh:
-		if res.Err != nil {
-			log.Error("database operation failed.")
-			resp.Status = vcomError.DatabaseOperationError
-			resp.Detail = res.Err.Error()
-			return ctx.JSON(http.StatusInternalServerError, resp)
-		}
+	// 고른 옵션이 상품에 있고 재고가 있어야 담을 수 있다.
+	sku, err := product.CheckSku(customContext.Manager, timer, cartItemAddRequest.ProductId,
+		cartItemAddRequest.SelectedJson, quantity)
+	if err != nil {
+		return ctx.JSON(setSkuError(&resp.BaseResponse, err), resp)
+	}
 
-	case <-timer.C:
-		log.Error("database operation timeout.")
-		resp.Status = vcomError.ApiOperationResponseTimeout
-		resp.Detail = vcomError.MessageOperationTimeout
-		return ctx.JSON(http.StatusInternalServerError, resp)
+	cartId := util.RandString()
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertCart, cartId,
+		cartItemAddRequest.UniqueId, cartItemAddRequest.ProductId, sku.SkuId, quantity,
+		cartItemAddRequest.SelectedJson); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
 	}
 
+	resp.CartId = cartId
 	resp.Status = vcomError.QueryResultOk
 	return ctx.JSON(http.StatusOK, resp)
 }
 
+// product.CheckSku 실패를 응답 status 로 변환한다. http status code 를 돌려준다.
+func setSkuError(resp *protocol.BaseResponse, err error) int {
+	switch err {
+	case product.ErrOptionNotFound:
+		resp.Status = vcomError.OptionNotFound
+		resp.Detail = vcomError.MessageOptionNotFound
+		return http.StatusBadRequest
+	case product.ErrOutOfStock:
+		resp.Status = vcomError.OutOfStock
+		resp.Detail = vcomError.MessageOutOfStock
+		return http.StatusConflict
+	}
+	log.Error("database operation failed. err: ", err)
+	return context.SetQueryError(resp, err)
+}
+
 func deleteCartItem(ctx echo.Context) error {
 	resp := &protocol.CartItemRemoveResponse{}
 	customContext, ok := ctx.(*context.CustomContext)
@@ -96,7 +101,7 @@ func deleteCartItem(ctx echo.Context) error {
 	cartItemRemoveRequest := &protocol.CartItemRemoveRequest{}
 	err := ctx.Bind(cartItemRemoveRequest)
 	if err != nil {
-		log.Error("failed to bind register user request")
+		customContext.Log.Error("failed to bind register user request")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageBindFailed
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -113,7 +118,7 @@ func deleteCartItem(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.DeleteCart, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -122,14 +127,14 @@ func deleteCartItem(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
 		}
 
 	case <-timer.C:
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
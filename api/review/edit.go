@@ -0,0 +1,234 @@
+package review
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// 작성자만 수정, 삭제 가능
+	reviewItemUrl = "/api/review/:review_id"
+	// 상품 판매자만 리뷰 하나에 한 번 답글을 달 수 있다.
+	reviewReplyUrl = "/api/review/:review_id/reply"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(reviewItemUrl, "PUT"), updateReview)
+	route.AddRoute(route.NewRouteType(reviewItemUrl, "DELETE"), deleteReview)
+	route.AddRoute(route.NewRouteType(reviewReplyUrl, "POST"), postReviewReply)
+}
+
+func updateReview(ctx echo.Context) error {
+	resp := &protocol.ReviewUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	// 보낸 값만 바꾼다. 별점만 고칠 때 본문이 지워지지 않도록 빈 본문은 보내지 않은 것으로 본다.
+	var body, star interface{}
+	if value := ctx.FormValue("body"); value != "" {
+		body = value
+	}
+	if value := ctx.FormValue("star"); value != "" {
+		starScore, err := strconv.Atoi(value)
+		if err != nil {
+			customContext.Log.Error("data invalid. err: ", err.Error(), ",  startScore: ", value)
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+		star = clampStar(starScore)
+	}
+	if body == nil && star == nil {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	// 같은 값으로 다시 보내면 바뀐 row 가 없으므로 RowsAffected 로는 작성자를 확인할 수 없다.
+	reviewId := ctx.Param("review_id")
+	author, _, err := selectReviewOwner(customContext.Manager, timer, reviewId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if author == "" || author != uniqueId {
+		resp.Status = vcomError.ReviewNotFound
+		resp.Detail = vcomError.MessageReviewNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.UpdateReview,
+		body, star, reviewId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func deleteReview(ctx echo.Context) error {
+	resp := &protocol.ReviewUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	reviewId := ctx.Param("review_id")
+	author, _, err := selectReviewOwner(customContext.Manager, timer, reviewId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if author == "" || author != uniqueId {
+		resp.Status = vcomError.ReviewNotFound
+		resp.Detail = vcomError.MessageReviewNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	// 답글, 추천 기록을 먼저 지우고 리뷰를 지운다.
+	for _, q := range []string{query.DeleteReviewReply, query.DeleteReviewThumbs} {
+		if _, err := database.Exec(customContext.Manager, timer, q, reviewId); err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+	}
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteReview, reviewId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func postReviewReply(ctx echo.Context) error {
+	resp := &protocol.ReviewReplyResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	reviewId := ctx.Param("review_id")
+	author, seller, err := selectReviewOwner(customContext.Manager, timer, reviewId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if author == "" {
+		resp.Status = vcomError.ReviewNotFound
+		resp.Detail = vcomError.MessageReviewNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	if seller != uniqueId {
+		customContext.Log.Warning("reply denied. unique_id: ", uniqueId, ", seller: ", seller)
+		resp.Status = vcomError.PermissionDenied
+		resp.Detail = vcomError.MessagePermissionDenied
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+
+	// review_id 가 primary key 이므로 동시에 요청이 와도 하나만 들어간다.
+	rows, err := database.Select(customContext.Manager, timer, query.SelectReviewReply, reviewId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	replied := rows.Next()
+	rows.Close()
+	if replied {
+		resp.Status = vcomError.ReviewReplyWritten
+		resp.Detail = vcomError.MessageReplyWritten
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertReviewReply,
+		reviewId, uniqueId, ctx.FormValue("body")); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	if err := customContext.Notify(notification.Notification{
+		UniqueId:    author,
+		Category:    notification.CategoryReview,
+		Title:       "내 리뷰에 판매자 답글이 달렸습니다.",
+		Body:        ctx.FormValue("body"),
+		ReferenceId: reviewId,
+	}); err != nil {
+		customContext.Log.Error("notify review reply failed. review_id: ", reviewId, ", err: ", err)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 리뷰 작성자와 상품 판매자. 리뷰가 없으면 빈 문자열을 돌려준다.
+func selectReviewOwner(m database.Manager, timer *time.Timer, reviewId string) (string, string, error) {
+	rows, err := database.Select(m, timer, query.SelectReviewOwner, reviewId)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	var author, seller string
+	if rows.Next() {
+		if err := rows.Scan(&author, &seller); err != nil {
+			return "", "", err
+		}
+	}
+	return author, seller, nil
+}
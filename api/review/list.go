@@ -0,0 +1,196 @@
+package review
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/media"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	productReviewUrl = "/api/review/product/:product_id"
+	userReviewUrl    = "/api/review/user/:unique_id"
+	reviewSummaryUrl = "/api/review/product/:product_id/summary"
+
+	paramSort = "sort"
+
+	sortNewest  = "newest"
+	sortStar    = "star"
+	sortMedia   = "media"   // 미디어가 있는 리뷰만, 최신순
+	sortHelpful = "helpful" // 추천 - 비추천 순
+
+	minStar = 1
+	maxStar = 5
+)
+
+// sort param 별 추가 where 조건과 order by 절
+var sortClauses = map[string][2]string{
+	sortNewest:  {"", "r.created DESC"},
+	sortStar:    {"", "r.star DESC, r.created DESC"},
+	sortMedia:   {"AND JSON_LENGTH(r.media_info_json, '$.media_indices') > 0", "r.created DESC"},
+	sortHelpful: {"", "(r.thumb_up - r.thumb_down) DESC, r.thumb_up DESC, r.created DESC"},
+}
+
+func init() {
+	route.AddRoute(route.NewRouteType(productReviewUrl, "GET"), getProductReviews)
+	route.AddRoute(route.NewRouteType(userReviewUrl, "GET"), getUserReviews)
+	route.AddRoute(route.NewRouteType(reviewSummaryUrl, "GET"), getReviewSummary)
+}
+
+func getProductReviews(ctx echo.Context) error {
+	return listReviews(ctx, query.SelectReviewsByProduct, ctx.Param("product_id"))
+}
+
+func getUserReviews(ctx echo.Context) error {
+	return listReviews(ctx, query.SelectReviewsByUser, ctx.Param("unique_id"))
+}
+
+func listReviews(ctx echo.Context, selectQuery string, key string) error {
+	resp := &protocol.ReviewListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	sort := ctx.QueryParam(paramSort)
+	if sort == "" {
+		sort = sortNewest
+	}
+	clause, ok := sortClauses[sort]
+	if !ok {
+		customContext.Log.Error("wrong sort param: ", sort)
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	page, count := customContext.Paging()
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	// 다음 page 여부를 알기 위해 하나 더 읽는다.
+	rows, err := database.Select(customContext.Manager, timer,
+		fmt.Sprintf(selectQuery, clause[0], clause[1]),
+		key, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	reviews, mediaIndices, err := scanReviews(rows)
+	if err != nil {
+		customContext.Log.Error("scan failed. err: ", err)
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+	if len(reviews) > count {
+		reviews = reviews[:count]
+		mediaIndices = mediaIndices[:count]
+		resp.HasNext = true
+	}
+
+	if err := expandReviewMedias(customContext.Manager, timer, reviews, mediaIndices); err != nil {
+		customContext.Log.Error("media expand failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Reviews = reviews
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getReviewSummary(ctx echo.Context) error {
+	resp := &protocol.ReviewSummaryResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	productId := ctx.Param("product_id")
+	rows, err := database.Select(customContext.Manager, timer, query.SelectReviewStarCount, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	sum := 0
+	for rows.Next() {
+		var star, count int
+		if err := rows.Scan(&star, &count); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		if star < minStar || star > maxStar {
+			continue
+		}
+		resp.CountByStar[star-1] += count
+		resp.TotalCount += count
+		sum += star * count
+	}
+	if resp.TotalCount > 0 {
+		resp.Average = float64(sum) / float64(resp.TotalCount)
+	}
+
+	resp.ProductId = productId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// review row 들을 읽는다. 각 review 의 media id 목록을 같은 순서로 함께 돌려준다.
+func scanReviews(rows *sql.Rows) ([]protocol.ReviewInfo, [][]string, error) {
+	defer rows.Close()
+
+	reviews := make([]protocol.ReviewInfo, 0)
+	mediaIndices := make([][]string, 0)
+	for rows.Next() {
+		review := protocol.ReviewInfo{}
+		var mediaInfoJson string
+		if err := rows.Scan(&review.ReviewId, &review.ProductId, &review.UniqueId,
+			&review.Body, &mediaInfoJson, &review.Star, &review.ThumbUp, &review.ThumbDown, &review.Created,
+			&review.Reply.Body, &review.Reply.Created); err != nil {
+			return nil, nil, err
+		}
+		reviews = append(reviews, review)
+		mediaIndices = append(mediaIndices, media.ParseIndices(mediaInfoJson))
+	}
+	return reviews, mediaIndices, rows.Err()
+}
+
+// 한 번의 query 로 page 내 모든 리뷰의 media 를 확장한다.
+func expandReviewMedias(m database.Manager, timer *time.Timer, reviews []protocol.ReviewInfo, mediaIndices [][]string) error {
+	all := make([]string, 0)
+	for _, ids := range mediaIndices {
+		all = append(all, ids...)
+	}
+	infos, err := media.Expand(m, timer, all)
+	if err != nil {
+		return err
+	}
+	for index := range reviews {
+		reviews[index].Medias = media.Pick(infos, mediaIndices[index])
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package review
 
 import (
 	"encoding/json"
+	"github.com/4538cgy/backend-second/api/asset"
 	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/order"
 	"github.com/4538cgy/backend-second/api/route"
 	"github.com/4538cgy/backend-second/api/types"
 	"github.com/4538cgy/backend-second/config"
@@ -13,9 +15,8 @@ import (
 	"github.com/4538cgy/backend-second/query"
 	"github.com/4538cgy/backend-second/util"
 	"github.com/labstack/echo/v4"
-	"io"
 	"net/http"
-	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 )
@@ -29,7 +30,7 @@ func init() {
 }
 
 func postReview(ctx echo.Context) error {
-	resp := &protocol.ProductPostResponse{}
+	resp := &protocol.ReviewPostResponse{}
 	customContext, ok := ctx.(*context.CustomContext)
 	if !ok {
 		log.Error("failed to casting echo.Context to api.CustomContext")
@@ -38,22 +39,63 @@ func postReview(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
 	reviewId := util.RandString()
-	uniqueId := ctx.FormValue("unique_id")
-	token := ctx.FormValue("token")
-	log.Info("token: ", token)
-	// TODO token validation check
-	productId := ctx.FormValue("product_id")
-	thumbsUpAndDown := util.RandString()
-	log.Info("ThumbsUpAndDown ID: ", thumbsUpAndDown) // TODO Redis 등록
+	orderLineId := ctx.FormValue("order_line_id")
+	thumbsUpAndDown := util.RandString() // review_thumb 에서 추천/비추천을 묶는 id
 	bodyMessage := ctx.FormValue("body")
 	starScore, err := strconv.Atoi(ctx.FormValue("star")) // 별점
 	if err != nil {
-		log.Error("data invalid. err: ", err.Error(), ",  startScore: ", ctx.FormValue("star"))
+		customContext.Log.Error("data invalid. err: ", err.Error(), ",  startScore: ", ctx.FormValue("star"))
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageUnknownError
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
+	starScore = clampStar(starScore)
+
+	// 배송 완료된 본인 주문에 대해서만 리뷰를 쓸 수 있다.
+	line, err := order.FindLine(customContext.Manager, timer, orderLineId, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if line == nil || line.Status != order.StatusDelivered {
+		customContext.Log.Warning("review not eligible. unique_id: ", uniqueId, ", order_line_id: ", orderLineId)
+		resp.Status = vcomError.ReviewNotEligible
+		resp.Detail = vcomError.MessageReviewNotEligible
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+	if productId := ctx.FormValue("product_id"); productId != "" && productId != line.ProductId {
+		customContext.Log.Warning("product mismatch. product_id: ", productId, ", order line product_id: ", line.ProductId)
+		resp.Status = vcomError.ReviewNotEligible
+		resp.Detail = vcomError.MessageReviewNotEligible
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+	productId := line.ProductId
+
+	// 주문 line 당 하나의 리뷰. order_line_id 의 unique key 로도 막는다.
+	rows, err := database.Select(customContext.Manager, timer, query.SelectReviewByOrderLine, orderLineId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	written := rows.Next()
+	rows.Close()
+	if written {
+		resp.Status = vcomError.ReviewAlreadyWritten
+		resp.Detail = vcomError.MessageReviewWritten
+		return ctx.JSON(http.StatusConflict, resp)
+	}
 
 	form, err := ctx.MultipartForm()
 	if err != nil {
@@ -65,39 +107,34 @@ func postReview(ctx echo.Context) error {
 
 	// TODO S3로 옮기자. 이미지의 경우 s3로 바로 올리고, 동영상의 경우 hls 변환 후 s3로 올리자.
 	for _, file := range medias {
-		// Source
 		src, err := file.Open()
 		if err != nil {
 			return err
 		}
 		defer src.Close()
 
-		// Destination
-		dst, err := os.Create(file.Filename)
-		if err != nil {
-			return err
-		}
-		defer dst.Close()
-
-		// Copy
-		if _, err = io.Copy(dst, src); err != nil {
+		id := util.RandString()
+		name := id + filepath.Ext(file.Filename)
+		if _, err = customContext.SaveAsset(asset.KindMedia, name, src); err != nil {
+			customContext.Log.Error("media save failed. err: ", err)
+			if err == asset.ErrUnsupportedType {
+				resp.Status = vcomError.InvalidParameter
+				resp.Detail = vcomError.MessageInvalidParameter
+				return ctx.JSON(http.StatusBadRequest, resp)
+			}
 			resp.Status = vcomError.InternalError
 			resp.Detail = vcomError.MessageIOFailed
 			return ctx.JSON(http.StatusInternalServerError, resp)
 		}
-		id := util.RandString()
 		mediaInfos.Item = append(mediaInfos.Item, types.MediaInfo{
-			Kind:     types.VideoType.String(), // TODO 확장자 보고 type 설정해서 넣읍시다.
+			Kind:     types.MediaTypeOf(name).String(),
 			MediaId:  id,
-			MediaUrl: "", // TODO url 미리 생성해서 넘기도록 합시다.
+			MediaUrl: customContext.AssetUrl(asset.KindMedia, name),
 		})
 		mediaIndices.MediaIds = append(mediaIndices.MediaIds, id)
 	}
 	// TODO 파일 변환 query
 
-	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
-	defer timer.Stop()
-
 	// video_info first
 	for _, media := range mediaInfos.Item {
 		resultCh := make(chan database.CudQueryResult)
@@ -108,7 +145,7 @@ func postReview(ctx echo.Context) error {
 		select {
 		case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertVideoList, values, resultCh):
 		case <-timer.C:
-			log.Error("failed to exec query")
+			customContext.Log.Error("failed to exec query")
 			resp.Status = vcomError.ApiOperationRequestTimeout
 			resp.Detail = vcomError.MessageOperationTimeout
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -117,7 +154,7 @@ func postReview(ctx echo.Context) error {
 		select {
 		case res := <-resultCh:
 			if res.Err != nil {
-				log.Error("database operation failed.")
+				customContext.Log.Error("database operation failed.")
 				resp.Status = vcomError.DatabaseOperationError
 				resp.Detail = res.Err.Error()
 				return ctx.JSON(http.StatusInternalServerError, resp)
@@ -125,7 +162,7 @@ func postReview(ctx echo.Context) error {
 
 		case <-timer.C:
 			// TODO rollback needed
-			log.Error("database operation timeout.")
+			customContext.Log.Error("database operation timeout.")
 			resp.Status = vcomError.ApiOperationResponseTimeout
 			resp.Detail = vcomError.MessageOperationTimeout
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -145,6 +182,7 @@ func postReview(ctx echo.Context) error {
 		reviewId,
 		productId,
 		uniqueId,
+		orderLineId,
 		thumbsUpAndDown,
 		bodyMessage,
 		string(mediaInfoJson),
@@ -153,7 +191,7 @@ func postReview(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertReview, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -162,7 +200,7 @@ func postReview(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -170,12 +208,24 @@ func postReview(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
+	resp.ReviewId = reviewId
 	resp.Status = vcomError.QueryResultOk
 	return ctx.JSON(http.StatusOK, resp)
 }
+
+// 별점은 1~5 로 맞춘다.
+func clampStar(star int) int {
+	if star < minStar {
+		return minStar
+	}
+	if star > maxStar {
+		return maxStar
+	}
+	return star
+}
@@ -0,0 +1,124 @@
+package review
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 리뷰 추천/비추천. PUT 으로 투표하거나 바꾸고, DELETE 로 취소한다.
+	// 사용자당 리뷰 하나에 한 표만 가질 수 있다.
+	reviewThumbUrl = "/api/review/:review_id/thumb"
+
+	paramVote = "vote"
+
+	voteUp   = 1
+	voteDown = -1
+)
+
+var votes = map[string]int{
+	"up":   voteUp,
+	"down": voteDown,
+}
+
+func init() {
+	route.AddRoute(route.NewRouteType(reviewThumbUrl, "PUT"), putReviewThumb)
+	route.AddRoute(route.NewRouteType(reviewThumbUrl, "DELETE"), deleteReviewThumb)
+}
+
+func putReviewThumb(ctx echo.Context) error {
+	vote, ok := votes[ctx.FormValue(paramVote)]
+	if !ok {
+		resp := &protocol.ReviewThumbResponse{}
+		log.Error("wrong vote param: ", ctx.FormValue(paramVote))
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	return updateReviewThumb(ctx, vote)
+}
+
+func deleteReviewThumb(ctx echo.Context) error {
+	return updateReviewThumb(ctx, 0)
+}
+
+// vote 가 0 이면 투표를 취소한다.
+func updateReviewThumb(ctx echo.Context, vote int) error {
+	resp := &protocol.ReviewThumbResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	reviewId := ctx.Param("review_id")
+	rows, err := database.Select(customContext.Manager, timer, query.SelectReviewThumbId, reviewId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	var thumbId string
+	if rows.Next() {
+		rows.Scan(&thumbId)
+	}
+	rows.Close()
+	if thumbId == "" {
+		resp.Status = vcomError.ReviewNotFound
+		resp.Detail = vcomError.MessageReviewNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	if vote == 0 {
+		_, err = database.Exec(customContext.Manager, timer, query.DeleteReviewThumb, thumbId, uniqueId)
+	} else {
+		_, err = database.Exec(customContext.Manager, timer, query.UpsertReviewThumb, thumbId, uniqueId, vote)
+	}
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	// 원본인 review_thumb 로부터 다시 세기 때문에 동시에 투표가 들어와도 카운터가 어긋나지 않는다.
+	_, err = database.Exec(customContext.Manager, timer, query.UpdateReviewThumbCount, thumbId, thumbId, thumbId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	rows, err = database.Select(customContext.Manager, timer, query.SelectReviewThumbCount, thumbId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		rows.Scan(&resp.ThumbUp, &resp.ThumbDown)
+	}
+
+	resp.ReviewId = reviewId
+	resp.Vote = vote
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -18,9 +18,13 @@ func NewRouteType(uri, registerType string) routeType {
 	return routeType{uri, registerType}
 }
 
+// 같은 method, uri 가 두 번 등록되면 어느 handler 가 쓰일지 알 수 없으므로 시작할 때 멈춘다.
 func AddRoute(route routeType, fun func(echo.Context) error) {
 	routeLock.Lock()
 	defer routeLock.Unlock()
+	if _, ok := apiMap[route]; ok {
+		log.Panicf("duplicated route. %s: %s", route.routeType, route.routeUri)
+	}
 	apiMap[route] = fun
 	log.Debug("Add route... ", route.routeUri)
 }
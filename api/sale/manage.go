@@ -0,0 +1,178 @@
+package sale
+
+import (
+	"github.com/4538cgy/backend-second/api/category"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/search"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// 판매자 본인의 상품 수정(PUT), 삭제(DELETE)
+	productUrl = "/api/sale/product/:product_id"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(productUrl, "PUT"), updateProduct)
+	route.AddRoute(route.NewRouteType(productUrl, "DELETE"), deleteProduct)
+}
+
+// 상품 판매자의 session 인지 확인한다. 실패하면 응답할 http status code 를 돌려준다.
+func validateOwner(customContext *context.CustomContext, resp *protocol.BaseResponse, timer *time.Timer, productId string) (string, int) {
+	uniqueId, err := customContext.ValidateSession(customContext.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return "", http.StatusUnauthorized
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProductOwner, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return "", context.SetQueryError(resp, err)
+	}
+	var sellerId string
+	found := rows.Next() && rows.Scan(&sellerId) == nil
+	rows.Close()
+	if !found {
+		resp.Status = vcomError.ProductNotFound
+		resp.Detail = vcomError.MessageProductNotFound
+		return "", http.StatusNotFound
+	}
+	if sellerId != uniqueId {
+		customContext.Log.Warning("product update denied. unique_id: ", uniqueId, ", product_id: ", productId)
+		resp.Status = vcomError.PermissionDenied
+		resp.Detail = vcomError.MessagePermissionDenied
+		return "", http.StatusForbidden
+	}
+	return uniqueId, http.StatusOK
+}
+
+// title, base_price 는 보낸 것만 바꾼다. category_ids 를 보내면 카테고리 연결을 모두 바꾼다.
+func updateProduct(ctx echo.Context) error {
+	resp := &protocol.ProductUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	productId := ctx.Param("product_id")
+	uniqueId, code := validateOwner(customContext, &resp.BaseResponse, timer, productId)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProduct, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	products, _, err := product.Scan(rows)
+	if err != nil || len(products) == 0 {
+		customContext.Log.Error("product read failed. product_id: ", productId, ", err: ", err)
+		resp.Status = vcomError.ProductNotFound
+		resp.Detail = vcomError.MessageProductNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	current := products[0]
+
+	title := current.Title
+	if value := ctx.FormValue("title"); value != "" {
+		title = value
+	}
+	basePrice := current.BasePrice
+	if value := ctx.FormValue("base_price"); value != "" {
+		basePrice, err = strconv.Atoi(value)
+		if err != nil || basePrice < 0 {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+
+	var categoryIds []string
+	categoryIdsValue, replaceCategory := ctx.Request().Form["category_ids"]
+	if replaceCategory {
+		tree, err := category.Load(customContext.Manager, timer)
+		if err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		if categoryIds, err = category.ParseIds(tree, categoryIdsValue[0]); err != nil {
+			resp.Status = vcomError.CategoryNotFound
+			resp.Detail = vcomError.MessageCategoryNotFound
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.UpdateProduct, title, basePrice, productId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if replaceCategory {
+		if _, err := database.Exec(customContext.Manager, timer, query.DeleteProductCategories, productId); err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		if err := category.Assign(customContext.Manager, timer, productId, categoryIds); err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+	}
+	if err := search.Index(customContext.Manager, timer, productId); err != nil {
+		customContext.Log.Error("search index failed. product_id: ", productId, ", err: ", err)
+	}
+	customContext.ProductChanged(productId)
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func deleteProduct(ctx echo.Context) error {
+	resp := &protocol.ProductUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	productId := ctx.Param("product_id")
+	uniqueId, code := validateOwner(customContext, &resp.BaseResponse, timer, productId)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.DeleteProduct, productId, uniqueId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := search.Remove(customContext.Manager, timer, productId); err != nil {
+		customContext.Log.Error("search index failed. product_id: ", productId, ", err: ", err)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -2,8 +2,13 @@ package sale
 
 import (
 	"encoding/json"
+	"github.com/4538cgy/backend-second/api/asset"
+	"github.com/4538cgy/backend-second/api/category"
 	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
 	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/search"
+	"github.com/4538cgy/backend-second/api/seller"
 	"github.com/4538cgy/backend-second/api/types"
 	"github.com/4538cgy/backend-second/config"
 	"github.com/4538cgy/backend-second/database"
@@ -13,9 +18,8 @@ import (
 	"github.com/4538cgy/backend-second/query"
 	"github.com/4538cgy/backend-second/util"
 	"github.com/labstack/echo/v4"
-	"io"
 	"net/http"
-	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 )
@@ -38,27 +42,86 @@ func postProduct(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
-	uniqueId := ctx.FormValue("unique_id")
-	// token := ctx.FormValue("token")
-	// TODO token validation check
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	// 승인된 판매자만 상품을 올릴 수 있다.
+	authentication, registered, err := seller.Authentication(customContext.Manager, timer, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	approved := registered && authentication == seller.SellerAuthenticated
+	if !approved {
+		customContext.Log.Warning("product post denied. unique_id: ", uniqueId, ", authentication: ", authentication)
+		resp.Status = vcomError.SellerNotApproved
+		resp.Detail = vcomError.MessageSellerNotApproved
+		return ctx.JSON(http.StatusForbidden, resp)
+	}
+
 	title := ctx.FormValue("title")
-	categoryJson := ctx.FormValue("category_info_json")
 	optionJson := ctx.FormValue("option_json")
 	basePrice, err := strconv.Atoi(ctx.FormValue("base_price"))
 	if err != nil {
-		log.Error("data invalid. err: ", err.Error(), ", base_price: ", ctx.FormValue("base_price"))
+		customContext.Log.Error("data invalid. err: ", err.Error(), ", base_price: ", ctx.FormValue("base_price"))
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageUnknownError
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 	baseAmount, err := strconv.Atoi(ctx.FormValue("base_amount"))
 	if err != nil {
-		log.Error("data invalid. err: ", err.Error(), ", base_amount: ", ctx.FormValue("base_amount"))
+		customContext.Log.Error("data invalid. err: ", err.Error(), ", base_amount: ", ctx.FormValue("base_amount"))
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageUnknownError
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
+	// 옵션 조합마다 재고를 따로 가진다. base_amount 는 전체 재고가 된다.
+	options, err := product.ParseOptions(optionJson, baseAmount)
+	if err != nil {
+		customContext.Log.Error("option invalid. err: ", err, ", option_json: ", optionJson)
+		resp.Status = vcomError.InvalidOption
+		resp.Detail = vcomError.MessageInvalidOption
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	baseAmount = 0
+	for _, sku := range options.Skus {
+		if basePrice+sku.PriceDelta < 0 {
+			resp.Status = vcomError.InvalidOption
+			resp.Detail = vcomError.MessageInvalidOption
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+		baseAmount += sku.Stock
+	}
+	normalizedOption, err := json.Marshal(options)
+	if err != nil {
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	// 상품은 관리되는 카테고리 노드에만 연결할 수 있다.
+	tree, err := category.Load(customContext.Manager, timer)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	categoryIds, err := category.ParseIds(tree, ctx.FormValue("category_ids"))
+	if err != nil {
+		customContext.Log.Error("category invalid. err: ", err, ", category_ids: ", ctx.FormValue("category_ids"))
+		resp.Status = vcomError.CategoryNotFound
+		resp.Detail = vcomError.MessageCategoryNotFound
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
 	form, err := ctx.MultipartForm()
 	if err != nil {
 		return err
@@ -69,38 +132,34 @@ func postProduct(ctx echo.Context) error {
 
 	// TODO S3로 옮기자.
 	for _, file := range videos {
-		// Source
 		src, err := file.Open()
 		if err != nil {
 			return err
 		}
 		defer src.Close()
 
-		// Destination
-		dst, err := os.Create(file.Filename)
-		if err != nil {
-			return err
-		}
-		defer dst.Close()
-
-		// Copy
-		if _, err = io.Copy(dst, src); err != nil {
+		id := util.RandString()
+		name := id + filepath.Ext(file.Filename)
+		if _, err = customContext.SaveAsset(asset.KindMedia, name, src); err != nil {
+			customContext.Log.Error("media save failed. err: ", err)
+			if err == asset.ErrUnsupportedType {
+				resp.Status = vcomError.InvalidParameter
+				resp.Detail = vcomError.MessageInvalidParameter
+				return ctx.JSON(http.StatusBadRequest, resp)
+			}
 			resp.Status = vcomError.InternalError
 			resp.Detail = vcomError.MessageIOFailed
 			return ctx.JSON(http.StatusInternalServerError, resp)
 		}
-		id := util.RandString()
 		mediaInfos.Item = append(mediaInfos.Item, types.MediaInfo{
+			Kind:     types.MediaTypeOf(name).String(),
 			MediaId:  id,
-			MediaUrl: "", // TODO url 미리 생성해서 넘기도록 합시다.
+			MediaUrl: customContext.AssetUrl(asset.KindMedia, name),
 		})
 		mediaIndices.MediaIds = append(mediaIndices.MediaIds, id)
 	}
 	// TODO 파일 변환 query
 
-	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
-	defer timer.Stop()
-
 	// video_info first
 	for _, vinfo := range mediaInfos.Item {
 		resultCh := make(chan database.CudQueryResult)
@@ -111,7 +170,7 @@ func postProduct(ctx echo.Context) error {
 		select {
 		case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertVideoList, values, resultCh):
 		case <-timer.C:
-			log.Error("failed to exec query")
+			customContext.Log.Error("failed to exec query")
 			resp.Status = vcomError.ApiOperationRequestTimeout
 			resp.Detail = vcomError.MessageOperationTimeout
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -120,7 +179,7 @@ func postProduct(ctx echo.Context) error {
 		select {
 		case res := <-resultCh:
 			if res.Err != nil {
-				log.Error("database operation failed.")
+				customContext.Log.Error("database operation failed.")
 				resp.Status = vcomError.DatabaseOperationError
 				resp.Detail = res.Err.Error()
 				return ctx.JSON(http.StatusInternalServerError, resp)
@@ -128,48 +187,16 @@ func postProduct(ctx echo.Context) error {
 
 		case <-timer.C:
 			// TODO rollback needed
-			log.Error("database operation timeout.")
+			customContext.Log.Error("database operation timeout.")
 			resp.Status = vcomError.ApiOperationResponseTimeout
 			resp.Detail = vcomError.MessageOperationTimeout
 			return ctx.JSON(http.StatusInternalServerError, resp)
 		}
 
 	}
-	// and then product list
 
+	// and then product
 	pid := util.RandString()
-	resultCh := make(chan database.CudQueryResult)
-	values := []interface{}{
-		pid,
-		categoryJson,
-	}
-	select {
-	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertProductCategoryInfo, values, resultCh):
-	case <-timer.C:
-		log.Error("failed to exec query")
-		resp.Status = vcomError.ApiOperationRequestTimeout
-		resp.Detail = vcomError.MessageOperationTimeout
-		return ctx.JSON(http.StatusInternalServerError, resp)
-	}
-
-	select {
-	case res := <-resultCh:
-		if res.Err != nil {
-			log.Error("database operation failed.")
-			resp.Status = vcomError.DatabaseOperationError
-			resp.Detail = res.Err.Error()
-			return ctx.JSON(http.StatusInternalServerError, resp)
-		}
-
-	case <-timer.C:
-		// TODO rollback needed
-		log.Error("database operation timeout.")
-		resp.Status = vcomError.ApiOperationResponseTimeout
-		resp.Detail = vcomError.MessageOperationTimeout
-		return ctx.JSON(http.StatusInternalServerError, resp)
-	}
-
-	// and finally, product
 	videoIds, err := json.Marshal(&mediaIndices)
 	if err != nil {
 		resp.Status = vcomError.InternalError
@@ -177,21 +204,20 @@ func postProduct(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
-	// channel registration first
-	resultCh = make(chan database.CudQueryResult)
-	values = []interface{}{
+	resultCh := make(chan database.CudQueryResult)
+	values := []interface{}{
 		pid,
 		uniqueId,
 		string(videoIds),
 		title,
 		basePrice,
 		baseAmount,
-		optionJson,
+		string(normalizedOption),
 	}
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertProductSale, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -200,7 +226,7 @@ func postProduct(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -208,12 +234,28 @@ func postProduct(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
+	// and finally, sku and category
+	if err := product.SaveSkus(customContext.Manager, timer, pid, options); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := category.Assign(customContext.Manager, timer, pid, categoryIds); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	// 색인은 다시 만들 수 있으니 실패해도 상품 등록은 성공으로 둔다.
+	if err := search.Index(customContext.Manager, timer, pid); err != nil {
+		customContext.Log.Error("search index failed. product_id: ", pid, ", err: ", err)
+	}
+
 	resp.Status = vcomError.QueryResultOk
 	return ctx.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,200 @@
+package search
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/category"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 상품 검색. q 와 category_id, min_price, max_price, in_stock 필터, sort 를 받는다.
+	searchUrl = "/api/search/product"
+	// 검색어 자동 완성
+	suggestUrl = "/api/search/suggest"
+
+	maxSuggestions = 10
+)
+
+const (
+	sortRelevance = "relevance"
+	sortNewest    = "newest"
+	sortPriceAsc  = "price_asc"
+	sortPriceDesc = "price_desc"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(searchUrl, "GET"), searchProducts)
+	route.AddRoute(route.NewRouteType(suggestUrl, "GET"), suggest)
+}
+
+func searchProducts(ctx echo.Context) error {
+	resp := &protocol.SearchResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	keyword := BooleanQuery(ctx.QueryParam("q"))
+	if keyword != "" {
+		conditions = append(conditions, query.MatchProductSearch)
+		args = append(args, keyword)
+	}
+
+	for _, param := range []struct {
+		name      string
+		condition string
+	}{
+		{"min_price", "p.base_price >= ?"},
+		{"max_price", "p.base_price <= ?"},
+	} {
+		value := ctx.QueryParam(param.name)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.Atoi(value)
+		if err != nil || price < 0 {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
+		conditions = append(conditions, param.condition)
+		args = append(args, price)
+	}
+
+	if inStock, _ := strconv.ParseBool(ctx.QueryParam("in_stock")); inStock {
+		conditions = append(conditions, "p.base_amount > 0")
+	}
+
+	// 카테고리는 하위 카테고리의 상품까지 찾는다.
+	if categoryId := ctx.QueryParam("category_id"); categoryId != "" {
+		tree, err := category.Load(customContext.Manager, timer)
+		if err != nil {
+			customContext.Log.Error("database operation failed. err: ", err)
+			return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+		}
+		if _, ok := tree.Find(categoryId); !ok {
+			resp.Status = vcomError.CategoryNotFound
+			resp.Detail = vcomError.MessageCategoryNotFound
+			return ctx.JSON(http.StatusNotFound, resp)
+		}
+		ids := tree.Descendants(categoryId)
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+		conditions = append(conditions, fmt.Sprintf(query.FilterProductCategories, placeholders))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	orderBy, orderArgs := orderClause(ctx.QueryParam("sort"), keyword)
+	args = append(args, orderArgs...)
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "AND " + strings.Join(conditions, " AND ")
+	}
+	page, count := customContext.Paging()
+	args = append(args, count+1, page*count)
+	rows, err := database.Select(customContext.Manager, timer, fmt.Sprintf(query.SelectSearchProducts, where, orderBy), args...)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	products, hasNext, err := product.Page(customContext.Manager, timer, rows, count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Products = products
+	resp.Page = page
+	resp.HasNext = hasNext
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 정렬 기준. 검색어가 있으면 관련도순, 없으면 최신순이 기본이다.
+func orderClause(sort, keyword string) (string, []interface{}) {
+	if sort == "" {
+		sort = sortNewest
+		if keyword != "" {
+			sort = sortRelevance
+		}
+	}
+	switch sort {
+	case sortRelevance:
+		if keyword != "" {
+			return query.MatchProductSearch + " DESC, p.created DESC, p.product_id", []interface{}{keyword}
+		}
+	case sortPriceAsc:
+		return "p.base_price ASC, p.product_id", nil
+	case sortPriceDesc:
+		return "p.base_price DESC, p.product_id", nil
+	}
+	return "p.created DESC, p.product_id", nil
+}
+
+func suggest(ctx echo.Context) error {
+	resp := &protocol.SearchSuggestResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	resp.Suggestions = make([]string, 0)
+	prefix := strings.TrimSpace(ctx.QueryParam("q"))
+	if prefix == "" {
+		resp.Status = vcomError.QueryResultOk
+		return ctx.JSON(http.StatusOK, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	pattern := PrefixPattern(prefix)
+	rows, err := database.Select(customContext.Manager, timer, query.SelectSearchSuggestions,
+		pattern, pattern, pattern, maxSuggestions)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Suggestions = append(resp.Suggestions, word)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/query"
+	"strings"
+	"time"
+)
+
+// product_search 는 상품 title, 판매자 channel_name, 카테고리 이름을 모은 검색용 table 이다.
+// FULLTEXT(title, channel_name, category_names) WITH PARSER ngram 으로 한국어를 2글자 단위로 나눠 색인한다.
+// 가격, 재고, 삭제 여부는 product 에서 바로 읽으므로 색인에는 text 만 둔다.
+
+// 상품을 product 의 현재 값으로 다시 색인한다. 삭제된 상품은 색인에서 빠진다.
+func Index(m database.Manager, timer *time.Timer, productId string) error {
+	if err := Remove(m, timer, productId); err != nil {
+		return err
+	}
+	_, err := database.Exec(m, timer, query.InsertProductSearch, productId)
+	return err
+}
+
+func Remove(m database.Manager, timer *time.Timer, productId string) error {
+	_, err := database.Exec(m, timer, query.DeleteProductSearch, productId)
+	return err
+}
+
+// 카테고리 이름이 바뀌면 연결된 상품을 다시 색인한다.
+func IndexCategory(m database.Manager, timer *time.Timer, categoryId string) error {
+	_, err := database.Exec(m, timer, query.ReplaceCategoryProductSearch, categoryId)
+	return err
+}
+
+// 검색어를 boolean mode 검색식으로 바꾼다. 공백으로 나눈 단어가 모두 들어 있어야 한다.
+// ngram 은 2글자 단위이므로 한 글자 단어는 빼고, 모두 빠지면 빈 문자열을 돌려준다.
+func BooleanQuery(keyword string) string {
+	terms := make([]string, 0)
+	for _, word := range strings.Fields(keyword) {
+		word = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`"+-<>()~*@`, r) {
+				return -1
+			}
+			return r
+		}, word)
+		if len([]rune(word)) < 2 {
+			continue
+		}
+		terms = append(terms, `+"`+word+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// LIKE 의 특수 문자를 escape 한 prefix 검색 pattern
+func PrefixPattern(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(prefix) + "%"
+}
@@ -0,0 +1,73 @@
+package seller
+
+import (
+	"github.com/4538cgy/backend-second/api/asset"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 판매자 등록 서류의 서명된 url 발급. 본인 혹은 관리자만 가능하다.
+	sellerDocumentUrl = "/api/seller/document"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(sellerDocumentUrl, "GET"), getSellerDocument)
+}
+
+func getSellerDocument(ctx echo.Context) error {
+	resp := &protocol.SellerDocumentResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	requester, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	// 지정하지 않으면 본인 서류
+	owner := ctx.QueryParam("unique_id")
+	if owner == "" {
+		owner = requester
+	}
+
+	if owner != requester {
+		isAdmin, err := customContext.IsAdmin(requester, timer)
+		if err != nil {
+			customContext.Log.Error("admin check failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		if !isAdmin {
+			customContext.Log.Warning("seller document access denied. requester: ", requester, ", owner: ", owner)
+			resp.Status = vcomError.PermissionDenied
+			resp.Detail = vcomError.MessagePermissionDenied
+			return ctx.JSON(http.StatusForbidden, resp)
+		}
+	}
+
+	url, expires := customContext.SignedAssetUrl(asset.KindSeller, owner+".pdf")
+	resp.Url = url
+	resp.ExpiresAt = expires.Unix()
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
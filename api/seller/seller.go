@@ -1,33 +1,34 @@
 package seller
 
 import (
-	"fmt"
+	"github.com/4538cgy/backend-second/api/asset"
 	"github.com/4538cgy/backend-second/api/context"
 	"github.com/4538cgy/backend-second/api/route"
-	_ "github.com/4538cgy/backend-second/api/session"
 	"github.com/4538cgy/backend-second/config"
 	"github.com/4538cgy/backend-second/database"
 	vcomError "github.com/4538cgy/backend-second/error"
 	"github.com/4538cgy/backend-second/log"
 	"github.com/4538cgy/backend-second/protocol"
 	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/validation"
 	"github.com/labstack/echo/v4"
-	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 )
 
+// 판매자 등록, 채널 이름 예약, 등록 상태 조회는 이 패키지에서만 다룬다.
 const (
 	sellerAuthUrl = "/api/sale/auth"
 )
 
-type sellerAuthType int
+// seller_registration.authentication
+type SellerAuthType int
 
 const (
-	sellerWaitAuthentication = sellerAuthType(0)
-	sellerAuthenticated      = sellerAuthType(1)
+	SellerWaitAuthentication = SellerAuthType(0) // 관리자 승인 대기
+	SellerAuthenticated      = SellerAuthType(1) // 승인
+	SellerRejected           = SellerAuthType(2) // 반려
 )
 
 func init() {
@@ -44,12 +45,22 @@ func authSeller(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
 	var err error
-	uniqueId := ctx.FormValue("unique_id")
-	// token := ctx.FormValue("token") // TODO verification
-	sellerType, err := strconv.Atoi(ctx.FormValue("seller_type"))
+	sessionToken := ctx.FormValue("session_token")
+	uniqueId, err := customContext.ValidateSession(sessionToken, timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	sellerType, err := strconv.Atoi(ctx.FormValue("seller_type")) // 개인 0, 기업회원 1
 	if err != nil {
-		log.Error("failed to bind register user request")
+		customContext.Log.Error("failed to bind register user request")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageBindFailed
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -62,33 +73,73 @@ func authSeller(ctx echo.Context) error {
 	channelDescription := ctx.FormValue("channel_description")
 	bankName := ctx.FormValue("bank_name")
 	bankAccountNumber := ctx.FormValue("bank_account_number")
+
+	// 사업자등록번호 check digit, 국세청 사업자 상태, 계좌번호 형식과 예금주를 확인한다.
+	sellerInfo := &validation.SellerInfo{
+		SellerType:                sellerType,
+		CompanyRegistrationNumber: companyRegistrationNumber,
+		OwnerName:                 companyOwnerName,
+		BankName:                  bankName,
+		BankAccountNumber:         bankAccountNumber,
+	}
+	if err := customContext.VerifySeller(sellerInfo); err != nil {
+		customContext.Log.Error("seller verification failed. err: ", err)
+		resp.Status = vcomError.SellerVerificationFailed
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	companyRegistrationNumber = sellerInfo.CompanyRegistrationNumber
+	bankName = sellerInfo.BankName
+	bankAccountNumber = sellerInfo.BankAccountNumber
+
+	// 이미 등록한 판매자이거나 사용중인 채널 이름이면 파일을 저장하기 전에 거절한다.
+	_, registered, err := Authentication(customContext.Manager, timer, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if registered {
+		resp.Status = vcomError.SellerAlreadyRegistered
+		resp.Detail = vcomError.MessageSellerRegistered
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+	available, err := channelNameAvailable(customContext.Manager, timer, channelName)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if !available {
+		resp.Status = vcomError.ChannelNameBeingUsed
+		resp.Detail = vcomError.MessageChannelNameBeingUsed
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
 	file, err := ctx.FormFile("file")
 	if err != nil {
-		return err
+		customContext.Log.Error("formfile error. err: ", err)
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageIOFailed
+		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 	src, err := file.Open()
 	if err != nil {
-		return err
+		customContext.Log.Error("file open failed. err: ", err)
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageIOFailed
+		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 	defer src.Close()
 
-	filePath := fmt.Sprintf(config.Get().Api.SellerUploadFilePath + "/" + uniqueId + ".pdf")
-	// Destination
-	dst, err := os.Create(filePath) // TODO s3 나 특정 위치로 파일을 옮길 수 있어야 함.
+	// 사업자 등록증, 통장 사본. 소유자와 관리자만 서명된 url 로 접근 가능하다.
+	filePath, err := customContext.SaveAsset(asset.KindSeller, uniqueId+".pdf", src) // TODO s3 나 특정 위치로 파일을 옮길 수 있어야 함.
 	if err != nil {
-		return err
-	}
-	defer dst.Close()
-	// Copy
-	if _, err = io.Copy(dst, src); err != nil {
-		return err
+		customContext.Log.Error("save file failed. err: ", err)
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageIOFailed
+		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
-	// TODO check user qualification on redis
-
-	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
-	defer timer.Stop()
 
-	// channel registration first
+	// channel registration first. channel_name 의 unique key 로 동시 예약을 막는다.
 	resultCh := make(chan database.CudQueryResult)
 	values := []interface{}{
 		channelName,
@@ -96,7 +147,7 @@ func authSeller(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertSellerChannel, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -105,7 +156,7 @@ func authSeller(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -113,7 +164,7 @@ func authSeller(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -121,9 +172,9 @@ func authSeller(ctx echo.Context) error {
 
 	// channel registration
 	resultCh = make(chan database.CudQueryResult)
-	authProgress := sellerWaitAuthentication
+	authProgress := SellerWaitAuthentication
 	if sellerType == 0 {
-		authProgress = sellerAuthenticated
+		authProgress = SellerAuthenticated
 	}
 	values = []interface{}{
 		uniqueId,
@@ -133,7 +184,7 @@ func authSeller(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertSellerRegistration, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -142,7 +193,7 @@ func authSeller(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -150,14 +201,12 @@ func authSeller(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
-	// TODO 개인회원의 계좌정보 일치 확인 필요.
-	// TODO 법인회원 정보 확인.
 	// channel auth
 	resultCh = make(chan database.CudQueryResult)
 	values = []interface{}{
@@ -177,7 +226,7 @@ func authSeller(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertSellerAuth, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -186,7 +235,7 @@ func authSeller(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -194,7 +243,7 @@ func authSeller(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -0,0 +1,130 @@
+package seller
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 채널 이름 사용 가능 여부
+	channelNameCheckUrl = "/api/seller/channel/:name"
+	// 본인의 판매자 등록 상태
+	sellerStatusUrl = "/api/seller/status"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(channelNameCheckUrl, "GET"), checkChannelName)
+	route.AddRoute(route.NewRouteType(sellerStatusUrl, "GET"), getSellerStatus)
+}
+
+// 판매자 등록 상태. 등록하지 않은 사용자는 registered 가 false 이다.
+func Authentication(m database.Manager, timer *time.Timer, uniqueId string) (SellerAuthType, bool, error) {
+	rows, err := database.Select(m, timer, query.SelectSellerAuthentication, uniqueId)
+	if err != nil {
+		return SellerWaitAuthentication, false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return SellerWaitAuthentication, false, rows.Err()
+	}
+	authentication := SellerWaitAuthentication
+	if err := rows.Scan(&authentication); err != nil {
+		return SellerWaitAuthentication, false, err
+	}
+	return authentication, true, nil
+}
+
+func channelNameAvailable(m database.Manager, timer *time.Timer, channelName string) (bool, error) {
+	if channelName == "" {
+		return false, nil
+	}
+	rows, err := database.Select(m, timer, query.SelectSellerChannel, channelName)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return !rows.Next(), nil
+}
+
+func checkChannelName(ctx echo.Context) error {
+	resp := &protocol.ChannelNameCheckResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	available, err := channelNameAvailable(customContext.Manager, timer, ctx.Param("name"))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Available = available
+	resp.Status = vcomError.QueryResultOk
+	if !available {
+		resp.Status = vcomError.ChannelNameBeingUsed
+		resp.Detail = vcomError.MessageChannelNameBeingUsed
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getSellerStatus(ctx echo.Context) error {
+	resp := &protocol.SellerStatusResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectSellerStatus, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		resp.Status = vcomError.SellerNotFound
+		resp.Detail = vcomError.MessageSellerNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+	if err := rows.Scan(&resp.SellerType, &resp.CompanyName, &resp.ChannelName, &resp.ChannelUrl,
+		&resp.ChannelDescription, &resp.Authentication, &resp.Reason, &resp.Updated); err != nil {
+		customContext.Log.Error("scan failed. err: ", err)
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -2,7 +2,6 @@ package session
 
 import (
 	"errors"
-	"fmt"
 	"github.com/4538cgy/backend-second/database"
 	"github.com/4538cgy/backend-second/log"
 	"github.com/4538cgy/backend-second/query"
@@ -15,6 +14,7 @@ type Session interface {
 	InsertSession(uid string, timer *time.Timer) (string, error)            // sessionToken, error
 	ValidateSession(sessionToken string, timer *time.Timer) (string, error) // unique_id, error
 	UpdateSession(sessionToken string) (string, error)                      // newSessionToken, error
+	IsAdmin(uniqueId string, timer *time.Timer) (bool, error)               // 관리자 여부, error
 }
 
 type sessionHandler struct {
@@ -60,11 +60,11 @@ func (s *sessionHandler) InsertSession(uid string, timer *time.Timer) (string, e
 }
 
 func (s *sessionHandler) ValidateSession(sessionToken string, timer *time.Timer) (string, error) {
-	query := fmt.Sprintf("SELECT unique_id FROM vcommerce.session WHERE token='%s' LIMIT 1", sessionToken)
 	result := make(chan database.SelectQueryResult)
 	select {
-	case s.dbManager.SelectQueryWritePump() <- database.NewSelectTransaction(
-		query,
+	case s.dbManager.SelectQueryWritePump() <- database.NewSelectTransactionWithArgs(
+		query.SelectSessionUniqueId,
+		[]interface{}{sessionToken},
 		result,
 	):
 	case <-timer.C:
@@ -78,8 +78,9 @@ func (s *sessionHandler) ValidateSession(sessionToken string, timer *time.Timer)
 		if res.Err != nil {
 			return "", errors.New("session result failed")
 		}
+		defer res.Rows.Close()
 		if res.Rows.Next() {
-			res.Rows.Scan(&sessionToken)
+			res.Rows.Scan(&uniqueId)
 		}
 	case <-timer.C:
 		return "", errors.New("rollback needed")
@@ -95,3 +96,27 @@ func (s *sessionHandler) UpdateSession(sessionToken string) (string, error) {
 
 	return "", nil
 }
+
+func (s *sessionHandler) IsAdmin(uniqueId string, timer *time.Timer) (bool, error) {
+	result := make(chan database.SelectQueryResult)
+	select {
+	case s.dbManager.SelectQueryWritePump() <- database.NewSelectTransactionWithArgs(
+		query.SelectAdmin,
+		[]interface{}{uniqueId},
+		result,
+	):
+	case <-timer.C:
+		return false, errors.New("admin query timeout")
+	}
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return false, errors.New("admin result failed")
+		}
+		defer res.Rows.Close()
+		return res.Rows.Next(), nil
+	case <-timer.C:
+		return false, errors.New("rollback needed")
+	}
+}
@@ -0,0 +1,184 @@
+package settlement
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 로그인한 판매자 본인의 정산 정보
+	balanceUrl    = "/api/settlement/balance"
+	ledgerUrl     = "/api/settlement/ledger"
+	statementsUrl = "/api/settlement/statements"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(balanceUrl, "GET"), getBalance)
+	route.AddRoute(route.NewRouteType(ledgerUrl, "GET"), getLedger)
+	route.AddRoute(route.NewRouteType(statementsUrl, "GET"), getStatements)
+}
+
+func getBalance(ctx echo.Context) error {
+	resp := &protocol.SettlementBalanceResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	sellerId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectLedgerBalance, sellerId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var amount int64
+		if err := rows.Scan(&kind, &amount); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		switch kind {
+		case KindSale:
+			resp.SaleAmount = amount
+		case KindFee:
+			resp.FeeAmount = amount
+		case KindAdjust:
+			resp.AdjustAmount = amount
+		case KindPayout:
+			resp.PaidAmount = amount
+		}
+		resp.Balance += amount
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getLedger(ctx echo.Context) error {
+	resp := &protocol.SettlementLedgerResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	sellerId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, query.SelectLedgerEntries, sellerId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Entries = make([]protocol.LedgerEntryInfo, 0)
+	for rows.Next() {
+		entry := protocol.LedgerEntryInfo{}
+		if err := rows.Scan(&entry.EntryId, &entry.ReferenceId, &entry.Kind, &entry.Amount, &entry.Created); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	if len(resp.Entries) > count {
+		resp.Entries = resp.Entries[:count]
+		resp.HasNext = true
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getStatements(ctx echo.Context) error {
+	resp := &protocol.SettlementStatementResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	sellerId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, query.SelectSettlementStatements, sellerId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Statements = make([]protocol.StatementInfo, 0)
+	for rows.Next() {
+		statement := protocol.StatementInfo{}
+		if err := rows.Scan(&statement.StatementId, &statement.PeriodStart, &statement.PeriodEnd,
+			&statement.SaleAmount, &statement.FeeAmount, &statement.AdjustAmount, &statement.NetAmount,
+			&statement.Status, &statement.PaidAt); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Statements = append(resp.Statements, statement)
+	}
+	if len(resp.Statements) > count {
+		resp.Statements = resp.Statements[:count]
+		resp.HasNext = true
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,332 @@
+package settlement
+
+import (
+	"errors"
+	"github.com/4538cgy/backend-second/api/order"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"time"
+)
+
+// ledger 항목 종류. 금액은 판매자 입장에서 받을 돈이 + 이다.
+const (
+	KindSale   = "sale"   // 배송 완료된 주문 line 금액. 판매자 쿠폰 할인은 빼고 플랫폼 쿠폰 할인은 플랫폼이 부담한다.
+	KindFee    = "fee"    // 플랫폼 수수료. 항상 - 이다.
+	KindAdjust = "adjust" // 환불 등 정정
+	KindPayout = "payout" // 판매자 계좌로 지급. 항상 - 이다.
+	// 정산서 합계가 0 이하이면 지급하지 않고 carry_out 으로 그 정산서를 0 으로 맞춘 뒤
+	// 같은 금액을 carry_in 으로 다음 정산 기간에 넘긴다. ledger 전체 합은 바뀌지 않는다.
+	KindCarryOut = "carry_out" // 항상 + 이다. payout 처럼 정산서 합계에서 제외한다.
+	KindCarryIn  = "carry_in"  // 항상 - 이다.
+
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+
+	datetimeFormat     = "2006-01-02 15:04:05"
+	statementIdFormat  = "20060102"
+	accrueBatchSize    = 500
+	jobTimeout         = 30 * time.Second
+	defaultIntervalSec = 3600
+	bpsDenominator     = 10000
+)
+
+// 정산서 status
+const (
+	StatementPending = 0 // 지급 대기
+	StatementPaid    = 1 // 지급 완료
+	StatementCarried = 2 // 지급할 금액이 없어 다음 정산서로 넘겼다.
+)
+
+// 정산서 합계에 넣지 않는 ledger 항목
+var unstatedKinds = []string{KindPayout, KindCarryOut}
+
+var ErrStatementNotPayable = errors.New("statement is not payable")
+
+// 정산 기간. [Start, End)
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+type Settlement interface {
+	Start()
+}
+
+type settlementHandler struct {
+	dbManager database.Manager
+	feeBps    int64
+	period    string
+	interval  time.Duration
+}
+
+func NewSettlementHandler(cfg *config.Config, dbManager database.Manager) Settlement {
+	period := cfg.Settlement.Period
+	if period != PeriodMonthly {
+		period = PeriodWeekly
+	}
+	intervalSec := cfg.Settlement.IntervalSec
+	if intervalSec <= 0 {
+		intervalSec = defaultIntervalSec
+	}
+	return &settlementHandler{
+		dbManager: dbManager,
+		feeBps:    int64(cfg.Settlement.PlatformFeeBps),
+		period:    period,
+		interval:  time.Duration(intervalSec) * time.Second,
+	}
+}
+
+// 주기적으로 배송 완료된 주문을 ledger 에 쌓고, 지난 정산 기간의 정산서를 만든다.
+func (s *settlementHandler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.run(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+func (s *settlementHandler) run(now time.Time) {
+	timer := time.NewTimer(jobTimeout)
+	defer timer.Stop()
+
+	accrued, err := s.accrue(timer)
+	if err != nil {
+		log.Error("settlement accrue failed. err: ", err)
+		return
+	}
+	if accrued > 0 {
+		log.Info("settlement accrued order lines: ", accrued)
+	}
+
+	// 작업이 멈춰 있던 동안 끝난 기간도 빠짐없이 정산서를 만든다.
+	from, err := s.unclosedSince(timer)
+	if err != nil {
+		log.Error("settlement statement failed. err: ", err)
+		return
+	}
+	if !from.IsZero() {
+		for _, p := range ClosedPeriods(from, now, s.period) {
+			if err := s.closeStatements(timer, p.Start, p.End); err != nil {
+				log.Error("settlement statement failed. period_start: ", p.Start, ", err: ", err)
+				return
+			}
+		}
+	}
+	if err := s.carryUnpayable(timer); err != nil {
+		log.Error("settlement carry failed. err: ", err)
+	}
+}
+
+// 정산서에 들어가지 않은 가장 오래된 ledger 항목의 시각. 없으면 zero time 이다.
+func (s *settlementHandler) unclosedSince(timer *time.Timer) (time.Time, error) {
+	rows, err := database.Select(s.dbManager, timer, query.SelectUnclosedLedgerStart, unstatedKinds[0], unstatedKinds[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer rows.Close()
+
+	var created string
+	if rows.Next() {
+		if err := rows.Scan(&created); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if err := rows.Err(); err != nil || created == "" {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(datetimeFormat, created, time.Local)
+}
+
+type accrual struct {
+	orderLineId string
+	sellerId    string
+	amount      int64
+}
+
+// 아직 ledger 에 없는 배송 완료 주문 line 을 판매 금액과 수수료로 나눠 기록한다.
+func (s *settlementHandler) accrue(timer *time.Timer) (int, error) {
+	rows, err := database.Select(s.dbManager, timer, query.SelectUnsettledOrderLines,
+		order.StatusDelivered, KindSale, accrueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	accruals := make([]accrual, 0)
+	for rows.Next() {
+		a := accrual{}
+		if err := rows.Scan(&a.orderLineId, &a.sellerId, &a.amount); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		accruals = append(accruals, a)
+	}
+	rows.Close()
+
+	for _, a := range accruals {
+		// 수수료를 먼저 기록한다. 판매 금액이 기록되어야 다음 작업에서 제외되므로 중간에 실패해도 다시 시도된다.
+		fee := Fee(a.amount, s.feeBps)
+		if err := AppendEntry(s.dbManager, timer, a.sellerId, a.orderLineId, KindFee, -fee); err != nil {
+			return 0, err
+		}
+		if err := AppendEntry(s.dbManager, timer, a.sellerId, a.orderLineId, KindSale, a.amount); err != nil {
+			return 0, err
+		}
+	}
+	return len(accruals), nil
+}
+
+func (s *settlementHandler) closeStatements(timer *time.Timer, start, end time.Time) error {
+	_, err := database.Exec(s.dbManager, timer, query.InsertSettlementStatements,
+		start.Format(statementIdFormat),
+		start.Format(datetimeFormat), end.Format(datetimeFormat),
+		KindSale, KindFee, KindAdjust,
+		start.Format(datetimeFormat), end.Format(datetimeFormat),
+		unstatedKinds[0], unstatedKinds[1])
+	return err
+}
+
+type unpayable struct {
+	statementId string
+	sellerId    string
+	netAmount   int64
+}
+
+type ledgerEntry struct {
+	referenceId string
+	kind        string
+	amount      int64
+}
+
+// 합계가 0 이하인 정산서를 넘기는 ledger 항목. carry_out 은 정산서 합계에서 빠지므로
+// 그 기간의 ledger 합은 0 이 되고, 차감액은 carry_in 으로 다음 정산서에 들어간다.
+func carryEntries(statementId string, netAmount int64) []ledgerEntry {
+	if netAmount >= 0 {
+		return nil
+	}
+	return []ledgerEntry{
+		{referenceId: statementId, kind: KindCarryOut, amount: -netAmount},
+		{referenceId: statementId, kind: KindCarryIn, amount: netAmount},
+	}
+}
+
+// 반품 정정이 판매 금액보다 큰 정산서는 지급하지 않고 차감액을 다음 정산서로 넘긴다.
+// 이전 작업에서 넘기지 못한 정산서도 함께 처리한다.
+func (s *settlementHandler) carryUnpayable(timer *time.Timer) error {
+	rows, err := database.Select(s.dbManager, timer, query.SelectUnpayableStatements, StatementPending)
+	if err != nil {
+		return err
+	}
+	statements := make([]unpayable, 0)
+	for rows.Next() {
+		u := unpayable{}
+		if err := rows.Scan(&u.statementId, &u.sellerId, &u.netAmount); err != nil {
+			rows.Close()
+			return err
+		}
+		statements = append(statements, u)
+	}
+	rows.Close()
+
+	for _, u := range statements {
+		// ledger 항목은 한 번만 쌓이므로 status 를 바꾸기 전에 실패해도 다음 작업에서 다시 시도된다.
+		for _, entry := range carryEntries(u.statementId, u.netAmount) {
+			if err := AppendEntry(s.dbManager, timer, u.sellerId, entry.referenceId, entry.kind, entry.amount); err != nil {
+				return err
+			}
+		}
+		if _, err := database.Exec(s.dbManager, timer, query.UpdateSettlementStatementStatus,
+			StatementCarried, u.statementId, StatementPending); err != nil {
+			return err
+		}
+		log.Info("settlement statement carried. statement_id: ", u.statementId, ", net_amount: ", u.netAmount)
+	}
+	return nil
+}
+
+// ledger 에 항목을 추가한다. 같은 reference 와 kind 의 항목은 한 번만 기록된다.
+func AppendEntry(m database.Manager, timer *time.Timer, sellerId, referenceId, kind string, amount int64) error {
+	_, err := database.Exec(m, timer, query.InsertLedgerEntry, util.RandString(), sellerId, referenceId, kind, amount)
+	return err
+}
+
+// 환불된 주문 line 의 판매 금액과 수수료를 ledger 에서 되돌린다. referenceId 는 환불 key 이다.
+// 아직 판매 금액이 쌓이지 않은 line 이면 먼저 쌓아서 ledger 합이 0 이 되도록 한다.
+func Reverse(m database.Manager, timer *time.Timer, sellerId, orderLineId, referenceId string, amount int64) error {
+	fee := Fee(amount, int64(config.Get().Settlement.PlatformFeeBps))
+	if err := AppendEntry(m, timer, sellerId, orderLineId, KindFee, -fee); err != nil {
+		return err
+	}
+	if err := AppendEntry(m, timer, sellerId, orderLineId, KindSale, amount); err != nil {
+		return err
+	}
+	return AppendEntry(m, timer, sellerId, referenceId, KindAdjust, -(amount - fee))
+}
+
+// 수수료. 원 단위 미만은 버린다.
+func Fee(amount, feeBps int64) int64 {
+	return amount * feeBps / bpsDenominator
+}
+
+// now 직전에 끝난 정산 기간. weekly 는 월요일 0시, monthly 는 1일 0시에 시작한다.
+func ClosedPeriod(now time.Time, period string) (time.Time, time.Time) {
+	end := periodStart(now, period)
+	return nextPeriod(end, period, -1), end
+}
+
+// from 이 속한 기간부터 now 직전에 끝난 기간까지. from 이 아직 끝나지 않은 기간이면 비어 있다.
+func ClosedPeriods(from, now time.Time, period string) []Period {
+	_, end := ClosedPeriod(now, period)
+	periods := make([]Period, 0)
+	for start := periodStart(from, period); start.Before(end); start = nextPeriod(start, period, 1) {
+		periods = append(periods, Period{Start: start, End: nextPeriod(start, period, 1)})
+	}
+	return periods
+}
+
+// t 가 속한 기간의 시작 시각
+func periodStart(t time.Time, period string) time.Time {
+	if period == PeriodMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(today.Weekday()) + 6) % 7 // 월요일 0
+	return today.AddDate(0, 0, -offset)
+}
+
+// 기간 시작 시각에서 count 만큼 앞뒤 기간의 시작 시각
+func nextPeriod(start time.Time, period string, count int) time.Time {
+	if period == PeriodMonthly {
+		return start.AddDate(0, count, 0)
+	}
+	return start.AddDate(0, 0, 7*count)
+}
+
+// 정산서를 지급 완료로 바꾸고 지급액만큼 ledger 에서 뺀다.
+func MarkPaid(m database.Manager, timer *time.Timer, statementId string) error {
+	rows, err := database.Select(m, timer, query.SelectSettlementStatement, statementId)
+	if err != nil {
+		return err
+	}
+	var sellerId string
+	var netAmount int64
+	found := rows.Next() && rows.Scan(&sellerId, &netAmount) == nil
+	rows.Close()
+	// 0 이하인 정산서는 지급하지 않고 다음 정산서로 넘긴다.
+	if !found || netAmount <= 0 {
+		return ErrStatementNotPayable
+	}
+
+	res, err := database.Exec(m, timer, query.UpdateSettlementStatementPaid, statementId)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrStatementNotPayable
+	}
+	return AppendEntry(m, timer, sellerId, statementId, KindPayout, -netAmount)
+}
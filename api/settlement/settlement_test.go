@@ -0,0 +1,159 @@
+package settlement
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFee(t *testing.T) {
+	tests := []struct {
+		amount int64
+		feeBps int64
+		want   int64
+	}{
+		{10000, 1000, 1000},
+		{12345, 1000, 1234}, // 원 단위 미만은 버린다.
+		{9, 1000, 0},
+		{10000, 0, 0},
+		{10000, 250, 250},
+		{0, 1000, 0},
+		{-12345, 1000, -1234}, // 반품 정정
+	}
+	for _, test := range tests {
+		if got := Fee(test.amount, test.feeBps); got != test.want {
+			t.Errorf("Fee(%d, %d) = %d. want %d", test.amount, test.feeBps, got, test.want)
+		}
+	}
+}
+
+func TestClosedPeriod(t *testing.T) {
+	date := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name   string
+		now    time.Time
+		period string
+		start  time.Time
+		end    time.Time
+	}{
+		{"weekly monday midnight", date(2021, 4, 19, 0), PeriodWeekly, date(2021, 4, 12, 0), date(2021, 4, 19, 0)},
+		{"weekly wednesday", date(2021, 4, 21, 15), PeriodWeekly, date(2021, 4, 12, 0), date(2021, 4, 19, 0)},
+		{"weekly sunday", date(2021, 4, 25, 23), PeriodWeekly, date(2021, 4, 12, 0), date(2021, 4, 19, 0)},
+		{"weekly across month", date(2021, 5, 2, 9), PeriodWeekly, date(2021, 4, 19, 0), date(2021, 4, 26, 0)},
+		{"weekly across year", date(2021, 1, 1, 9), PeriodWeekly, date(2020, 12, 21, 0), date(2020, 12, 28, 0)},
+		{"unknown period is weekly", date(2021, 4, 21, 15), "", date(2021, 4, 12, 0), date(2021, 4, 19, 0)},
+		{"monthly first day", date(2021, 4, 1, 0), PeriodMonthly, date(2021, 3, 1, 0), date(2021, 4, 1, 0)},
+		{"monthly mid month", date(2021, 3, 15, 12), PeriodMonthly, date(2021, 2, 1, 0), date(2021, 3, 1, 0)},
+		{"monthly across year", date(2021, 1, 31, 12), PeriodMonthly, date(2020, 12, 1, 0), date(2021, 1, 1, 0)},
+	}
+	for _, test := range tests {
+		start, end := ClosedPeriod(test.now, test.period)
+		if !start.Equal(test.start) || !end.Equal(test.end) {
+			t.Errorf("%s: %s ~ %s. want %s ~ %s", test.name, start, end, test.start, test.end)
+		}
+	}
+}
+
+func TestClosedPeriods(t *testing.T) {
+	date := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name   string
+		from   time.Time
+		now    time.Time
+		period string
+		starts []time.Time
+	}{
+		{"weekly previous only", date(2021, 4, 14, 9), date(2021, 4, 21, 15), PeriodWeekly,
+			[]time.Time{date(2021, 4, 12, 0)}},
+		// 작업이 멈춰 있던 동안 끝난 기간도 모두 닫는다.
+		{"weekly after downtime", date(2021, 3, 31, 9), date(2021, 4, 21, 15), PeriodWeekly,
+			[]time.Time{date(2021, 3, 29, 0), date(2021, 4, 5, 0), date(2021, 4, 12, 0)}},
+		{"weekly current period", date(2021, 4, 19, 0), date(2021, 4, 21, 15), PeriodWeekly, []time.Time{}},
+		{"monthly across year", date(2020, 11, 20, 9), date(2021, 2, 1, 0), PeriodMonthly,
+			[]time.Time{date(2020, 11, 1, 0), date(2020, 12, 1, 0), date(2021, 1, 1, 0)}},
+		{"monthly current period", date(2021, 2, 3, 0), date(2021, 2, 10, 0), PeriodMonthly, []time.Time{}},
+	}
+	for _, test := range tests {
+		periods := ClosedPeriods(test.from, test.now, test.period)
+		if len(periods) != len(test.starts) {
+			t.Errorf("%s: %v", test.name, periods)
+			continue
+		}
+		for index, p := range periods {
+			if !p.Start.Equal(test.starts[index]) || !p.End.Equal(nextPeriod(p.Start, test.period, 1)) {
+				t.Errorf("%s: %d: %s ~ %s", test.name, index, p.Start, p.End)
+			}
+		}
+	}
+}
+
+// 정산서 합계. closeStatements 의 query 처럼 unstatedKinds 는 뺀다.
+func statementNet(entries []ledgerEntry) int64 {
+	var net int64
+	for _, entry := range entries {
+		stated := true
+		for _, kind := range unstatedKinds {
+			if entry.kind == kind {
+				stated = false
+			}
+		}
+		if stated {
+			net += entry.amount
+		}
+	}
+	return net
+}
+
+func ledgerSum(entries []ledgerEntry) int64 {
+	var sum int64
+	for _, entry := range entries {
+		sum += entry.amount
+	}
+	return sum
+}
+
+func TestCarryEntries(t *testing.T) {
+	for _, net := range []int64{0, 1, 1000} {
+		if entries := carryEntries("statement", net); len(entries) != 0 {
+			t.Fatalf("net: %d, entries: %v", net, entries)
+		}
+	}
+
+	// 판매 1000 원의 반품 정정 1500 원이 첫 기간에 들어왔다.
+	first := []ledgerEntry{
+		{referenceId: "line1", kind: KindSale, amount: 1000},
+		{referenceId: "line1", kind: KindFee, amount: -100},
+		{referenceId: "return:line0", kind: KindAdjust, amount: -1500},
+	}
+	net := statementNet(first)
+	if net != -600 {
+		t.Fatal("first net: ", net)
+	}
+	carried := carryEntries("seller_20210412", net)
+	if len(carried) != 2 || carried[0].kind != KindCarryOut || carried[1].kind != KindCarryIn {
+		t.Fatal("carried: ", carried)
+	}
+	// carry_out 은 같은 기간에, carry_in 은 넘긴 시각이 속한 다음 기간에 쌓인다.
+	first = append(first, carried[0])
+	second := []ledgerEntry{
+		carried[1],
+		{referenceId: "line2", kind: KindSale, amount: 2000},
+		{referenceId: "line2", kind: KindFee, amount: -200},
+	}
+
+	if net := statementNet(first); net != -600 {
+		t.Fatal("first statement changed: ", net)
+	}
+	if sum := ledgerSum(first); sum != 0 {
+		t.Fatal("first period ledger sum: ", sum)
+	}
+	if net := statementNet(second); net != 1200 {
+		t.Fatal("second net: ", net)
+	}
+	if sum := ledgerSum(append(first, second...)); sum != 1000-100-1500+2000-200 {
+		t.Fatal("ledger sum: ", sum)
+	}
+}
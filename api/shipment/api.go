@@ -0,0 +1,157 @@
+package shipment
+
+import (
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/order"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/4538cgy/backend-second/util"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// 판매자의 송장 등록(POST), 주문의 송장 목록(GET)
+	shipmentUrl = "/api/shipment"
+
+	maxInvoiceLength = 30
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(shipmentUrl, "POST"), registerShipment)
+	route.AddRoute(route.NewRouteType(shipmentUrl, "GET"), getShipments)
+}
+
+// 주문에서 판매자 본인 상품 중 아직 보내지 않은 line 을 모두 한 송장으로 보낸다.
+func registerShipment(ctx echo.Context) error {
+	resp := &protocol.ShipmentCreateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	sellerId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	orderId := ctx.FormValue("order_id")
+	courierCode := ctx.FormValue("courier_code")
+	invoiceNumber := strings.Replace(strings.TrimSpace(ctx.FormValue("invoice_number")), "-", "", -1)
+	if orderId == "" || invoiceNumber == "" || len(invoiceNumber) > maxInvoiceLength {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	if _, ok := CourierName(courierCode); !ok {
+		resp.Status = vcomError.UnknownCourier
+		resp.Detail = vcomError.MessageUnknownCourier
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	// 주문 생성, 결제 완료 line 만 보낼 수 있다.
+	shipmentId := util.RandString()
+	res, err := database.Exec(customContext.Manager, timer, fmt.Sprintf(query.UpdateOrderLinesShipping, "?,?"),
+		order.StatusShipping, shipmentId, orderId, sellerId, order.StatusOrdered, order.StatusPaid)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		resp.Status = vcomError.ShipmentNotAllowed
+		resp.Detail = vcomError.MessageShipmentNotAllowed
+		return ctx.JSON(http.StatusConflict, resp)
+	}
+
+	if _, err := database.Exec(customContext.Manager, timer, query.InsertShipment,
+		shipmentId, orderId, sellerId, courierCode, invoiceNumber, StatusRegistered); err != nil {
+		// TODO rollback needed
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	if err := order.SyncStatus(customContext.Manager, timer, orderId); err != nil {
+		customContext.Log.Error("order status sync failed. order_id: ", orderId, ", err: ", err)
+	}
+
+	if buyer, err := order.Buyer(customContext.Manager, timer, orderId); err != nil {
+		customContext.Log.Error("order buyer read failed. order_id: ", orderId, ", err: ", err)
+	} else if err := customContext.Notify(notification.Notification{
+		UniqueId:    buyer,
+		Category:    notification.CategoryOrder,
+		Title:       "상품이 발송되었습니다.",
+		Body:        fmt.Sprintf("%s %s", couriers[courierCode], invoiceNumber),
+		ReferenceId: orderId,
+	}); err != nil {
+		customContext.Log.Error("notify shipment failed. order_id: ", orderId, ", err: ", err)
+	}
+
+	resp.ShipmentId = shipmentId
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getShipments(ctx echo.Context) error {
+	resp := &protocol.ShipmentListResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	// 구매자는 주문의 모든 송장을, 판매자는 자기가 보낸 송장만 본다.
+	rows, err := database.Select(customContext.Manager, timer, query.SelectShipmentsByOrder,
+		ctx.QueryParam("order_id"), uniqueId, uniqueId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Shipments = make([]protocol.ShipmentInfo, 0)
+	for rows.Next() {
+		s := protocol.ShipmentInfo{}
+		if err := rows.Scan(&s.ShipmentId, &s.OrderId, &s.SellerId, &s.CourierCode, &s.InvoiceNumber,
+			&s.Status, &s.Location, &s.Created, &s.Updated); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		s.CourierName, _ = CourierName(s.CourierCode)
+		resp.Shipments = append(resp.Shipments, s)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
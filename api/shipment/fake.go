@@ -0,0 +1,33 @@
+package shipment
+
+import (
+	"time"
+)
+
+const defaultFakeDeliverAfter = 24 * time.Hour
+
+// 송장 등록 후 지난 시간만으로 배송 상태를 정한다. 개발 환경과 test 에 사용한다.
+type fakeTracker struct {
+	deliverAfter time.Duration
+	now          func() time.Time
+}
+
+func NewFakeTracker(deliverAfter time.Duration) Tracker {
+	if deliverAfter <= 0 {
+		deliverAfter = defaultFakeDeliverAfter
+	}
+	return &fakeTracker{deliverAfter: deliverAfter, now: time.Now}
+}
+
+func (f *fakeTracker) Track(courierCode, invoiceNumber string, registered time.Time) (TrackingResult, error) {
+	elapsed := f.now().Sub(registered)
+	switch {
+	case elapsed >= f.deliverAfter:
+		return TrackingResult{Status: StatusDelivered, Location: "배송지"}, nil
+	case elapsed >= f.deliverAfter*2/3:
+		return TrackingResult{Status: StatusOutForDelivery, Location: "배송 대리점"}, nil
+	case elapsed >= f.deliverAfter/3:
+		return TrackingResult{Status: StatusInTransit, Location: "허브 터미널"}, nil
+	}
+	return TrackingResult{Status: StatusRegistered, Location: "집하점"}, nil
+}
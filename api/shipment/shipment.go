@@ -0,0 +1,186 @@
+package shipment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/api/notification"
+	"github.com/4538cgy/backend-second/api/order"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/query"
+	"time"
+)
+
+// 배송 상태. shipment.status
+const (
+	StatusRegistered     = "registered"       // 송장 등록
+	StatusInTransit      = "in_transit"       // 이동중
+	StatusOutForDelivery = "out_for_delivery" // 배송 출발
+	StatusDelivered      = "delivered"        // 배송 완료
+)
+
+const (
+	trackerFake = "fake"
+
+	defaultRefreshIntervalSec = 600
+	refreshBatchSize          = 200
+	jobTimeout                = 30 * time.Second
+)
+
+// 택배사 코드. 배송 조회 업체들이 쓰는 코드를 따른다.
+var couriers = map[string]string{
+	"01": "우체국택배",
+	"04": "CJ대한통운",
+	"05": "한진택배",
+	"06": "로젠택배",
+	"08": "롯데택배",
+	"23": "경동택배",
+	"46": "CU편의점택배",
+	"24": "GS Postbox 택배",
+}
+
+func CourierName(code string) (string, bool) {
+	name, ok := couriers[code]
+	return name, ok
+}
+
+type TrackingResult struct {
+	Status   string
+	Location string // 마지막으로 확인된 위치
+}
+
+// 택배사 배송 조회
+type Tracker interface {
+	Track(courierCode, invoiceNumber string, registered time.Time) (TrackingResult, error)
+}
+
+type Shipment interface {
+	Start()
+}
+
+type shipmentHandler struct {
+	dbManager database.Manager
+	notifier  notification.Notifier
+	tracker   Tracker
+	interval  time.Duration
+}
+
+func NewShipmentHandler(cfg *config.Config, dbManager database.Manager, notifier notification.Notifier) (Shipment, error) {
+	h := &shipmentHandler{dbManager: dbManager, notifier: notifier}
+	switch cfg.Shipment.Tracker {
+	case trackerFake:
+		// 실제 배송과 상관없이 배송 완료로 바꾸므로 설정에 직접 적었을 때만 쓴다.
+		log.Warning("fake shipment tracker is used. every shipment will be delivered after ", cfg.Shipment.FakeDeliverAfterSec, " sec.")
+		h.tracker = NewFakeTracker(time.Duration(cfg.Shipment.FakeDeliverAfterSec) * time.Second)
+	case "":
+		return nil, errors.New("shipment tracker is not configured")
+	default:
+		// TODO 배송 조회 업체가 정해지면 추가.
+		return nil, fmt.Errorf("wrong tracker: %s", cfg.Shipment.Tracker)
+	}
+
+	intervalSec := cfg.Shipment.RefreshIntervalSec
+	if intervalSec <= 0 {
+		intervalSec = defaultRefreshIntervalSec
+	}
+	h.interval = time.Duration(intervalSec) * time.Second
+	return h, nil
+}
+
+// 배송 완료되지 않은 송장을 주기적으로 조회한다.
+func (h *shipmentHandler) Start() {
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			h.refresh()
+			<-ticker.C
+		}
+	}()
+}
+
+type tracking struct {
+	shipmentId    string
+	orderId       string
+	sellerId      string
+	courierCode   string
+	invoiceNumber string
+	status        string
+	created       time.Time
+}
+
+func (h *shipmentHandler) refresh() {
+	timer := time.NewTimer(jobTimeout)
+	defer timer.Stop()
+
+	rows, err := database.Select(h.dbManager, timer, query.SelectShipmentsInTransit, StatusDelivered, refreshBatchSize)
+	if err != nil {
+		log.Error("shipment select failed. err: ", err)
+		return
+	}
+	shipments := make([]tracking, 0)
+	for rows.Next() {
+		t := tracking{}
+		var created string
+		if err := rows.Scan(&t.shipmentId, &t.orderId, &t.sellerId, &t.courierCode, &t.invoiceNumber, &t.status, &created); err != nil {
+			rows.Close()
+			log.Error("shipment scan failed. err: ", err)
+			return
+		}
+		t.created, _ = time.ParseInLocation("2006-01-02 15:04:05", created, time.Local)
+		shipments = append(shipments, t)
+	}
+	rows.Close()
+
+	for _, t := range shipments {
+		result, err := h.tracker.Track(t.courierCode, t.invoiceNumber, t.created)
+		if err != nil {
+			log.Error("tracking failed. shipment_id: ", t.shipmentId, ", err: ", err)
+			continue
+		}
+		delivered := result.Status == StatusDelivered && t.status != StatusDelivered
+		// 주문 line 을 먼저 바꾼다. 실패하면 송장도 배송 완료로 바꾸지 않아 다음 조회에서 다시 시도한다.
+		if delivered {
+			if err := h.deliver(timer, t); err != nil {
+				log.Error("shipment deliver failed. shipment_id: ", t.shipmentId, ", err: ", err)
+				continue
+			}
+		}
+		// 바뀐 게 없어도 updated 를 갱신해서 다음 조회 순서가 뒤로 밀리게 한다.
+		if _, err := database.Exec(h.dbManager, timer, query.UpdateShipmentStatus, result.Status, result.Location, t.shipmentId); err != nil {
+			log.Error("shipment update failed. shipment_id: ", t.shipmentId, ", err: ", err)
+			continue
+		}
+		if delivered {
+			log.Info("shipment delivered. shipment_id: ", t.shipmentId, ", order_id: ", t.orderId)
+			h.notifyDelivered(timer, t)
+		}
+	}
+}
+
+// 송장의 주문 line 을 배송 완료로 바꾸고 주문 status 를 다시 계산한다. 이미 배송 완료된 line 은 그대로 둔다.
+func (h *shipmentHandler) deliver(timer *time.Timer, t tracking) error {
+	if _, err := database.Exec(h.dbManager, timer, query.UpdateShipmentLinesStatus,
+		order.StatusDelivered, t.shipmentId, order.StatusShipping); err != nil {
+		return err
+	}
+	return order.SyncStatus(h.dbManager, timer, t.orderId)
+}
+
+func (h *shipmentHandler) notifyDelivered(timer *time.Timer, t tracking) {
+	buyer, err := order.Buyer(h.dbManager, timer, t.orderId)
+	if err != nil || buyer == "" {
+		log.Error("order buyer read failed. order_id: ", t.orderId, ", err: ", err)
+		return
+	}
+	if err := h.notifier.Notify(notification.Notification{
+		UniqueId:    buyer,
+		Category:    notification.CategoryOrder,
+		Title:       "배송이 완료되었습니다.",
+		Body:        "상품은 마음에 드셨나요? 리뷰를 남겨주세요.",
+		ReferenceId: t.orderId,
+	}); err != nil {
+		log.Error("notify delivered failed. order_id: ", t.orderId, ", err: ", err)
+	}
+}
@@ -0,0 +1,302 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/media"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/api/types"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// 앱이 모아서 보내는 영상 이벤트. 피드 순위에 쓰이므로 로그인한 사용자만 보낼 수 있다.
+	videoEventUrl = "/api/stats/events"
+	// 판매자 본인 상품의 기간별 통계와 영상별 통계
+	productStatUrl = "/api/stats/product/:product_id"
+	videoStatUrl   = "/api/stats/product/:product_id/videos"
+
+	maxEventsPerRequest = 100
+	datetimeFormat      = "2006-01-02 15:04:05"
+)
+
+// 통계 기간. 24h 는 시간 단위, 나머지는 날짜 단위로 나눈다.
+var windows = map[string]struct {
+	days         int
+	bucketFormat string
+}{
+	"24h": {1, "%Y-%m-%d %H:00"},
+	"7d":  {7, "%Y-%m-%d"},
+	"30d": {30, "%Y-%m-%d"},
+}
+
+const defaultWindow = "7d"
+
+func init() {
+	route.AddRoute(route.NewRouteType(videoEventUrl, "POST"), postVideoEvents)
+	route.AddRoute(route.NewRouteType(productStatUrl, "GET"), getProductStat)
+	route.AddRoute(route.NewRouteType(videoStatUrl, "GET"), getVideoStat)
+}
+
+func postVideoEvents(ctx echo.Context) error {
+	resp := &protocol.VideoEventResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	request := &protocol.VideoEventRequest{}
+	if err := ctx.Bind(request); err != nil {
+		customContext.Log.Error("failed to bind video event request. err: ", err)
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageBindFailed
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	if len(request.Events) > maxEventsPerRequest {
+		resp.Status = vcomError.InvalidParameter
+		resp.Detail = vcomError.MessageInvalidParameter
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	events, err := productEvents(customContext.Manager, timer, request.Events)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Accepted = customContext.RecordEvents(uniqueId, events)
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// 상품에 속한 영상의 이벤트만 남긴다. 아무 id 로 메모리에 값이 쌓이지 않도록 기록하기 전에 확인한다.
+func productEvents(m database.Manager, timer *time.Timer, events []types.VideoEvent) ([]types.VideoEvent, error) {
+	productIds := make([]interface{}, 0)
+	seen := map[string]bool{}
+	for _, event := range events {
+		if event.ProductId == "" || seen[event.ProductId] {
+			continue
+		}
+		seen[event.ProductId] = true
+		productIds = append(productIds, event.ProductId)
+	}
+	if len(productIds) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(productIds)), ",")
+	rows, err := database.Select(m, timer, fmt.Sprintf(query.SelectProductVideos, placeholders), productIds...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	videos := map[string]map[string]bool{} // product id -> video id
+	for rows.Next() {
+		var productId, videoListJson string
+		if err := rows.Scan(&productId, &videoListJson); err != nil {
+			return nil, err
+		}
+		videos[productId] = map[string]bool{}
+		for _, videoId := range media.ParseIndices(videoListJson) {
+			videos[productId][videoId] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	valid := make([]types.VideoEvent, 0, len(events))
+	for _, event := range events {
+		if videos[event.ProductId][event.VideoId] {
+			valid = append(valid, event)
+		}
+	}
+	return valid, nil
+}
+
+// 상품 판매자의 session 인지 확인한다. 실패하면 응답할 http status code 를 돌려준다.
+func validateOwner(customContext *context.CustomContext, resp *protocol.BaseResponse, timer *time.Timer) (string, int) {
+	sellerId, err := customContext.ValidateSession(customContext.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return "", http.StatusUnauthorized
+	}
+
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProductOwner, customContext.Param("product_id"))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return "", context.SetQueryError(resp, err)
+	}
+	var ownerId string
+	found := rows.Next() && rows.Scan(&ownerId) == nil
+	rows.Close()
+	if !found {
+		resp.Status = vcomError.ProductNotFound
+		resp.Detail = vcomError.MessageProductNotFound
+		return "", http.StatusNotFound
+	}
+	if ownerId != sellerId {
+		resp.Status = vcomError.PermissionDenied
+		resp.Detail = vcomError.MessagePermissionDenied
+		return "", http.StatusForbidden
+	}
+	return sellerId, http.StatusOK
+}
+
+func window(ctx echo.Context) (string, time.Time, string) {
+	name := ctx.QueryParam("window")
+	w, ok := windows[name]
+	if !ok {
+		name = defaultWindow
+		w = windows[name]
+	}
+	now := time.Now()
+	start := now.Add(-24 * time.Hour).Truncate(time.Hour)
+	if w.days > 1 {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		start = today.AddDate(0, 0, 1-w.days)
+	}
+	return name, start, w.bucketFormat
+}
+
+func scanStat(rows *sql.Rows) (protocol.StatInfo, error) {
+	stat := protocol.StatInfo{}
+	err := rows.Scan(&stat.Bucket, &stat.Impressions, &stat.Plays,
+		&stat.Watch25, &stat.Watch50, &stat.Watch75, &stat.Watch100, &stat.Taps)
+	rates(&stat)
+	return stat, err
+}
+
+func rates(stat *protocol.StatInfo) {
+	stat.PlayRate, stat.TapRate, stat.CompletionRate = 0, 0, 0
+	if stat.Impressions > 0 {
+		stat.PlayRate = float64(stat.Plays) / float64(stat.Impressions)
+	}
+	if stat.Plays > 0 {
+		stat.TapRate = float64(stat.Taps) / float64(stat.Plays)
+		stat.CompletionRate = float64(stat.Watch100) / float64(stat.Plays)
+	}
+}
+
+func getProductStat(ctx echo.Context) error {
+	resp := &protocol.ProductStatResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	sellerId, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+
+	name, start, bucketFormat := window(ctx)
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProductStatSeries,
+		bucketFormat, ctx.Param("product_id"), sellerId, start.Format(datetimeFormat))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Series = make([]protocol.StatInfo, 0)
+	for rows.Next() {
+		stat, err := scanStat(rows)
+		if err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Series = append(resp.Series, stat)
+		resp.Total.Impressions += stat.Impressions
+		resp.Total.Plays += stat.Plays
+		resp.Total.Watch25 += stat.Watch25
+		resp.Total.Watch50 += stat.Watch50
+		resp.Total.Watch75 += stat.Watch75
+		resp.Total.Watch100 += stat.Watch100
+		resp.Total.Taps += stat.Taps
+	}
+	rates(&resp.Total)
+
+	resp.Window = name
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getVideoStat(ctx echo.Context) error {
+	resp := &protocol.VideoStatResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	sellerId, code := validateOwner(customContext, &resp.BaseResponse, timer)
+	if code != http.StatusOK {
+		return ctx.JSON(code, resp)
+	}
+
+	name, start, _ := window(ctx)
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProductVideoStats,
+		ctx.Param("product_id"), sellerId, start.Format(datetimeFormat))
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Videos = make([]protocol.StatInfo, 0)
+	for rows.Next() {
+		stat, err := scanStat(rows)
+		if err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Videos = append(resp.Videos, stat)
+	}
+
+	resp.Window = name
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 type MediaType int
 
 const (
@@ -17,6 +22,15 @@ func (m MediaType) String() string {
 	return "Unknown"
 }
 
+// 확장자로 media type 을 판별한다. 알 수 없는 확장자는 video 로 취급한다.
+func MediaTypeOf(filename string) MediaType {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic":
+		return ImageType
+	}
+	return VideoType
+}
+
 type (
 	MediaInfo struct {
 		Kind       string `json:"media_kind"`
@@ -32,3 +46,28 @@ type (
 type MediaIndices struct {
 	MediaIds []string `json:"media_indices"`
 }
+
+// option_json 의 구조. 옵션 그룹(사이즈, 색상 등)과 그룹별 값 하나씩을 고른 조합(sku)으로 이루어진다.
+type (
+	OptionGroup struct {
+		Name   string   `json:"name"`
+		Values []string `json:"values"`
+	}
+	OptionSku struct {
+		Selected   map[string]string `json:"selected"`    // 그룹 이름: 값
+		PriceDelta int               `json:"price_delta"` // base_price 에 더해지는 금액
+		Stock      int               `json:"stock"`
+	}
+	ProductOptions struct {
+		Groups []OptionGroup `json:"groups"`
+		Skus   []OptionSku   `json:"skus"`
+	}
+)
+
+// 앱이 보내는 영상 이벤트 하나. milestone 은 watch 이벤트에서만 쓴다.
+type VideoEvent struct {
+	VideoId   string `json:"video_id"`
+	ProductId string `json:"product_id"`
+	Type      string `json:"event"`     // impression | play | watch | tap
+	Milestone int    `json:"milestone"` // 25 | 50 | 75 | 100
+}
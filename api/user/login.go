@@ -53,7 +53,7 @@ func loginForNonFirebase(ctx echo.Context) error {
 	nonFirebaseLoginRequest := &protocol.NonFirebaseAuthRequest{}
 	err := ctx.Bind(nonFirebaseLoginRequest)
 	if err != nil {
-		log.Error("failed to bind register user request")
+		customContext.Log.Error("failed to bind register user request")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageBindFailed
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -97,7 +97,7 @@ func loginForNonFirebase(ctx echo.Context) error {
 	}
 
 	if signedInUser {
-		log.Info("Already registered user.")
+		customContext.Log.Info("Already registered user.")
 		resp.SignedIn = true
 		resp.Status = vcomError.QueryResultOk
 		resp.Email = email
@@ -130,7 +130,7 @@ func loginForFirebase(ctx echo.Context) error {
 	firebaseLoginRequest := &protocol.FirebaseAuthRequest{}
 	err := ctx.Bind(firebaseLoginRequest)
 	if err != nil {
-		log.Error("failed to bind register user request")
+		customContext.Log.Error("failed to bind register user request")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageBindFailed
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -183,7 +183,7 @@ func loginForFirebase(ctx echo.Context) error {
 	}
 
 	if signedInUser {
-		log.Info("Already registered user.")
+		customContext.Log.Info("Already registered user.")
 		resp.SignedIn = true
 		resp.Status = vcomError.QueryResultOk
 		return ctx.JSON(http.StatusOK, resp)
@@ -208,7 +208,7 @@ func login(ctx echo.Context) error {
 	loginRequest := &protocol.LoginRequest{}
 	err := ctx.Bind(loginRequest)
 	if err != nil {
-		log.Error("failed to bind register user request")
+		customContext.Log.Error("failed to bind register user request")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageBindFailed
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -276,7 +276,7 @@ func login(ctx echo.Context) error {
 		resp.Status = vcomError.SessionInsertionFailed
 		resp.Detail = vcomError.MessageIOFailed
 	}
-	log.Info("Already registered user.")
+	customContext.Log.Info("Already registered user.")
 	resp.SessionToken = serverSessionToken
 	resp.Status = vcomError.QueryResultOk
 	return ctx.JSON(http.StatusOK, resp)
@@ -2,6 +2,7 @@ package user
 
 import (
 	"fmt"
+	"github.com/4538cgy/backend-second/api/asset"
 	"github.com/4538cgy/backend-second/api/context"
 	"github.com/4538cgy/backend-second/api/route"
 	"github.com/4538cgy/backend-second/config"
@@ -12,9 +13,7 @@ import (
 	"github.com/4538cgy/backend-second/query"
 	"github.com/4538cgy/backend-second/util"
 	"github.com/labstack/echo/v4"
-	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"time"
 )
@@ -70,7 +69,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertEmail, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -79,7 +78,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -87,7 +86,7 @@ func registerUser(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -101,7 +100,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertUserID, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -110,7 +109,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -118,7 +117,7 @@ func registerUser(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -127,41 +126,36 @@ func registerUser(ctx echo.Context) error {
 	// save file
 	file, err := ctx.FormFile("file")
 	if err != nil {
-		log.Error("FormFile failed. err: ", err)
+		customContext.Log.Error("FormFile failed. err: ", err)
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageUnknownError
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 	src, err := file.Open()
 	if err != nil {
-		log.Error("File Open failed. err: ", err)
+		customContext.Log.Error("File Open failed. err: ", err)
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageUnknownError
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 	defer src.Close()
 
-	ext := filepath.Ext(file.Filename)
-	filePath := fmt.Sprintf(config.Get().Asset.UserProfileImageSavePath + "/" + uniqueId + ext)
-	// Destination
-	dst, err := os.Create(filePath) // TODO s3 나 특정 위치로 파일을 옮길 수 있어야 함.
+	name := uniqueId + filepath.Ext(file.Filename)
+	filePath, err := customContext.SaveAsset(asset.KindProfile, name, src) // TODO s3 나 특정 위치로 파일을 옮길 수 있어야 함.
 	if err != nil {
-		log.Error("File Create failed. err: ", err)
-		resp.Status = vcomError.InternalError
-		resp.Detail = vcomError.MessageUnknownError
-		return ctx.JSON(http.StatusInternalServerError, resp)
-	}
-	defer dst.Close()
-	// Copy
-	if _, err = io.Copy(dst, src); err != nil {
-		log.Error("File Copy failed. err: ", err)
+		customContext.Log.Error("File Save failed. err: ", err)
+		if err == asset.ErrUnsupportedType {
+			resp.Status = vcomError.InvalidParameter
+			resp.Detail = vcomError.MessageInvalidParameter
+			return ctx.JSON(http.StatusBadRequest, resp)
+		}
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageUnknownError
 		return ctx.JSON(http.StatusInternalServerError, resp)
 	}
 
-	profileImagePath := "/asset/profile/" + uniqueId + ext
-	log.Info("file saved: ", filePath)
+	profileImagePath := customContext.AssetUrl(asset.KindProfile, name)
+	customContext.Log.Info("file saved: ", filePath)
 	// user insert
 	resultCh = make(chan database.CudQueryResult)
 	values = []interface{}{
@@ -177,7 +171,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertUser, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -186,7 +180,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -194,7 +188,7 @@ func registerUser(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -211,7 +205,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case customContext.InsertQueryWritePump() <- database.NewCudTransaction(query.InsertSession, values, resultCh):
 	case <-timer.C:
-		log.Error("failed to exec query")
+		customContext.Log.Error("failed to exec query")
 		resp.Status = vcomError.ApiOperationRequestTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -220,7 +214,7 @@ func registerUser(ctx echo.Context) error {
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			log.Error("database operation failed.")
+			customContext.Log.Error("database operation failed.")
 			resp.Status = vcomError.DatabaseOperationError
 			resp.Detail = res.Err.Error()
 			return ctx.JSON(http.StatusInternalServerError, resp)
@@ -228,7 +222,7 @@ func registerUser(ctx echo.Context) error {
 
 	case <-timer.C:
 		// TODO rollback needed
-		log.Error("database operation timeout.")
+		customContext.Log.Error("database operation timeout.")
 		resp.Status = vcomError.ApiOperationResponseTimeout
 		resp.Detail = vcomError.MessageOperationTimeout
 		return ctx.JSON(http.StatusInternalServerError, resp)
@@ -248,7 +242,7 @@ func checkEmail(ctx echo.Context) error {
 	}
 	emailAddress := ctx.QueryParam(paramEmail)
 	if emailAddress == "" {
-		log.Error("no query param.")
+		customContext.Log.Error("no query param.")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageQueryParamNotfound
 	}
@@ -299,7 +293,7 @@ func checkUserId(ctx echo.Context) error {
 	}
 	userId := ctx.QueryParam(paramUser)
 	if userId == "" {
-		log.Error("no query param.")
+		customContext.Log.Error("no query param.")
 		resp.Status = vcomError.InternalError
 		resp.Detail = vcomError.MessageQueryParamNotfound
 	}
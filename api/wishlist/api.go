@@ -0,0 +1,194 @@
+package wishlist
+
+import (
+	"github.com/4538cgy/backend-second/api/context"
+	"github.com/4538cgy/backend-second/api/product"
+	"github.com/4538cgy/backend-second/api/route"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/database"
+	vcomError "github.com/4538cgy/backend-second/error"
+	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/protocol"
+	"github.com/4538cgy/backend-second/query"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	// 찜한 상품 목록
+	wishlistUrl = "/api/wishlist"
+	// 찜하기(POST), 찜 취소(DELETE)
+	wishlistProductUrl = "/api/wishlist/:product_id"
+	// 찜한 상품의 가격 인하, 재입고 알림
+	wishlistAlertUrl = "/api/wishlist/alerts"
+)
+
+func init() {
+	route.AddRoute(route.NewRouteType(wishlistUrl, "GET"), getWishlist)
+	route.AddRoute(route.NewRouteType(wishlistProductUrl, "POST"), likeProduct)
+	route.AddRoute(route.NewRouteType(wishlistProductUrl, "DELETE"), unlikeProduct)
+	route.AddRoute(route.NewRouteType(wishlistAlertUrl, "GET"), getAlerts)
+}
+
+func getWishlist(ctx echo.Context) error {
+	resp := &protocol.WishlistResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, query.SelectWishlistProducts, uniqueId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	products, videoIndices, err := product.Scan(rows)
+	if err != nil {
+		customContext.Log.Error("scan failed. err: ", err)
+		resp.Status = vcomError.DatabaseOperationError
+		resp.Detail = err.Error()
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+	if len(products) > count {
+		products, videoIndices = products[:count], videoIndices[:count]
+		resp.HasNext = true
+	}
+	if err := product.ExpandVideos(customContext.Manager, timer, products, videoIndices); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	resp.Products = products
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func likeProduct(ctx echo.Context) error {
+	return updateWishlist(ctx, query.InsertWishlist)
+}
+
+func unlikeProduct(ctx echo.Context) error {
+	return updateWishlist(ctx, query.DeleteWishlist)
+}
+
+func updateWishlist(ctx echo.Context, wishlistQuery string) error {
+	resp := &protocol.WishlistUpdateResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.FormValue("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	productId := ctx.Param("product_id")
+	rows, err := database.Select(customContext.Manager, timer, query.SelectProductOwner, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	found := rows.Next()
+	rows.Close()
+	if !found {
+		resp.Status = vcomError.ProductNotFound
+		resp.Detail = vcomError.MessageProductNotFound
+		return ctx.JSON(http.StatusNotFound, resp)
+	}
+
+	// 이미 찜했거나 찜하지 않은 상품이어도 성공으로 처리한다.
+	if _, err := database.Exec(customContext.Manager, timer, wishlistQuery, uniqueId, productId); err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+
+	rows, err = database.Select(customContext.Manager, timer, query.SelectWishlistCount, productId)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		rows.Scan(&resp.LikeCount)
+	}
+
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func getAlerts(ctx echo.Context) error {
+	resp := &protocol.WishlistAlertResponse{}
+	customContext, ok := ctx.(*context.CustomContext)
+	if !ok {
+		log.Error("failed to casting echo.Context to api.CustomContext")
+		resp.Status = vcomError.InternalError
+		resp.Detail = vcomError.MessageUnknownError
+		return ctx.JSON(http.StatusInternalServerError, resp)
+	}
+
+	timer := time.NewTimer(time.Duration(config.Get().Api.HandleTimeoutMS) * time.Second)
+	defer timer.Stop()
+
+	uniqueId, err := customContext.ValidateSession(ctx.QueryParam("session_token"), timer)
+	if err != nil {
+		customContext.Log.Error("validate session failed. err: ", err)
+		resp.Status = vcomError.SessionValidationFailed
+		resp.Detail = vcomError.MessageInvalidSession
+		return ctx.JSON(http.StatusUnauthorized, resp)
+	}
+
+	page, count := customContext.Paging()
+	rows, err := database.Select(customContext.Manager, timer, query.SelectWishlistAlerts, uniqueId, count+1, page*count)
+	if err != nil {
+		customContext.Log.Error("database operation failed. err: ", err)
+		return ctx.JSON(context.SetQueryError(&resp.BaseResponse, err), resp)
+	}
+	defer rows.Close()
+
+	resp.Alerts = make([]protocol.WishlistAlertInfo, 0)
+	for rows.Next() {
+		alert := protocol.WishlistAlertInfo{}
+		if err := rows.Scan(&alert.AlertId, &alert.ProductId, &alert.Title, &alert.Kind, &alert.Price, &alert.Created); err != nil {
+			customContext.Log.Error("scan failed. err: ", err)
+			resp.Status = vcomError.DatabaseOperationError
+			resp.Detail = err.Error()
+			return ctx.JSON(http.StatusInternalServerError, resp)
+		}
+		resp.Alerts = append(resp.Alerts, alert)
+	}
+	if len(resp.Alerts) > count {
+		resp.Alerts = resp.Alerts[:count]
+		resp.HasNext = true
+	}
+
+	resp.Page = page
+	resp.Status = vcomError.QueryResultOk
+	return ctx.JSON(http.StatusOK, resp)
+}
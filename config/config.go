@@ -16,11 +16,15 @@ type Database struct {
 	Port              int
 	MaxOpenConnection int
 	MaxIdleConnection int
+	// 시작할 때 DB 에 붙지 못하면 간격을 두 배씩 늘리며 다시 시도한다.
+	ConnectRetryCount      int
+	ConnectRetryIntervalMS int
 }
 
 type LogConfig struct {
 	Enable   bool
 	StdOut   bool
+	Format   string // text | json
 	Level    string
 	Filename string
 	MaxSize  int
@@ -38,20 +42,80 @@ type Api struct {
 
 type Asset struct {
 	UserProfileImageSavePath string
+	MediaSavePath            string
+	UrlSigningKey            string
+	SignedUrlExpireSec       int
 }
 
 type Firebase struct {
 	ServiceAccountKeyPath string
 }
 
+type Verify struct {
+	BusinessVerifier string // nts. fake 는 개발 환경에서만 쓴다.
+	NtsServiceKey    string
+	AccountVerifier  string // manual 이면 예금주를 확인하지 않고 관리자 승인에 맡긴다. fake 는 개발 환경에서만 쓴다.
+}
+
+type Settlement struct {
+	PlatformFeeBps int    // 플랫폼 수수료. 1bps = 0.01%
+	Period         string // weekly | monthly
+	IntervalSec    int    // 정산 작업 주기
+}
+
+type Engagement struct {
+	FlushIntervalSec int // 모아둔 영상 이벤트를 db 에 쓰는 주기
+	MaxPendingKeys   int // 이만큼 쌓이면 주기를 기다리지 않고 쓴다. 쓰기 전까지 새 key 의 이벤트는 버린다.
+	// 사용자 한 명이 한 시간에 보낼 수 있는 이벤트 수. 피드 순위를 올리려는 반복 요청을 막는다.
+	MaxUserEventsPerHour int
+}
+
+type Notification struct {
+	PushChannel  string // fcm | fake | log
+	EmailChannel string // smtp | log
+	SmtpAddress  string // host:port
+	SmtpUser     string
+	SmtpPassword string
+	SmtpFrom     string
+	QueueSize    int // 발송 대기열 크기. 가득 차면 push, email 은 버리고 앱 내 알림만 남는다.
+}
+
+type Shipment struct {
+	Tracker             string // fake
+	RefreshIntervalSec  int    // 배송 조회 주기
+	FakeDeliverAfterSec int    // fake tracker 가 배송 완료로 바꾸기까지 걸리는 시간
+}
+
+type Payment struct {
+	Provider string // fake
+}
+
+type Inventory struct {
+	ReserveTtlSec    int // 결제를 기다리며 재고를 잡아두는 시간
+	SweepIntervalSec int // 만료된 예약을 푸는 주기
+}
+
+type Metrics struct {
+	// /metrics 를 여는 내부 주소. API port 와 분리한다. 비어 있으면 열지 않는다.
+	Address string
+}
+
 type Config struct {
-	Log       LogConfig `toml:"log"`
-	Database  Database  `toml:"database"`
-	Echo      Echo      `toml:"echo"`
-	Api       Api       `toml:"api"`
-	Asset     Asset     `toml:"asset"`
-	Firebase  Firebase  `toml:"firebase"`
-	LogConfig lumberjack.Logger
+	Log          LogConfig    `toml:"log"`
+	Database     Database     `toml:"database"`
+	Echo         Echo         `toml:"echo"`
+	Api          Api          `toml:"api"`
+	Asset        Asset        `toml:"asset"`
+	Firebase     Firebase     `toml:"firebase"`
+	Verify       Verify       `toml:"verify"`
+	Settlement   Settlement   `toml:"settlement"`
+	Engagement   Engagement   `toml:"engagement"`
+	Notification Notification `toml:"notification"`
+	Shipment     Shipment     `toml:"shipment"`
+	Payment      Payment      `toml:"payment"`
+	Inventory    Inventory    `toml:"inventory"`
+	Metrics      Metrics      `toml:"metrics"`
+	LogConfig    lumberjack.Logger
 }
 
 var conf *Config
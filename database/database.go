@@ -1,17 +1,33 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/4538cgy/backend-second/config"
 	"github.com/4538cgy/backend-second/log"
+	"github.com/4538cgy/backend-second/metrics"
 	_ "github.com/go-sql-driver/mysql"
+	"time"
 )
 
 const driverMySql = "mysql"
 const dbQueryPumpChannelBufferSize = 128
 
+const (
+	defaultConnectRetryCount      = 5
+	defaultConnectRetryIntervalMS = 500
+	maxConnectRetryInterval       = 10 * time.Second
+	connectPingTimeout            = 3 * time.Second
+)
+
+// metrics 의 query kind label
+const (
+	queryKindSelect = "select"
+	queryKindCud    = "cud"
+)
+
 type SelectQueryResult struct {
 	Err  error
 	Rows *sql.Rows
@@ -19,7 +35,9 @@ type SelectQueryResult struct {
 
 type selectTransaction struct {
 	selectQuery string
+	args        []interface{}
 	resultCh    chan<- SelectQueryResult
+	requestId   string
 }
 
 func NewSelectTransaction(query string, ch chan<- SelectQueryResult) selectTransaction {
@@ -29,6 +47,15 @@ func NewSelectTransaction(query string, ch chan<- SelectQueryResult) selectTrans
 	}
 }
 
+// placeholder(?) 가 있는 select query 용
+func NewSelectTransactionWithArgs(query string, args []interface{}, ch chan<- SelectQueryResult) selectTransaction {
+	return selectTransaction{
+		selectQuery: query,
+		args:        args,
+		resultCh:    ch,
+	}
+}
+
 type CudQueryResult struct {
 	Err    error
 	Result sql.Result
@@ -38,6 +65,7 @@ type cudTransaction struct {
 	insertQuery string
 	args        []interface{}
 	resultCh    chan<- CudQueryResult
+	requestId   string
 }
 
 func NewCudTransaction(query string, args []interface{}, ch chan<- CudQueryResult) cudTransaction {
@@ -48,6 +76,11 @@ func NewCudTransaction(query string, args []interface{}, ch chan<- CudQueryResul
 	}
 }
 
+var (
+	ErrQueryRequestTimeout  = errors.New("query request timeout")
+	ErrQueryResponseTimeout = errors.New("query response timeout")
+)
+
 type manager struct {
 	conf               *config.Config
 	db                 *sql.DB
@@ -57,11 +90,32 @@ type manager struct {
 
 type Manager interface {
 	Connect() error
+	Ping(timeout time.Duration) error
 	DSN() string
 	SelectQueryWritePump() chan<- selectTransaction
 	InsertQueryWritePump() chan<- cudTransaction
 }
 
+// 요청 id 를 가진 Manager. pump 가 query 로그에 요청 id 를 남길 수 있게 한다.
+type requestManager struct {
+	Manager
+	requestId string
+}
+
+func WithRequestId(m Manager, requestId string) Manager {
+	if requestId == "" {
+		return m
+	}
+	return &requestManager{Manager: m, requestId: requestId}
+}
+
+func requestIdOf(m Manager) string {
+	if rm, ok := m.(*requestManager); ok {
+		return rm.requestId
+	}
+	return ""
+}
+
 func NewDBManager(cfg *config.Config) (Manager, error) {
 	switch cfg.Database.Driver {
 	case driverMySql:
@@ -73,6 +127,8 @@ func NewDBManager(cfg *config.Config) (Manager, error) {
 		selectQueryWriteCh: make(chan selectTransaction, dbQueryPumpChannelBufferSize),
 		insertQueryWriteCh: make(chan cudTransaction, dbQueryPumpChannelBufferSize),
 	}
+	metrics.RegisterQueueDepth(queryKindSelect, func() int { return len(manager.selectQueryWriteCh) })
+	metrics.RegisterQueueDepth(queryKindCud, func() int { return len(manager.insertQueryWriteCh) })
 	return &manager, nil
 }
 
@@ -92,6 +148,12 @@ func (m *manager) Connect() error {
 	}
 	m.db = db
 
+	// sql.Open 은 연결하지 않으므로 ping 으로 실제로 붙는지 확인한다.
+	if err := m.pingWithRetry(); err != nil {
+		db.Close()
+		return err
+	}
+
 	for index := 0; index < m.conf.Database.MaxOpenConnection; index++ {
 		go m.readPump()
 		go m.writePump()
@@ -100,6 +162,43 @@ func (m *manager) Connect() error {
 	return nil
 }
 
+func (m *manager) pingWithRetry() error {
+	retry := m.conf.Database.ConnectRetryCount
+	if retry <= 0 {
+		retry = defaultConnectRetryCount
+	}
+	intervalMS := m.conf.Database.ConnectRetryIntervalMS
+	if intervalMS <= 0 {
+		intervalMS = defaultConnectRetryIntervalMS
+	}
+	interval := time.Duration(intervalMS) * time.Millisecond
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = m.Ping(connectPingTimeout); err == nil {
+			return nil
+		}
+		if attempt >= retry {
+			return errors.New(fmt.Sprintf("database ping failed after %d retries. err: %s", retry, err))
+		}
+		log.Warningf("database ping failed. retry in %s. err: %s", interval, err)
+		time.Sleep(interval)
+		interval *= 2
+		if interval > maxConnectRetryInterval {
+			interval = maxConnectRetryInterval
+		}
+	}
+}
+
+func (m *manager) Ping(timeout time.Duration) error {
+	if m.db == nil {
+		return errors.New("database not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return m.db.PingContext(ctx)
+}
+
 func (m *manager) SelectQueryWritePump() chan<- selectTransaction {
 	return m.selectQueryWriteCh
 }
@@ -117,15 +216,18 @@ func (m *manager) readPump() {
 				return
 			}
 
-			log.Debug("selectQuery: ", query)
-			res, err := m.db.Query(query.selectQuery)
+			start := time.Now()
+			res, err := m.db.Query(query.selectQuery, query.args...)
+			metrics.ObserveQuery(queryKindSelect, time.Since(start), err)
+			entry := log.WithRequestId(query.requestId).WithField(log.FieldDuration, time.Since(start).Milliseconds())
 			if err != nil {
+				entry.Warning("select query failed. query: ", query.selectQuery, ", err: ", err)
 				query.resultCh <- SelectQueryResult{
 					Err: err,
 				}
 				continue
 			}
-			log.Debug("Query success. res: ", res)
+			entry.Debug("select query: ", query.selectQuery)
 			query.resultCh <- SelectQueryResult{
 				Rows: res,
 			}
@@ -141,25 +243,82 @@ func (m *manager) writePump() {
 				log.Info("unexpected channel closed.")
 			}
 
-			log.Debug("insertQuery: ", query)
+			start := time.Now()
 			stmt, err := m.db.Prepare(query.insertQuery)
 			if err != nil {
+				metrics.ObserveQuery(queryKindCud, time.Since(start), err)
+				log.WithRequestId(query.requestId).WithField(log.FieldDuration, time.Since(start).Milliseconds()).
+					Warning("prepare query failed. query: ", query.insertQuery, ", err: ", err)
 				query.resultCh <- CudQueryResult{
 					Err: err,
 				}
 				continue
 			}
 			res, err := stmt.Exec(query.args...)
+			metrics.ObserveQuery(queryKindCud, time.Since(start), err)
+			entry := log.WithRequestId(query.requestId).WithField(log.FieldDuration, time.Since(start).Milliseconds())
 			if err != nil {
+				entry.Warning("cud query failed. query: ", query.insertQuery, ", err: ", err)
 				query.resultCh <- CudQueryResult{
 					Err: err,
 				}
 				continue
 			}
-			log.Debug("Query success. res: ", res)
+			entry.Debug("cud query: ", query.insertQuery)
 			query.resultCh <- CudQueryResult{
 				Result: res,
 			}
 		}
 	}
 }
+
+// select query 를 pump 로 보내고 결과를 기다린다. 성공한 경우 rows 는 호출한 쪽에서 닫아야 한다.
+func Select(m Manager, timer *time.Timer, query string, args ...interface{}) (*sql.Rows, error) {
+	// 타임아웃으로 먼저 빠져나가도 pump 가 막히지 않도록 buffer 를 둔다.
+	resultCh := make(chan SelectQueryResult, 1)
+	transaction := NewSelectTransactionWithArgs(query, args, resultCh)
+	transaction.requestId = requestIdOf(m)
+	select {
+	case m.SelectQueryWritePump() <- transaction:
+	case <-timer.C:
+		return nil, ErrQueryRequestTimeout
+	}
+
+	select {
+	case res := <-resultCh:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Rows, nil
+	case <-timer.C:
+		// 늦게 도착한 rows 를 닫지 않으면 connection 이 pool 로 돌아가지 않는다.
+		go func() {
+			if res := <-resultCh; res.Rows != nil {
+				res.Rows.Close()
+			}
+		}()
+		return nil, ErrQueryResponseTimeout
+	}
+}
+
+// insert, update, delete query 를 pump 로 보내고 결과를 기다린다.
+func Exec(m Manager, timer *time.Timer, query string, args ...interface{}) (sql.Result, error) {
+	resultCh := make(chan CudQueryResult, 1)
+	transaction := NewCudTransaction(query, args, resultCh)
+	transaction.requestId = requestIdOf(m)
+	select {
+	case m.InsertQueryWritePump() <- transaction:
+	case <-timer.C:
+		return nil, ErrQueryRequestTimeout
+	}
+
+	select {
+	case res := <-resultCh:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Result, nil
+	case <-timer.C:
+		return nil, ErrQueryResponseTimeout
+	}
+}
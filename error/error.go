@@ -6,14 +6,59 @@ const (
 )
 
 const (
-	MessageEmailBeingUsed     = "email address is being used"
-	MessageUserIdBeingUsed    = "already registered user id"
-	MessageOperationTimeout   = "operation timeout"
-	MessageUnknownError       = "unknown error message"
-	MessageQueryParamNotfound = "no query param"
-	MessageBindFailed         = "bind failed"
-	MessageUserNotRegistered  = "not registered user"
-	MessageIOFailed           = "I/O failed"
+	MessageEmailBeingUsed        = "email address is being used"
+	MessageUserIdBeingUsed       = "already registered user id"
+	MessageOperationTimeout      = "operation timeout"
+	MessageUnknownError          = "unknown error message"
+	MessageQueryParamNotfound    = "no query param"
+	MessageBindFailed            = "bind failed"
+	MessageUserNotRegistered     = "not registered user"
+	MessageIOFailed              = "I/O failed"
+	MessageInvalidSession        = "invalid session"
+	MessagePermissionDenied      = "permission denied"
+	MessageInvalidParameter      = "invalid parameter"
+	MessageReviewNotFound        = "review not found"
+	MessageReviewNotEligible     = "no delivered order for review"
+	MessageReviewWritten         = "review already written"
+	MessageReplyWritten          = "reply already written"
+	MessageChannelNotFound       = "channel not found"
+	MessageSellerNotApproved     = "seller not approved"
+	MessageSellerNotPending      = "seller registration is not pending"
+	MessageSellerRegistered      = "already registered seller"
+	MessageSellerNotFound        = "not registered seller"
+	MessageChannelNameBeingUsed  = "channel name is being used"
+	MessageStatementNotPayable   = "statement not found or already paid"
+	MessageInvalidOption         = "invalid product option"
+	MessageOptionNotFound        = "selected option not found"
+	MessageOutOfStock            = "out of stock"
+	MessageCartItemNotFound      = "cart item not found"
+	MessageProductNotFound       = "product not found"
+	MessageOrderNotFound         = "order not found"
+	MessageOrderNotCancellable   = "order can not be cancelled"
+	MessageOrderNotPayable       = "order can not be paid"
+	MessageOrderExpired          = "order payment time expired"
+	MessageCategoryNotFound      = "category not found"
+	MessageCategorySlugBeingUsed = "category slug is being used"
+	MessageCategoryHasChildren   = "category has children"
+	MessageInvalidCategoryParent = "invalid parent category"
+	MessageFeedCursorExpired     = "feed cursor expired"
+	MessageLiveNotFound          = "live broadcast not found"
+	MessageLiveNotOnAir          = "live broadcast is not on air"
+	MessageLiveInvalidStatus     = "invalid live broadcast status"
+	MessageAddressNotFound       = "address not found"
+	MessageShipmentNotAllowed    = "no order line to ship"
+	MessageUnknownCourier        = "unknown courier code"
+	MessageCouponNotFound        = "coupon not found"
+	MessageCouponNotApplicable   = "coupon is not applicable"
+	MessageCouponLimitExceeded   = "coupon issue limit exceeded"
+	MessagePaymentDeclined       = "payment declined"
+	MessagePaymentNotFound       = "payment not found"
+	MessageRefundFailed          = "refund failed"
+	MessageRefundPending         = "refund is pending"
+	MessageClaimNotFound         = "claim not found"
+	MessageClaimNotAllowed       = "claim not allowed for order line"
+	MessageClaimInvalidStatus    = "invalid claim status"
+	MessageServiceNotReady       = "service not ready"
 )
 
 // Response status detail code
@@ -30,13 +75,87 @@ const (
 	InvalidAuthType = 5
 	UserNotFound    = 6
 
-	SessionInsertionFailed = 100
+	InvalidParameter = 7
+
+	SessionInsertionFailed  = 100
+	SessionValidationFailed = 101
+
+	PermissionDenied = 200
 
 	ApiOperationRequestTimeout  = 300
 	ApiOperationResponseTimeout = 301
 
+	CategoryNotFound      = 400
+	CategorySlugBeingUsed = 401
+	CategoryHasChildren   = 402
+	InvalidCategoryParent = 403
+
+	ReviewNotFound       = 500
+	ReviewNotEligible    = 501
+	ReviewAlreadyWritten = 502
+	ReviewReplyWritten   = 503
+
+	ChannelNotFound = 600
+
+	SellerNotApproved = 700
+	SellerNotPending  = 701
+	// 사업자등록번호, 계좌 확인 실패. detail 에 사유가 담긴다.
+	SellerVerificationFailed = 702
+	SellerAlreadyRegistered  = 703
+	SellerNotFound           = 704
+	ChannelNameBeingUsed     = 705
+
+	StatementNotPayable = 800
+
+	InvalidOption    = 900
+	OptionNotFound   = 901
+	OutOfStock       = 902
+	CartItemNotFound = 903
+	ProductNotFound  = 904
+	OrderNotFound    = 905
+	// 배송이 시작된 line 이 있는 주문
+	OrderNotCancellable = 906
+	// 결제 대기 상태가 아닌 주문
+	OrderNotPayable = 907
+	// 결제 전에 재고 예약이 만료되어 취소된 주문
+	OrderExpired = 908
+
 	DatabaseOperationError = 1000
 
+	FeedCursorExpired = 1100
+
+	LiveNotFound = 1200
+	LiveNotOnAir = 1201
+	// 예정 -> 방송중 -> 종료 순서를 벗어난 요청
+	LiveInvalidStatus = 1202
+
+	AddressNotFound = 1300
+
+	// 판매자의 배송 전 주문 line 이 없다.
+	ShipmentNotAllowed = 1400
+	UnknownCourier     = 1401
+
+	// 발급받지 않았거나 이미 쓴 쿠폰
+	CouponNotFound = 1500
+	// 유효 기간, 최소 주문 금액, 적용 범위에 맞지 않는다.
+	CouponNotApplicable = 1501
+	CouponLimitExceeded = 1502
+
+	PaymentDeclined = 1600
+	PaymentNotFound = 1601
+	// PG 취소 실패. 주문 취소나 반품 승인은 반영되었을 수 있다.
+	RefundFailed = 1602
+	// 이전 환불의 PG 결과를 알 수 없다. 확인하기 전에는 다시 환불하지 않는다.
+	RefundPending = 1603
+
+	ClaimNotFound = 1700
+	// 배송 완료되지 않았거나 이미 반품, 교환을 요청한 주문 line
+	ClaimNotAllowed = 1701
+	// 요청 -> 승인(반품은 환불까지) 혹은 거절 순서를 벗어난 요청
+	ClaimInvalidStatus = 1702
+
+	ServiceNotReady = 1800
+
 	FirebaseTokenCreateFailed = 2000
 	FirebaseVerifyTokenFailed = 2001
 	FirebaseUserInfoFailed    = 2002
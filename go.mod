@@ -9,9 +9,11 @@ require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/labstack/echo/v4 v4.1.17
+	github.com/prometheus/client_golang v1.10.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/spf13/pflag v1.0.5
-	golang.org/x/oauth2 v0.0.0-20210323180902-22b0adad7558 // indirect
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
+	golang.org/x/oauth2 v0.0.0-20210323180902-22b0adad7558
 	google.golang.org/api v0.43.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 )
@@ -26,20 +26,48 @@ var Fatalf = logrus.Fatalf
 var Panic = logrus.Panic
 var Panicf = logrus.Panicf
 
+const (
+	formatJson = "json"
+
+	// 요청 하나에서 남긴 로그와 query 로그를 묶는 field
+	FieldRequestId = "request_id"
+	FieldDuration  = "duration_ms"
+)
+
+type Entry = logrus.Entry
+
+// 요청 id 가 붙는 logger. 요청을 처리하는 동안에는 CustomContext.Log 를 쓴다.
+func WithRequestId(requestId string) *Entry {
+	if requestId == "" {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+	return logrus.WithField(FieldRequestId, requestId)
+}
+
 func Init(cfg *config.Config) {
 	if !cfg.Log.Enable {
 		return
 	}
 
 	logrus.SetReportCaller(true)
-	logrus.SetFormatter(&logrus.TextFormatter{
-		TimestampFormat: time.RFC3339,
-		FullTimestamp:   true,
-		ForceColors:     true,
-		CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-			return "", fmt.Sprintf(" %s:%d", filepath.Base(frame.File), frame.Line)
-		},
-	})
+	if cfg.Log.Format == formatJson {
+		logrus.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339,
+			CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
+				return "", fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+			},
+		})
+	} else {
+		// 파일에 색상 escape code 가 남지 않도록 stdout 일 때만 색을 넣는다.
+		logrus.SetFormatter(&logrus.TextFormatter{
+			TimestampFormat: time.RFC3339,
+			FullTimestamp:   true,
+			ForceColors:     cfg.Log.StdOut,
+			CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
+				return "", fmt.Sprintf(" %s:%d", filepath.Base(frame.File), frame.Line)
+			},
+		})
+	}
 
 	switch cfg.Log.Level {
 	case "trace":
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	namespace = "vcom"
+
+	// 어떤 route 에도 맞지 않은 요청. url 을 그대로 label 로 쓰면 series 가 끝없이 늘어난다.
+	routeUnmatched = "unmatched"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP 요청 수",
+	}, []string{"method", "route", "code"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 요청 처리 시간",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "database pump 에서 query 를 실행한 시간",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind"})
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "실패한 query 수",
+	}, []string{"kind"})
+
+	timeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_timeouts_total",
+		Help:      "handler 가 타임아웃으로 응답한 수. code 는 vcomError code 이다.",
+	}, []string{"code"})
+
+	firebaseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "firebase_call_duration_seconds",
+		Help:      "firebase 호출 시간",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"call"})
+)
+
+const metricsUri = "/metrics"
+
+// address 에서 /metrics 만 연다. 서버가 멈출 때까지 돌아오지 않는다.
+func Serve(address string) error {
+	mux := http.NewServeMux()
+	mux.Handle(metricsUri, promhttp.Handler())
+	return http.ListenAndServe(address, mux)
+}
+
+// route 는 등록할 때 쓴 uri pattern 이다. 예) /api/order/:order_id/pay
+func ObserveRequest(method, route string, code int, elapsed time.Duration) {
+	if route == "" {
+		route = routeUnmatched
+	}
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// kind 는 select 또는 cud
+func ObserveQuery(kind string, elapsed time.Duration, err error) {
+	queryDuration.WithLabelValues(kind).Observe(elapsed.Seconds())
+	if err != nil {
+		queryErrors.WithLabelValues(kind).Inc()
+	}
+}
+
+func CountTimeout(code int) {
+	timeouts.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// defer metrics.ObserveFirebase("verify_id_token", time.Now()) 처럼 쓴다.
+func ObserveFirebase(call string, start time.Time) {
+	firebaseDuration.WithLabelValues(call).Observe(time.Since(start).Seconds())
+}
+
+// pump channel 에 쌓여 있는 query 수. scrape 할 때마다 depth 를 호출한다.
+func RegisterQueueDepth(queue string, depth func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "db_pump_queue_depth",
+		Help:        "database pump channel 에서 기다리는 query 수",
+		ConstLabels: prometheus.Labels{"queue": queue},
+	}, func() float64 {
+		return float64(depth())
+	})
+}
@@ -1,11 +1,17 @@
 package protocol
 
+import "github.com/4538cgy/backend-second/api/types"
+
 type Code int
 type BaseResponse struct {
 	Status Code   `json:"status"`
 	Detail string `json:"detail"`
 }
 
+func (r *BaseResponse) GetStatus() Code {
+	return r.Status
+}
+
 // email 사용중인지 여부 체크 응답
 type EmailCheckResponse struct {
 	BaseResponse
@@ -69,6 +75,32 @@ type SellerAuthResponse struct {
 	BaseResponse
 }
 
+// 채널 이름 사용 가능 여부 응답
+type ChannelNameCheckResponse struct {
+	BaseResponse
+	Available bool `json:"available"`
+}
+
+// 판매자 등록 상태 응답
+type SellerStatusResponse struct {
+	BaseResponse
+	SellerType         int    `json:"seller_type"` // 개인 0, 기업회원 1
+	CompanyName        string `json:"company_name"`
+	ChannelName        string `json:"channel_name"`
+	ChannelUrl         string `json:"channel_url"`
+	ChannelDescription string `json:"channel_description"`
+	Authentication     int    `json:"authentication"` // 대기 0, 승인 1, 반려 2
+	Reason             string `json:"reason"`         // 반려 사유
+	Updated            string `json:"updated"`
+}
+
+// 판매자 등록 서류 조회 응답
+type SellerDocumentResponse struct {
+	BaseResponse
+	Url       string `json:"url"`        // 서명된 서류 url
+	ExpiresAt int64  `json:"expires_at"` // url 만료 시각. unix time
+}
+
 type ProductPostResponse struct {
 	BaseResponse
 }
@@ -77,11 +109,13 @@ type CartItemAddRequest struct {
 	UniqueId     string // `unique_id`: firebase token id 혹은 email address
 	Token        string // `token`: login 혹은 user 등록 요청의 응답으로 받은 토큰
 	ProductId    string // `product_id`: 카트에 담을 제품의 product id
-	SelectedJson string // `selected_json`: 구매할 옵션 리스트
+	SelectedJson string // `selected_json`: 구매할 옵션. {"옵션 그룹 이름": "값"}
+	Quantity     int    // `quantity`: 구매 수량. 없으면 1
 }
 
 type CartItemAddResponse struct {
 	BaseResponse
+	CartId string `json:"cart_id"`
 }
 
 type CartItemRemoveRequest struct {
@@ -96,4 +130,588 @@ type CartItemRemoveResponse struct {
 
 type ReviewPostResponse struct {
 	BaseResponse
+	ReviewId string `json:"review_id"`
+}
+
+type ReviewUpdateResponse struct {
+	BaseResponse
+}
+
+type ReviewReplyResponse struct {
+	BaseResponse
+}
+
+type ReviewInfo struct {
+	ReviewId  string            `json:"review_id"`
+	ProductId string            `json:"product_id"`
+	UniqueId  string            `json:"unique_id"` // 작성자
+	Body      string            `json:"body"`
+	Star      int               `json:"star"`        // 별점 1~5
+	Medias    []types.MediaInfo `json:"media_infos"` // media_info_json 을 확장한 미디어 목록
+	ThumbUp   int               `json:"thumb_up"`
+	ThumbDown int               `json:"thumb_down"`
+	Created   string            `json:"created"`
+	Reply     ReviewReply       `json:"reply"` // 판매자 답글. 없으면 body 가 비어있다.
+}
+
+type ReviewReply struct {
+	Body    string `json:"body"`
+	Created string `json:"created"`
+}
+
+// 리뷰 목록 응답
+type ReviewListResponse struct {
+	BaseResponse
+	Reviews []ReviewInfo `json:"reviews"`
+	Page    int          `json:"page"`     // 요청한 page. 0 부터 시작
+	HasNext bool         `json:"has_next"` // 다음 page 존재 여부
+}
+
+// 상품 별점 요약 응답
+type ReviewSummaryResponse struct {
+	BaseResponse
+	ProductId   string  `json:"product_id"`
+	Average     float64 `json:"average"`       // 평균 별점
+	TotalCount  int     `json:"total_count"`   // 전체 리뷰 수
+	CountByStar [5]int  `json:"count_by_star"` // index 0 이 1점, 4 가 5점
+}
+
+// 리뷰 추천/비추천 응답
+type ReviewThumbResponse struct {
+	BaseResponse
+	ReviewId  string `json:"review_id"`
+	ThumbUp   int    `json:"thumb_up"`
+	ThumbDown int    `json:"thumb_down"`
+	Vote      int    `json:"vote"` // 요청한 사용자의 투표. 1 추천, -1 비추천, 0 없음
+}
+
+type ProductInfo struct {
+	ProductId   string            `json:"product_id"`
+	SellerId    string            `json:"seller_id"` // 판매자 unique_id
+	ChannelName string            `json:"channel_name"`
+	Title       string            `json:"title"`
+	BasePrice   int               `json:"base_price"`
+	BaseAmount  int               `json:"base_amount"` // 재고
+	Videos      []types.MediaInfo `json:"videos"`      // video_list_json 을 확장한 미디어 목록
+	Created     string            `json:"created"`
+}
+
+type ChannelInfo struct {
+	SellerId      string `json:"seller_id"`
+	ChannelName   string `json:"channel_name"`
+	ChannelUrl    string `json:"channel_url"`
+	Description   string `json:"channel_description"`
+	FollowerCount int    `json:"follower_count"`
+	Following     bool   `json:"following"` // session_token 을 보낸 경우 팔로우 여부
+}
+
+// 채널 페이지 응답
+type ChannelResponse struct {
+	BaseResponse
+	Channel  ChannelInfo   `json:"channel"`
+	Products []ProductInfo `json:"products"`
+	Page     int           `json:"page"`
+	HasNext  bool          `json:"has_next"`
+}
+
+// 채널 팔로우, 언팔로우 응답
+type ChannelFollowResponse struct {
+	BaseResponse
+	FollowerCount int `json:"follower_count"`
+}
+
+// 상품 목록 응답
+type ProductListResponse struct {
+	BaseResponse
+	Products []ProductInfo `json:"products"`
+	Page     int           `json:"page"`
+	HasNext  bool          `json:"has_next"`
+}
+
+// 옵션 조합별 가격과 재고
+type SkuInfo struct {
+	SkuId    string            `json:"sku_id"`
+	Selected map[string]string `json:"selected"` // 옵션 그룹 이름: 값
+	Price    int               `json:"price"`    // base_price + price_delta
+	Stock    int               `json:"stock"`
+}
+
+// 상품 상세 응답
+type ProductDetailResponse struct {
+	BaseResponse
+	Product   ProductInfo         `json:"product"`
+	Options   []types.OptionGroup `json:"options"`
+	Skus      []SkuInfo           `json:"skus"`
+	LikeCount int                 `json:"like_count"` // 찜한 사용자 수
+}
+
+// 카테고리 tree 의 노드. children 은 sort_order 순이다.
+type CategoryNode struct {
+	CategoryId string         `json:"category_id"`
+	ParentId   string         `json:"parent_id"`
+	Name       string         `json:"name"`
+	Slug       string         `json:"slug"`
+	SortOrder  int            `json:"sort_order"`
+	Children   []CategoryNode `json:"children"`
+}
+
+// 카테고리 tree 응답
+type CategoryTreeResponse struct {
+	BaseResponse
+	Categories []CategoryNode `json:"categories"`
+}
+
+// 카테고리 상품 목록 응답. 하위 카테고리의 상품을 포함한다.
+type CategoryProductsResponse struct {
+	BaseResponse
+	Products []ProductInfo `json:"products"`
+	Page     int           `json:"page"`
+	HasNext  bool          `json:"has_next"`
+}
+
+// 카테고리 생성, 수정, 삭제 응답
+type CategoryResponse struct {
+	BaseResponse
+	CategoryId string `json:"category_id"`
+}
+
+// 상품 검색 응답
+type SearchResponse struct {
+	BaseResponse
+	Products []ProductInfo `json:"products"`
+	Page     int           `json:"page"`
+	HasNext  bool          `json:"has_next"`
+}
+
+// 검색어 자동 완성 응답
+type SearchSuggestResponse struct {
+	BaseResponse
+	Suggestions []string `json:"suggestions"`
+}
+
+// 상품 수정, 삭제 응답
+type ProductUpdateResponse struct {
+	BaseResponse
+}
+
+// 홈 피드 응답. next_cursor 가 비어 있으면 마지막 page 이다.
+type FeedResponse struct {
+	BaseResponse
+	Products   []ProductInfo `json:"products"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// 영상 이벤트 묶음
+type VideoEventRequest struct {
+	Events []types.VideoEvent `json:"events"`
+}
+
+type VideoEventResponse struct {
+	BaseResponse
+	Accepted int `json:"accepted"` // 기록된 이벤트 수
+}
+
+// 영상 통계. bucket 은 시간 혹은 날짜, 영상별 통계에서는 video_id 이다.
+type StatInfo struct {
+	Bucket         string  `json:"bucket"`
+	Impressions    int64   `json:"impressions"`
+	Plays          int64   `json:"plays"`
+	Watch25        int64   `json:"watch_25"`
+	Watch50        int64   `json:"watch_50"`
+	Watch75        int64   `json:"watch_75"`
+	Watch100       int64   `json:"watch_100"`
+	Taps           int64   `json:"taps"`
+	PlayRate       float64 `json:"play_rate"`       // plays / impressions
+	TapRate        float64 `json:"tap_rate"`        // taps / plays
+	CompletionRate float64 `json:"completion_rate"` // watch_100 / plays
+}
+
+// 상품 통계 응답. window 는 24h | 7d | 30d
+type ProductStatResponse struct {
+	BaseResponse
+	Window string     `json:"window"`
+	Total  StatInfo   `json:"total"`
+	Series []StatInfo `json:"series"`
+}
+
+// 상품의 영상별 통계 응답
+type VideoStatResponse struct {
+	BaseResponse
+	Window string     `json:"window"`
+	Videos []StatInfo `json:"videos"`
+}
+
+// 주문 생성 응답
+type CheckoutResponse struct {
+	BaseResponse
+	OrderId    string `json:"order_id"`
+	TotalPrice int    `json:"total_price"` // 쿠폰 할인을 뺀 결제 금액
+	Discount   int    `json:"discount"`    // 쿠폰 할인 합
+}
+
+type OrderCancelResponse struct {
+	BaseResponse
+	OrderId          string   `json:"order_id"`
+	CancelledLineIds []string `json:"cancelled_line_ids"` // 이번 요청으로 취소된 line. 일부만 취소될 수 있다.
+}
+
+type OrderPayResponse struct {
+	BaseResponse
+	OrderId string `json:"order_id"`
+	Amount  int    `json:"amount"` // 결제 금액
+}
+
+// 판매자 등록 신청 정보
+type SellerRegistrationInfo struct {
+	UniqueId                  string `json:"unique_id"`
+	SellerType                int    `json:"seller_type"` // 개인 0, 기업회원 1
+	CompanyRegistrationNumber string `json:"company_registration_number"`
+	OwnerName                 string `json:"owner_name"`
+	CompanyName               string `json:"company_name"`
+	ChannelName               string `json:"channel_name"`
+	BankName                  string `json:"bank_name"`
+	BankAccountNumber         string `json:"bank_account_number"`
+	Authentication            int    `json:"authentication"` // 대기 0, 승인 1, 반려 2
+	Created                   string `json:"created"`
+}
+
+// 승인 대기 판매자 목록 응답
+type SellerRegistrationListResponse struct {
+	BaseResponse
+	Registrations []SellerRegistrationInfo `json:"registrations"`
+	Page          int                      `json:"page"`
+	HasNext       bool                     `json:"has_next"`
+}
+
+// 판매자 승인, 반려 응답
+type SellerDecisionResponse struct {
+	BaseResponse
+}
+
+type SellerAuditInfo struct {
+	AuditId        string `json:"audit_id"`
+	UniqueId       string `json:"unique_id"`      // 판매자
+	AdminId        string `json:"admin_id"`       // 처리한 관리자
+	Authentication int    `json:"authentication"` // 승인 1, 반려 2
+	Reason         string `json:"reason"`
+	Created        string `json:"created"`
+}
+
+// 판매자 승인 이력 응답
+type SellerAuditResponse struct {
+	BaseResponse
+	Audits []SellerAuditInfo `json:"audits"`
+}
+
+// 판매자 정산 잔액 응답. 금액은 원 단위
+type SettlementBalanceResponse struct {
+	BaseResponse
+	Balance      int64 `json:"balance"`       // 지급 예정 금액
+	SaleAmount   int64 `json:"sale_amount"`   // 누적 판매 금액
+	FeeAmount    int64 `json:"fee_amount"`    // 누적 수수료. 음수
+	AdjustAmount int64 `json:"adjust_amount"` // 누적 정정 금액
+	PaidAmount   int64 `json:"paid_amount"`   // 누적 지급 금액. 음수
+}
+
+type LedgerEntryInfo struct {
+	EntryId     string `json:"entry_id"`
+	ReferenceId string `json:"reference_id"` // order_line_id 혹은 statement_id
+	Kind        string `json:"kind"`         // sale | fee | adjust | payout
+	Amount      int64  `json:"amount"`
+	Created     string `json:"created"`
+}
+
+// 정산 ledger 내역 응답
+type SettlementLedgerResponse struct {
+	BaseResponse
+	Entries []LedgerEntryInfo `json:"entries"`
+	Page    int               `json:"page"`
+	HasNext bool              `json:"has_next"`
+}
+
+type StatementInfo struct {
+	StatementId  string `json:"statement_id"`
+	PeriodStart  string `json:"period_start"`
+	PeriodEnd    string `json:"period_end"`
+	SaleAmount   int64  `json:"sale_amount"`
+	FeeAmount    int64  `json:"fee_amount"`
+	AdjustAmount int64  `json:"adjust_amount"`
+	NetAmount    int64  `json:"net_amount"`
+	Status       int    `json:"status"` // 지급 대기 0, 지급 완료 1, 다음 정산서로 넘김 2
+	PaidAt       string `json:"paid_at"`
+}
+
+// 정산서 목록 응답
+type SettlementStatementResponse struct {
+	BaseResponse
+	Statements []StatementInfo `json:"statements"`
+	Page       int             `json:"page"`
+	HasNext    bool            `json:"has_next"`
+}
+
+// 정산 지급 처리 응답
+type SettlementPayoutResponse struct {
+	BaseResponse
+}
+
+type LiveBroadcastInfo struct {
+	BroadcastId     string `json:"broadcast_id"`
+	SellerId        string `json:"seller_id"`
+	ChannelName     string `json:"channel_name"`
+	Title           string `json:"title"`
+	ScheduledAt     string `json:"scheduled_at"`
+	Status          int    `json:"status"` // 예정 0, 방송중 1, 종료 2
+	PlaybackUrl     string `json:"playback_url"`
+	VodUrl          string `json:"vod_url"`
+	StartedAt       string `json:"started_at"`
+	EndedAt         string `json:"ended_at"`
+	PinnedProductId string `json:"pinned_product_id"`
+}
+
+type LiveProductInfo struct {
+	ProductId        string `json:"product_id"`
+	Title            string `json:"title"`
+	BasePrice        int    `json:"base_price"`
+	LivePrice        int    `json:"live_price"` // 0 이면 라이브 가격 없음
+	LivePriceMinutes int    `json:"live_price_minutes"`
+	LivePriceActive  bool   `json:"live_price_active"` // 지금 주문하면 라이브 가격이 적용된다.
+	Pinned           bool   `json:"pinned"`
+}
+
+// 방송 정보와 방송에 올린 상품
+type LiveResponse struct {
+	BaseResponse
+	Broadcast LiveBroadcastInfo `json:"broadcast"`
+	Products  []LiveProductInfo `json:"products"`
+}
+
+type LiveListResponse struct {
+	BaseResponse
+	Broadcasts []LiveBroadcastInfo `json:"broadcasts"`
+	Page       int                 `json:"page"`
+	HasNext    bool                `json:"has_next"`
+}
+
+type LiveCreateResponse struct {
+	BaseResponse
+	BroadcastId string `json:"broadcast_id"`
+}
+
+type LiveUpdateResponse struct {
+	BaseResponse
+}
+
+// 방송 채팅 websocket 으로 주고받는 메시지. 클라이언트는 type, message 만 보낸다.
+type LiveChatMessage struct {
+	Type      string `json:"type"` // chat | pin | end | viewer
+	UniqueId  string `json:"unique_id,omitempty"`
+	Message   string `json:"message,omitempty"`
+	ProductId string `json:"product_id,omitempty"`
+	Viewers   int    `json:"viewers,omitempty"`
+	Created   string `json:"created,omitempty"`
+}
+
+// 찜한 상품 목록. 가격과 재고는 지금 값이다.
+type WishlistResponse struct {
+	BaseResponse
+	Products []ProductInfo `json:"products"`
+	Page     int           `json:"page"`
+	HasNext  bool          `json:"has_next"`
+}
+
+type WishlistUpdateResponse struct {
+	BaseResponse
+	LikeCount int `json:"like_count"`
+}
+
+type WishlistAlertInfo struct {
+	AlertId   string `json:"alert_id"`
+	ProductId string `json:"product_id"`
+	Title     string `json:"title"`
+	Kind      string `json:"kind"`  // price_drop | restock
+	Price     int    `json:"price"` // 알림을 만들 때의 가격
+	Created   string `json:"created"`
+}
+
+// 찜한 상품의 가격 인하, 재입고 알림 목록
+type WishlistAlertResponse struct {
+	BaseResponse
+	Alerts  []WishlistAlertInfo `json:"alerts"`
+	Page    int                 `json:"page"`
+	HasNext bool                `json:"has_next"`
+}
+
+type NotificationInfo struct {
+	NotificationId string `json:"notification_id"`
+	Category       string `json:"category"` // seller | order | review | wishlist
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	ReferenceId    string `json:"reference_id"`
+	Read           bool   `json:"read"`
+	Created        string `json:"created"`
+}
+
+// 앱 내 알림 목록
+type NotificationResponse struct {
+	BaseResponse
+	Notifications []NotificationInfo `json:"notifications"`
+	UnreadCount   int                `json:"unread_count"`
+	Page          int                `json:"page"`
+	HasNext       bool               `json:"has_next"`
+}
+
+// 읽음 처리 응답
+type NotificationReadResponse struct {
+	BaseResponse
+	UnreadCount int `json:"unread_count"`
+}
+
+type NotificationSettingInfo struct {
+	Category string `json:"category"`
+	InApp    bool   `json:"in_app"`
+	Push     bool   `json:"push"`
+	Email    bool   `json:"email"`
+}
+
+// 분류별 알림 수신 설정
+type NotificationSettingResponse struct {
+	BaseResponse
+	Settings []NotificationSettingInfo `json:"settings"`
+}
+
+type DeviceTokenResponse struct {
+	BaseResponse
+}
+
+type AddressInfo struct {
+	AddressId     string `json:"address_id"`
+	Recipient     string `json:"recipient"`
+	Phone         string `json:"phone"`
+	Zipcode       string `json:"zipcode"`        // 5자리 우편번호
+	RoadAddress   string `json:"road_address"`   // 도로명 주소
+	DetailAddress string `json:"detail_address"` // 상세 주소
+	Default       bool   `json:"default"`
+}
+
+// 배송지 목록. 기본 배송지가 먼저 온다.
+type AddressListResponse struct {
+	BaseResponse
+	Addresses []AddressInfo `json:"addresses"`
+}
+
+type AddressResponse struct {
+	BaseResponse
+	AddressId string `json:"address_id"`
+}
+
+type ShipmentInfo struct {
+	ShipmentId    string `json:"shipment_id"`
+	OrderId       string `json:"order_id"`
+	SellerId      string `json:"seller_id"`
+	CourierCode   string `json:"courier_code"`
+	CourierName   string `json:"courier_name"`
+	InvoiceNumber string `json:"invoice_number"`
+	Status        string `json:"status"`   // registered | in_transit | out_for_delivery | delivered
+	Location      string `json:"location"` // 마지막 조회 위치
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+}
+
+// 주문의 송장 목록. 구매자와 판매자가 볼 수 있다.
+type ShipmentListResponse struct {
+	BaseResponse
+	Shipments []ShipmentInfo `json:"shipments"`
+}
+
+type ShipmentCreateResponse struct {
+	BaseResponse
+	ShipmentId string `json:"shipment_id"`
+}
+
+type CouponInfo struct {
+	CouponId       string   `json:"coupon_id"`
+	IssuerId       string   `json:"issuer_id"` // 판매자 쿠폰이면 판매자 unique_id. 플랫폼 쿠폰은 빈 문자열
+	Name           string   `json:"name"`
+	DiscountType   string   `json:"discount_type"` // fixed | percent
+	DiscountValue  int      `json:"discount_value"`
+	MinOrderAmount int      `json:"min_order_amount"`
+	MaxDiscount    int      `json:"max_discount"` // 0 이면 제한 없음
+	ValidFrom      string   `json:"valid_from"`
+	ValidUntil     string   `json:"valid_until"`
+	PerUserLimit   int      `json:"per_user_limit"`
+	Scope          string   `json:"scope"` // all | product | seller | category
+	ScopeIds       []string `json:"scope_ids"`
+}
+
+type CouponResponse struct {
+	BaseResponse
+	Coupon CouponInfo `json:"coupon"`
+}
+
+type CouponIssueResponse struct {
+	BaseResponse
+	UserCouponId string `json:"user_coupon_id"`
+}
+
+type UserCouponInfo struct {
+	UserCouponId string     `json:"user_coupon_id"`
+	Used         bool       `json:"used"`
+	OrderId      string     `json:"order_id"` // 사용한 주문
+	Coupon       CouponInfo `json:"coupon"`
+}
+
+// 발급받은 쿠폰 목록. 유효 기간이 먼저 끝나는 것부터 온다.
+type UserCouponListResponse struct {
+	BaseResponse
+	Coupons []UserCouponInfo `json:"coupons"`
+	Page    int              `json:"page"`
+	HasNext bool             `json:"has_next"`
+}
+
+// 반품, 교환 요청
+type ClaimInfo struct {
+	ClaimId      string            `json:"claim_id"`
+	OrderLineId  string            `json:"order_line_id"`
+	OrderId      string            `json:"order_id"`
+	UniqueId     string            `json:"unique_id"` // 구매자
+	SellerId     string            `json:"seller_id"`
+	Kind         string            `json:"kind"` // return | exchange
+	Reason       string            `json:"reason"`
+	Medias       []types.MediaInfo `json:"media_infos"`
+	Status       int               `json:"status"`        // 요청 0, 승인 1, 거절 2, 환불 완료 3
+	RejectReason string            `json:"reject_reason"` // 거절 사유
+	Created      string            `json:"created"`
+	Updated      string            `json:"updated"`
+}
+
+// 반품, 교환 요청의 상태 변경 기록
+type ClaimHistoryInfo struct {
+	Status  int    `json:"status"`
+	ActorId string `json:"actor_id"` // 상태를 바꾼 구매자 혹은 판매자
+	Note    string `json:"note"`
+	Created string `json:"created"`
+}
+
+type ClaimResponse struct {
+	BaseResponse
+	Claim   ClaimInfo          `json:"claim"`
+	History []ClaimHistoryInfo `json:"history"` // 오래된 것부터
+}
+
+type ClaimListResponse struct {
+	BaseResponse
+	Claims  []ClaimInfo `json:"claims"`
+	Page    int         `json:"page"`
+	HasNext bool        `json:"has_next"`
+}
+
+type ClaimCreateResponse struct {
+	BaseResponse
+	ClaimId string `json:"claim_id"`
+}
+
+// readiness 응답. 점검 항목별로 ok 또는 실패 이유를 담는다.
+type HealthResponse struct {
+	BaseResponse
+	Checks map[string]string `json:"checks"`
 }
@@ -1,6 +1,8 @@
 package query
 
 const InsertSession = "INSERT INTO vcommerce.session(`token`, `unique_id`, `created`, `updated`) VALUES (?, ?, now(), now())"
+const SelectSessionUniqueId = "SELECT unique_id FROM vcommerce.session WHERE token=? LIMIT 1"
+const SelectAdmin = "SELECT unique_id FROM vcommerce.admin WHERE unique_id=? LIMIT 1"
 
 const InsertEmail = "INSERT INTO vcommerce.emails(`email`, `created`) VALUES (?, now())"
 const InsertUserID = "INSERT INTO vcommerce.userids(`user_id`, `created`) VALUES (?, now())"
@@ -8,13 +10,276 @@ const InsertUser = "INSERT INTO vcommerce.user(`unique_id`, `user_id`, `day_of_b
 
 const InsertSellerAuth = "INSERT INTO vcommerce.seller(`unique_id`, `seller_type`, `company_registration_number`, `owner_name`, `company_name`, `channel_name`, `channel_url`, `channel_description`, `bank_name`, `bank_account_number`, `uploaded_file_path`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, now(), now())"
 const InsertSellerChannel = "INSERT INTO vcommerce.seller_channel(`channel_name`, `created`) VALUES (?, now())"
+const SelectSellerChannel = "SELECT channel_name FROM vcommerce.seller_channel WHERE channel_name=? LIMIT 1"
 const InsertSellerRegistration = "INSERT INTO vcommerce.seller_registration(`unique_id`, `authentication`, `created`, `updated`) VALUES (?, ?, now(), now())"
+const SelectSellerAuthentication = "SELECT authentication FROM vcommerce.seller_registration WHERE unique_id=? LIMIT 1"
+const SelectSellerStatus = "SELECT s.seller_type, s.company_name, s.channel_name, s.channel_url, s.channel_description, r.authentication, IFNULL(r.reason, ''), r.updated FROM vcommerce.seller_registration r JOIN vcommerce.seller s ON s.unique_id=r.unique_id WHERE r.unique_id=? LIMIT 1"
+const SelectSellerRegistrations = "SELECT s.unique_id, s.seller_type, s.company_registration_number, s.owner_name, s.company_name, s.channel_name, s.bank_name, s.bank_account_number, r.authentication, r.created FROM vcommerce.seller_registration r JOIN vcommerce.seller s ON s.unique_id=r.unique_id WHERE r.authentication=? ORDER BY r.created LIMIT ? OFFSET ?"
+const UpdateSellerAuthentication = "UPDATE vcommerce.seller_registration SET `authentication`=?, `reason`=?, `updated`=now() WHERE unique_id=? AND authentication=?"
+const InsertSellerAudit = "INSERT INTO vcommerce.seller_registration_audit(`audit_id`, `unique_id`, `admin_id`, `authentication`, `reason`, `created`) VALUES (?, ?, ?, ?, ?, now())"
+const SelectSellerAudits = "SELECT audit_id, unique_id, admin_id, authentication, reason, created FROM vcommerce.seller_registration_audit WHERE unique_id=? ORDER BY created DESC"
 
 const InsertVideoList = "INSERT INTO vcommerce.video_info(`video_id`, `video_url`, `serve_ready`, `created`, `updated`) VALUES (?, ?, 0, now(), now())"
-const InsertProductCategoryInfo = "INSERT INTO vcommerce.product_category(`product_id`, `category_json`, `created`, `json`) VALUES (?, ?, now(), now())"
+
+// %s: video_id 개수만큼의 placeholder
+const SelectVideoInfos = "SELECT video_id, video_url, serve_ready FROM vcommerce.video_info WHERE video_id IN (%s)"
 const InsertProductSale = "INSERT INTO vcommerce.product(`product_id`, `unique_id`, `video_list_json`, `title`, `base_price`, `base_amount`, `option_json`, `deleted`, `created`) VALUES (?, ?, ?, ?, ?, ?, ?, 0, now())"
+const SelectProductOwner = "SELECT unique_id FROM vcommerce.product WHERE product_id=? AND deleted=0 LIMIT 1"
+const UpdateProduct = "UPDATE vcommerce.product SET `title`=?, `base_price`=? WHERE product_id=? AND unique_id=? AND deleted=0"
+const DeleteProduct = "UPDATE vcommerce.product SET `deleted`=1 WHERE product_id=? AND unique_id=? AND deleted=0"
+const DeleteProductCategories = "DELETE FROM vcommerce.product_category WHERE product_id=?"
+const SelectProductOption = "SELECT option_json FROM vcommerce.product WHERE product_id=? AND deleted=0 LIMIT 1"
+
+// product_sku 는 (product_id, option_key) unique key. 재고는 sku 단위이고 product.base_amount 는 sku 재고의 합이다.
+const InsertProductSku = "INSERT INTO vcommerce.product_sku(`sku_id`, `product_id`, `option_key`, `price_delta`, `stock`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, now(), now())"
+const SelectProductSku = "SELECT s.sku_id, s.product_id, s.option_key, s.price_delta, s.stock FROM vcommerce.product_sku s JOIN vcommerce.product p ON p.product_id=s.product_id WHERE s.sku_id=? AND p.deleted=0 LIMIT 1"
+const SelectProductSkuByOption = "SELECT s.sku_id, s.product_id, s.option_key, s.price_delta, s.stock FROM vcommerce.product_sku s JOIN vcommerce.product p ON p.product_id=s.product_id WHERE s.product_id=? AND s.option_key=? AND p.deleted=0 LIMIT 1"
+const SelectProductSkus = "SELECT sku_id, product_id, option_key, price_delta, stock FROM vcommerce.product_sku WHERE product_id=? ORDER BY created, sku_id"
+const DecreaseProductSkuStock = "UPDATE vcommerce.product_sku s JOIN vcommerce.product p ON p.product_id=s.product_id SET s.stock=s.stock-?, p.base_amount=p.base_amount-?, s.updated=now() WHERE s.sku_id=? AND s.stock>=?"
+const IncreaseProductSkuStock = "UPDATE vcommerce.product_sku s JOIN vcommerce.product p ON p.product_id=s.product_id SET s.stock=s.stock+?, p.base_amount=p.base_amount+?, s.updated=now() WHERE s.sku_id=?"
+
+// category 는 (slug) unique key. 최상위 노드의 parent_id 는 빈 문자열이다.
+const SelectCategories = "SELECT category_id, parent_id, name, slug, sort_order FROM vcommerce.category"
+const InsertCategory = "INSERT INTO vcommerce.category(`category_id`, `parent_id`, `name`, `slug`, `sort_order`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, now(), now())"
+const UpdateCategory = "UPDATE vcommerce.category SET `parent_id`=?, `name`=?, `slug`=?, `sort_order`=?, `updated`=now() WHERE category_id=?"
+const DeleteCategory = "DELETE FROM vcommerce.category WHERE category_id=?"
+const DeleteCategoryProducts = "DELETE FROM vcommerce.product_category WHERE category_id=?"
+const InsertProductCategory = "INSERT IGNORE INTO vcommerce.product_category(`product_id`, `category_id`, `created`) VALUES (?, ?, now())"
+const SelectProductCategories = "SELECT category_id FROM vcommerce.product_category WHERE product_id=?"
+
+// product_search 는 product_id primary key. category_names 는 연결된 카테고리 이름을 공백으로 이은 것이다.
+const productSearchSelect = "SELECT p.product_id, p.unique_id, p.title, IFNULL(s.channel_name, ''), (SELECT IFNULL(GROUP_CONCAT(c.name SEPARATOR ' '), '') FROM vcommerce.product_category pc JOIN vcommerce.category c ON c.category_id=pc.category_id WHERE pc.product_id=p.product_id), now() FROM vcommerce.product p LEFT JOIN vcommerce.seller s ON s.unique_id=p.unique_id"
+const InsertProductSearch = "INSERT INTO vcommerce.product_search(`product_id`, `seller_id`, `title`, `channel_name`, `category_names`, `updated`) " + productSearchSelect + " WHERE p.product_id=? AND p.deleted=0"
+const ReplaceCategoryProductSearch = "REPLACE INTO vcommerce.product_search(`product_id`, `seller_id`, `title`, `channel_name`, `category_names`, `updated`) " + productSearchSelect + " WHERE p.product_id IN (SELECT product_id FROM vcommerce.product_category WHERE category_id=?) AND p.deleted=0"
+const DeleteProductSearch = "DELETE FROM vcommerce.product_search WHERE product_id=?"
+
+// 첫번째 %s: 추가 where 조건, 두번째 %s: order by 절
+const SelectSearchProducts = "SELECT p.product_id, p.unique_id, ps.channel_name, p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.product_search ps JOIN vcommerce.product p ON p.product_id=ps.product_id WHERE p.deleted=0 %s ORDER BY %s LIMIT ? OFFSET ?"
+const MatchProductSearch = "MATCH(ps.title, ps.channel_name, ps.category_names) AGAINST (? IN BOOLEAN MODE)"
+
+// %s: category_id 개수만큼의 placeholder
+const FilterProductCategories = "p.product_id IN (SELECT product_id FROM vcommerce.product_category WHERE category_id IN (%s))"
+
+// 상품 title, 채널 이름, 카테고리 이름 중 prefix 로 시작하는 것. 짧은 것부터 보여준다.
+const SelectSearchSuggestions = "SELECT word FROM (SELECT title AS word FROM vcommerce.product_search WHERE title LIKE ? UNION SELECT channel_name FROM vcommerce.product_search WHERE channel_name LIKE ? UNION SELECT name FROM vcommerce.category WHERE name LIKE ?) t ORDER BY CHAR_LENGTH(word), word LIMIT ?"
+
+// 주문 line 조회는 api/order.scanLine 의 column 순서를 따른다.
+const orderLineSelect = "SELECT order_line_id, order_id, unique_id, product_id, sku_id, seller_id, quantity, unit_price, discount, seller_discount, status FROM vcommerce.order_line"
+const SelectOrderLine = orderLineSelect + " WHERE order_line_id=? AND unique_id=? LIMIT 1"
+const SelectSellerOrderLine = orderLineSelect + " WHERE order_line_id=? AND seller_id=? LIMIT 1"
+const InsertOrder = "INSERT INTO vcommerce.orders(`order_id`, `unique_id`, `total_price`, `status`, `recipient`, `phone`, `zipcode`, `road_address`, `detail_address`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, now(), now())"
+
+// discount 는 line 에 나눠진 쿠폰 할인 합, seller_discount 는 그 중 판매자 쿠폰 몫이다.
+const InsertOrderLine = "INSERT INTO vcommerce.order_line(`order_line_id`, `order_id`, `unique_id`, `product_id`, `sku_id`, `seller_id`, `quantity`, `unit_price`, `discount`, `seller_discount`, `status`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, now(), now())"
+const SelectOrderLines = orderLineSelect + " WHERE order_id=? AND unique_id=? ORDER BY order_line_id"
+
+// 배송이 시작되지 않은 line 만 취소된다.
+const UpdateOrderLineCancelled = "UPDATE vcommerce.order_line SET `status`=?, `updated`=now() WHERE order_line_id=? AND shipment_id='' AND status IN (?, ?)"
+
+// 상품 조회는 api/product.Scan 의 column 순서를 따른다.
+const SelectProduct = "SELECT p.product_id, p.unique_id, IFNULL(s.channel_name, ''), p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.product p LEFT JOIN vcommerce.seller s ON s.unique_id=p.unique_id WHERE p.product_id=? AND p.deleted=0 LIMIT 1"
+const SelectProductsBySeller = "SELECT p.product_id, p.unique_id, IFNULL(s.channel_name, ''), p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.product p LEFT JOIN vcommerce.seller s ON s.unique_id=p.unique_id WHERE p.unique_id=? AND p.deleted=0 ORDER BY p.created DESC LIMIT ? OFFSET ?"
+
+// %s: category_id 개수만큼의 placeholder
+const SelectProductsByCategories = "SELECT DISTINCT p.product_id, p.unique_id, IFNULL(s.channel_name, ''), p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.product_category pc JOIN vcommerce.product p ON p.product_id=pc.product_id LEFT JOIN vcommerce.seller s ON s.unique_id=p.unique_id WHERE pc.category_id IN (%s) AND p.deleted=0 ORDER BY p.created DESC, p.product_id LIMIT ? OFFSET ?"
+const SelectFollowingProducts = "SELECT p.product_id, p.unique_id, s.channel_name, p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.channel_follow f JOIN vcommerce.seller s ON s.channel_name=f.channel_name JOIN vcommerce.product p ON p.unique_id=s.unique_id WHERE f.unique_id=? AND p.deleted=0 ORDER BY p.created DESC, p.product_id LIMIT ? OFFSET ?"
+
+// %s: product_id 개수만큼의 placeholder
+const SelectProductsByIds = "SELECT p.product_id, p.unique_id, IFNULL(s.channel_name, ''), p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.product p LEFT JOIN vcommerce.seller s ON s.unique_id=p.unique_id WHERE p.product_id IN (%s) AND p.deleted=0"
 
-const InsertCart = "INSERT INTO vcommerce.cart(`cart_id`, `unique_id`, `product_id`, `selected_json`, `created`) VALUES (?, ?, ?, ?, now())"
+// 피드 점수 재료. column 순서: product_id, unique_id, video_list_json, 기준 시각까지 등록 후 지난 분, 평균 별점, 리뷰 수, 반응(도움돼요 + 최근 7일 재생, 상품 이동), 채널 팔로워 수
+const SelectFeedCandidates = "SELECT p.product_id, p.unique_id, p.video_list_json, TIMESTAMPDIFF(MINUTE, p.created, ?), IFNULL(AVG(r.star), 0), COUNT(r.review_id), IFNULL(SUM(r.thumb_up), 0) + (SELECT IFNULL(SUM(vs.plays + vs.taps * 5), 0) FROM vcommerce.video_stat_hourly vs WHERE vs.product_id=p.product_id AND vs.hour >= now() - INTERVAL 7 DAY), (SELECT COUNT(*) FROM vcommerce.channel_follow f JOIN vcommerce.seller s ON s.channel_name=f.channel_name WHERE s.unique_id=p.unique_id) FROM vcommerce.product p LEFT JOIN vcommerce.review r ON r.product_id=p.product_id WHERE p.deleted=0 AND p.created <= ? GROUP BY p.product_id ORDER BY p.created DESC LIMIT ?"
+const SelectFollowingSellers = "SELECT s.unique_id FROM vcommerce.channel_follow f JOIN vcommerce.seller s ON s.channel_name=f.channel_name WHERE f.unique_id=?"
+
+// video_stat_hourly 는 (video_id, product_id, hour) unique key. 상품에 속한 영상의 값만 더해진다.
+// 영상 이벤트의 영상이 상품에 속하는지 확인한다.
+const SelectProductVideos = "SELECT product_id, video_list_json FROM vcommerce.product WHERE product_id IN (%s) AND deleted=0"
+const UpsertVideoStat = "INSERT INTO vcommerce.video_stat_hourly(`video_id`, `product_id`, `seller_id`, `hour`, `impressions`, `plays`, `watch_25`, `watch_50`, `watch_75`, `watch_100`, `taps`) SELECT ?, p.product_id, p.unique_id, ?, ?, ?, ?, ?, ?, ?, ? FROM vcommerce.product p WHERE p.product_id=? AND JSON_CONTAINS(p.video_list_json, JSON_QUOTE(?), '$.media_indices') ON DUPLICATE KEY UPDATE `impressions`=`impressions`+VALUES(`impressions`), `plays`=`plays`+VALUES(`plays`), `watch_25`=`watch_25`+VALUES(`watch_25`), `watch_50`=`watch_50`+VALUES(`watch_50`), `watch_75`=`watch_75`+VALUES(`watch_75`), `watch_100`=`watch_100`+VALUES(`watch_100`), `taps`=`taps`+VALUES(`taps`)"
+const SelectProductStatSeries = "SELECT DATE_FORMAT(hour, ?) AS bucket, SUM(impressions), SUM(plays), SUM(watch_25), SUM(watch_50), SUM(watch_75), SUM(watch_100), SUM(taps) FROM vcommerce.video_stat_hourly WHERE product_id=? AND seller_id=? AND hour >= ? GROUP BY bucket ORDER BY bucket"
+const SelectProductVideoStats = "SELECT video_id, SUM(impressions), SUM(plays), SUM(watch_25), SUM(watch_50), SUM(watch_75), SUM(watch_100), SUM(taps) FROM vcommerce.video_stat_hourly WHERE product_id=? AND seller_id=? AND hour >= ? GROUP BY video_id ORDER BY SUM(plays) DESC, video_id"
+
+const SelectChannel = "SELECT s.unique_id, s.channel_name, s.channel_url, s.channel_description, (SELECT COUNT(*) FROM vcommerce.channel_follow f WHERE f.channel_name=s.channel_name) FROM vcommerce.seller s WHERE s.channel_name=? LIMIT 1"
+const SelectChannelFollow = "SELECT channel_name FROM vcommerce.channel_follow WHERE unique_id=? AND channel_name=? LIMIT 1"
+const InsertChannelFollow = "INSERT IGNORE INTO vcommerce.channel_follow(`unique_id`, `channel_name`, `created`) VALUES (?, ?, now())"
+const DeleteChannelFollow = "DELETE FROM vcommerce.channel_follow WHERE unique_id=? AND channel_name=?"
+const SelectChannelFollowerCount = "SELECT COUNT(*) FROM vcommerce.channel_follow WHERE channel_name=?"
+
+const InsertCart = "INSERT INTO vcommerce.cart(`cart_id`, `unique_id`, `product_id`, `sku_id`, `quantity`, `selected_json`, `created`) VALUES (?, ?, ?, ?, ?, ?, now())"
 const DeleteCart = "DELETE FROM vcommerce.cart WHERE cart_id=? AND unique_id=?"
 
-const InsertReview = "INSERT INTO vcommerce.review(`review_id`, `product_id`, `unique_id`, `thumb_up_down_id`, `body`, `media_info_json`, `star`, `created`, `updated`) VALUES(?, ?, ?, ?, ?, ?, ?, now(), now())"
+// %s: cart_id 개수만큼의 placeholder
+const SelectCartItems = "SELECT c.cart_id, c.product_id, c.sku_id, c.quantity, p.unique_id, p.base_price FROM vcommerce.cart c JOIN vcommerce.product p ON p.product_id=c.product_id WHERE c.unique_id=? AND p.deleted=0 AND c.cart_id IN (%s)"
+const DeleteCartItems = "DELETE FROM vcommerce.cart WHERE unique_id=? AND cart_id IN (%s)"
+
+const InsertReview = "INSERT INTO vcommerce.review(`review_id`, `product_id`, `unique_id`, `order_line_id`, `thumb_up_down_id`, `body`, `media_info_json`, `star`, `created`, `updated`) VALUES(?, ?, ?, ?, ?, ?, ?, ?, now(), now())"
+const SelectReviewByOrderLine = "SELECT review_id FROM vcommerce.review WHERE order_line_id=? LIMIT 1"
+const SelectReviewOwner = "SELECT r.unique_id, p.unique_id FROM vcommerce.review r JOIN vcommerce.product p ON p.product_id=r.product_id WHERE r.review_id=? LIMIT 1"
+
+// NULL 인 값은 바꾸지 않는다.
+const UpdateReview = "UPDATE vcommerce.review SET `body`=IFNULL(?, `body`), `star`=IFNULL(?, `star`), `updated`=now() WHERE review_id=? AND unique_id=?"
+const DeleteReview = "DELETE FROM vcommerce.review WHERE review_id=? AND unique_id=?"
+const InsertReviewReply = "INSERT INTO vcommerce.review_reply(`review_id`, `unique_id`, `body`, `created`, `updated`) VALUES (?, ?, ?, now(), now())"
+const SelectReviewReply = "SELECT review_id FROM vcommerce.review_reply WHERE review_id=? LIMIT 1"
+const DeleteReviewReply = "DELETE FROM vcommerce.review_reply WHERE review_id=?"
+
+// 첫번째 %s: 추가 where 조건, 두번째 %s: order by 절
+const SelectReviewsByProduct = "SELECT r.review_id, r.product_id, r.unique_id, r.body, r.media_info_json, r.star, r.thumb_up, r.thumb_down, r.created, IFNULL(rp.body, ''), IFNULL(rp.created, '') FROM vcommerce.review r LEFT JOIN vcommerce.review_reply rp ON rp.review_id=r.review_id WHERE r.product_id=? %s ORDER BY %s LIMIT ? OFFSET ?"
+const SelectReviewsByUser = "SELECT r.review_id, r.product_id, r.unique_id, r.body, r.media_info_json, r.star, r.thumb_up, r.thumb_down, r.created, IFNULL(rp.body, ''), IFNULL(rp.created, '') FROM vcommerce.review r LEFT JOIN vcommerce.review_reply rp ON rp.review_id=r.review_id WHERE r.unique_id=? %s ORDER BY %s LIMIT ? OFFSET ?"
+const SelectReviewStarCount = "SELECT star, COUNT(*) FROM vcommerce.review WHERE product_id=? GROUP BY star"
+
+const SelectReviewThumbId = "SELECT thumb_up_down_id FROM vcommerce.review WHERE review_id=? LIMIT 1"
+const UpsertReviewThumb = "INSERT INTO vcommerce.review_thumb(`thumb_up_down_id`, `unique_id`, `vote`, `created`, `updated`) VALUES (?, ?, ?, now(), now()) ON DUPLICATE KEY UPDATE `vote`=VALUES(`vote`), `updated`=now()"
+const DeleteReviewThumb = "DELETE FROM vcommerce.review_thumb WHERE thumb_up_down_id=? AND unique_id=?"
+const DeleteReviewThumbs = "DELETE FROM vcommerce.review_thumb WHERE thumb_up_down_id=(SELECT thumb_up_down_id FROM vcommerce.review WHERE review_id=?)"
+const UpdateReviewThumbCount = "UPDATE vcommerce.review SET `thumb_up`=(SELECT COUNT(*) FROM vcommerce.review_thumb WHERE thumb_up_down_id=? AND vote=1), `thumb_down`=(SELECT COUNT(*) FROM vcommerce.review_thumb WHERE thumb_up_down_id=? AND vote=-1) WHERE thumb_up_down_id=?"
+const SelectReviewThumbCount = "SELECT thumb_up, thumb_down FROM vcommerce.review WHERE thumb_up_down_id=? LIMIT 1"
+
+// settlement_ledger 는 append-only. (reference_id, kind) unique key 로 같은 항목이 두 번 쌓이지 않는다.
+const SelectUnsettledOrderLines = "SELECT ol.order_line_id, ol.seller_id, ol.quantity * ol.unit_price - ol.seller_discount FROM vcommerce.order_line ol WHERE ol.status=? AND NOT EXISTS (SELECT 1 FROM vcommerce.settlement_ledger l WHERE l.reference_id=ol.order_line_id AND l.kind=?) LIMIT ?"
+const InsertLedgerEntry = "INSERT IGNORE INTO vcommerce.settlement_ledger(`entry_id`, `seller_id`, `reference_id`, `kind`, `amount`, `created`) VALUES (?, ?, ?, ?, ?, now())"
+const SelectLedgerBalance = "SELECT kind, IFNULL(SUM(amount), 0) FROM vcommerce.settlement_ledger WHERE seller_id=? GROUP BY kind"
+const SelectLedgerEntries = "SELECT entry_id, reference_id, kind, amount, created FROM vcommerce.settlement_ledger WHERE seller_id=? ORDER BY created DESC, entry_id LIMIT ? OFFSET ?"
+
+// 기간 내 ledger 를 판매자별로 모아 정산서를 만든다. payout, carry_out 은 정산서 합계에서 제외한다.
+const InsertSettlementStatements = "INSERT IGNORE INTO vcommerce.settlement_statement(`statement_id`, `seller_id`, `period_start`, `period_end`, `sale_amount`, `fee_amount`, `adjust_amount`, `net_amount`, `status`, `created`) SELECT CONCAT(seller_id, '_', ?), seller_id, ?, ?, SUM(IF(kind=?, amount, 0)), SUM(IF(kind=?, amount, 0)), SUM(IF(kind=?, amount, 0)), SUM(amount), 0, now() FROM vcommerce.settlement_ledger WHERE created >= ? AND created < ? AND kind NOT IN (?, ?) GROUP BY seller_id"
+
+// 지급할 금액이 없는 정산서. 차감액은 다음 정산서로 넘긴다.
+const SelectUnpayableStatements = "SELECT statement_id, seller_id, net_amount FROM vcommerce.settlement_statement WHERE status=? AND net_amount<=0"
+
+// 마지막 정산서 이후 정산서에 들어가지 않은 가장 오래된 ledger 항목. 없으면 빈 문자열이다.
+const SelectUnclosedLedgerStart = "SELECT IFNULL(MIN(created), '') FROM vcommerce.settlement_ledger WHERE created >= IFNULL((SELECT MAX(period_end) FROM vcommerce.settlement_statement), '1970-01-01 00:00:00') AND kind NOT IN (?, ?)"
+const UpdateSettlementStatementStatus = "UPDATE vcommerce.settlement_statement SET `status`=? WHERE statement_id=? AND status=?"
+const SelectSettlementStatements = "SELECT statement_id, period_start, period_end, sale_amount, fee_amount, adjust_amount, net_amount, status, IFNULL(paid_at, '') FROM vcommerce.settlement_statement WHERE seller_id=? ORDER BY period_start DESC LIMIT ? OFFSET ?"
+const SelectSettlementStatement = "SELECT seller_id, net_amount FROM vcommerce.settlement_statement WHERE statement_id=? LIMIT 1"
+const UpdateSettlementStatementPaid = "UPDATE vcommerce.settlement_statement SET `status`=1, `paid_at`=now() WHERE statement_id=? AND status=0 AND net_amount>0"
+
+// live_broadcast.status: 예정 0, 방송중 1, 종료 2
+const liveBroadcastSelect = "SELECT b.broadcast_id, b.seller_id, IFNULL(s.channel_name, ''), b.title, b.scheduled_at, b.status, b.playback_url, b.vod_url, IFNULL(b.started_at, ''), IFNULL(b.ended_at, ''), b.pinned_product_id FROM vcommerce.live_broadcast b LEFT JOIN vcommerce.seller s ON s.unique_id=b.seller_id"
+const InsertLiveBroadcast = "INSERT INTO vcommerce.live_broadcast(`broadcast_id`, `seller_id`, `title`, `scheduled_at`, `status`, `playback_url`, `vod_url`, `pinned_product_id`, `created`) VALUES (?, ?, ?, ?, ?, '', '', '', now())"
+const SelectLiveBroadcast = liveBroadcastSelect + " WHERE b.broadcast_id=? LIMIT 1"
+
+// %s: order by 절
+const SelectLiveBroadcasts = liveBroadcastSelect + " WHERE b.status=? ORDER BY %s LIMIT ? OFFSET ?"
+const UpdateLiveStart = "UPDATE vcommerce.live_broadcast SET `status`=?, `playback_url`=?, `started_at`=now() WHERE broadcast_id=? AND status=?"
+const UpdateLiveEnd = "UPDATE vcommerce.live_broadcast SET `status`=?, `vod_url`=?, `pinned_product_id`='', `ended_at`=now() WHERE broadcast_id=? AND status=?"
+const UpdateLiveVod = "UPDATE vcommerce.live_broadcast SET `vod_url`=? WHERE broadcast_id=? AND status=?"
+const UpdateLivePin = "UPDATE vcommerce.live_broadcast SET `pinned_product_id`=? WHERE broadcast_id=?"
+
+// 방송에 올린 상품만 고정할 수 있다.
+const UpdateLivePinProduct = "UPDATE vcommerce.live_broadcast SET `pinned_product_id`=? WHERE broadcast_id=? AND EXISTS (SELECT 1 FROM vcommerce.live_product lp WHERE lp.broadcast_id=live_broadcast.broadcast_id AND lp.product_id=?)"
+
+// 판매자 본인의 상품만 추가된다.
+const UpsertLiveProduct = "INSERT INTO vcommerce.live_product(`broadcast_id`, `product_id`, `live_price`, `live_price_minutes`, `created`) SELECT ?, p.product_id, ?, ?, now() FROM vcommerce.product p WHERE p.product_id=? AND p.unique_id=? AND p.deleted=0 ON DUPLICATE KEY UPDATE `live_price`=VALUES(`live_price`), `live_price_minutes`=VALUES(`live_price_minutes`)"
+const DeleteLiveProduct = "DELETE FROM vcommerce.live_product WHERE broadcast_id=? AND product_id=?"
+const SelectLiveProducts = "SELECT p.product_id, p.title, p.base_price, lp.live_price, lp.live_price_minutes, (b.status=1 AND lp.live_price > 0 AND (lp.live_price_minutes=0 OR b.started_at + INTERVAL lp.live_price_minutes MINUTE > now())) FROM vcommerce.live_product lp JOIN vcommerce.live_broadcast b ON b.broadcast_id=lp.broadcast_id JOIN vcommerce.product p ON p.product_id=lp.product_id WHERE lp.broadcast_id=? AND p.deleted=0 ORDER BY lp.created, p.product_id"
+const SelectActiveLivePrice = "SELECT MIN(lp.live_price) FROM vcommerce.live_product lp JOIN vcommerce.live_broadcast b ON b.broadcast_id=lp.broadcast_id WHERE lp.product_id=? AND b.status=? AND lp.live_price > 0 AND (lp.live_price_minutes=0 OR b.started_at + INTERVAL lp.live_price_minutes MINUTE > now())"
+
+// wishlist.notified_price, notified_in_stock 은 마지막으로 알려준(혹은 찜할 때의) 가격과 재고 여부이다.
+const InsertWishlist = "INSERT IGNORE INTO vcommerce.wishlist(`unique_id`, `product_id`, `notified_price`, `notified_in_stock`, `created`) SELECT ?, p.product_id, p.base_price, p.base_amount > 0, now() FROM vcommerce.product p WHERE p.product_id=? AND p.deleted=0"
+const DeleteWishlist = "DELETE FROM vcommerce.wishlist WHERE unique_id=? AND product_id=?"
+const SelectWishlistCount = "SELECT COUNT(*) FROM vcommerce.wishlist WHERE product_id=?"
+const SelectWishlistProducts = "SELECT p.product_id, p.unique_id, IFNULL(s.channel_name, ''), p.title, p.base_price, p.base_amount, p.video_list_json, p.created FROM vcommerce.wishlist w JOIN vcommerce.product p ON p.product_id=w.product_id LEFT JOIN vcommerce.seller s ON s.unique_id=p.unique_id WHERE w.unique_id=? AND p.deleted=0 ORDER BY w.created DESC, p.product_id LIMIT ? OFFSET ?"
+const SelectWishlistWatchers = "SELECT w.unique_id, p.title, w.notified_price, w.notified_in_stock, p.base_price, p.base_amount FROM vcommerce.wishlist w JOIN vcommerce.product p ON p.product_id=w.product_id WHERE w.product_id=? AND p.deleted=0 AND (p.base_price <> w.notified_price OR w.notified_in_stock <> (p.base_amount > 0))"
+const UpdateWishlistNotified = "UPDATE vcommerce.wishlist SET `notified_price`=?, `notified_in_stock`=? WHERE unique_id=? AND product_id=?"
+const InsertWishlistAlert = "INSERT INTO vcommerce.wishlist_alert(`alert_id`, `unique_id`, `product_id`, `kind`, `price`, `created`) VALUES (?, ?, ?, ?, ?, now())"
+const SelectWishlistAlerts = "SELECT a.alert_id, a.product_id, p.title, a.kind, a.price, a.created FROM vcommerce.wishlist_alert a JOIN vcommerce.product p ON p.product_id=a.product_id WHERE a.unique_id=? ORDER BY a.created DESC, a.alert_id LIMIT ? OFFSET ?"
+
+const SelectUserEmail = "SELECT email FROM vcommerce.user WHERE unique_id=? LIMIT 1"
+const InsertNotification = "INSERT INTO vcommerce.notification(`notification_id`, `unique_id`, `category`, `title`, `body`, `reference_id`, `is_read`, `created`) VALUES (?, ?, ?, ?, ?, ?, 0, now())"
+
+// %s: 추가 where 조건
+const SelectNotifications = "SELECT notification_id, category, title, body, reference_id, is_read, created FROM vcommerce.notification WHERE unique_id=? %s ORDER BY created DESC, notification_id LIMIT ? OFFSET ?"
+const SelectUnreadNotificationCount = "SELECT COUNT(*) FROM vcommerce.notification WHERE unique_id=? AND is_read=0"
+
+// %s: notification_id 개수만큼의 placeholder
+const UpdateNotificationsRead = "UPDATE vcommerce.notification SET `is_read`=1, `read_at`=now() WHERE unique_id=? AND is_read=0 AND notification_id IN (%s)"
+const UpdateAllNotificationsRead = "UPDATE vcommerce.notification SET `is_read`=1, `read_at`=now() WHERE unique_id=? AND is_read=0"
+const SelectNotificationSettings = "SELECT category, in_app, push, email FROM vcommerce.notification_setting WHERE unique_id=?"
+const UpsertNotificationSetting = "INSERT INTO vcommerce.notification_setting(`unique_id`, `category`, `in_app`, `push`, `email`, `updated`) VALUES (?, ?, ?, ?, ?, now()) ON DUPLICATE KEY UPDATE `in_app`=VALUES(`in_app`), `push`=VALUES(`push`), `email`=VALUES(`email`), `updated`=now()"
+
+// token 은 기기마다 하나. 다른 사용자가 같은 기기로 로그인하면 그 사용자에게 옮겨간다.
+const UpsertDeviceToken = "INSERT INTO vcommerce.device_token(`token`, `unique_id`, `platform`, `created`, `updated`) VALUES (?, ?, ?, now(), now()) ON DUPLICATE KEY UPDATE `unique_id`=VALUES(`unique_id`), `platform`=VALUES(`platform`), `updated`=now()"
+const DeleteDeviceToken = "DELETE FROM vcommerce.device_token WHERE token=? AND unique_id=?"
+const SelectDeviceTokens = "SELECT token FROM vcommerce.device_token WHERE unique_id=?"
+
+// %s: token 개수만큼의 placeholder
+const DeleteDeviceTokens = "DELETE FROM vcommerce.device_token WHERE token IN (%s)"
+
+const addressSelect = "SELECT address_id, recipient, phone, zipcode, road_address, detail_address, is_default FROM vcommerce.address"
+const SelectAddresses = addressSelect + " WHERE unique_id=? ORDER BY is_default DESC, created DESC"
+const SelectAddress = addressSelect + " WHERE address_id=? AND unique_id=? LIMIT 1"
+const SelectDefaultAddress = addressSelect + " WHERE unique_id=? ORDER BY is_default DESC, created DESC LIMIT 1"
+const InsertAddress = "INSERT INTO vcommerce.address(`address_id`, `unique_id`, `recipient`, `phone`, `zipcode`, `road_address`, `detail_address`, `is_default`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, ?, ?, 0, now(), now())"
+const UpdateAddress = "UPDATE vcommerce.address SET `recipient`=?, `phone`=?, `zipcode`=?, `road_address`=?, `detail_address`=?, `updated`=now() WHERE address_id=? AND unique_id=?"
+const DeleteAddress = "DELETE FROM vcommerce.address WHERE address_id=? AND unique_id=?"
+
+// 사용자의 기본 배송지는 하나이다.
+const UpdateDefaultAddress = "UPDATE vcommerce.address SET `is_default`=(address_id=?) WHERE unique_id=?"
+
+// 판매자가 주문의 자기 상품 line 들을 한 송장으로 보낸다. %s: 배송 보낼 수 있는 status 의 placeholder
+const UpdateOrderLinesShipping = "UPDATE vcommerce.order_line SET `status`=?, `shipment_id`=?, `updated`=now() WHERE order_id=? AND seller_id=? AND shipment_id='' AND status IN (%s)"
+const InsertShipment = "INSERT INTO vcommerce.shipment(`shipment_id`, `order_id`, `seller_id`, `courier_code`, `invoice_number`, `status`, `created`, `updated`) VALUES (?, ?, ?, ?, ?, ?, now(), now())"
+const SelectShipmentsByOrder = "SELECT s.shipment_id, s.order_id, s.seller_id, s.courier_code, s.invoice_number, s.status, s.location, s.created, s.updated FROM vcommerce.shipment s JOIN vcommerce.orders o ON o.order_id=s.order_id WHERE s.order_id=? AND (o.unique_id=? OR s.seller_id=?) ORDER BY s.created"
+const SelectShipmentsInTransit = "SELECT shipment_id, order_id, seller_id, courier_code, invoice_number, status, created FROM vcommerce.shipment WHERE status<>? ORDER BY updated LIMIT ?"
+const UpdateShipmentStatus = "UPDATE vcommerce.shipment SET `status`=?, `location`=?, `updated`=now() WHERE shipment_id=?"
+const UpdateShipmentLinesStatus = "UPDATE vcommerce.order_line SET `status`=?, `updated`=now() WHERE shipment_id=? AND status=?"
+const SelectOrderBuyer = "SELECT unique_id FROM vcommerce.orders WHERE order_id=? LIMIT 1"
+
+// 주문 status 는 취소되지 않은 line 중 가장 앞선 단계를 따른다. 모두 취소되면 취소이다.
+const UpdateOrderStatusFromLines = "UPDATE vcommerce.orders SET `status`=IFNULL((SELECT MIN(ol.status) FROM vcommerce.order_line ol WHERE ol.order_id=? AND ol.status<>?), ?), `updated`=now() WHERE order_id=?"
+
+// 쿠폰 조회는 api/promotion.scanCoupon 의 column 순서를 따른다.
+const couponSelect = "SELECT c.coupon_id, c.issuer_id, c.name, c.discount_type, c.discount_value, c.min_order_amount, c.max_discount, c.valid_from, c.valid_until, c.per_user_limit, c.scope, c.scope_ids_json FROM vcommerce.coupon c"
+const InsertCoupon = "INSERT INTO vcommerce.coupon(`coupon_id`, `issuer_id`, `name`, `discount_type`, `discount_value`, `min_order_amount`, `max_discount`, `valid_from`, `valid_until`, `per_user_limit`, `scope`, `scope_ids_json`, `created`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, now())"
+const SelectCoupon = couponSelect + " WHERE c.coupon_id=? LIMIT 1"
+
+// 사용자가 받은 수가 per_user_limit 보다 적고 유효 기간이 끝나지 않은 경우에만 발급된다.
+const IssueCoupon = "INSERT INTO vcommerce.user_coupon(`user_coupon_id`, `coupon_id`, `unique_id`, `status`, `order_id`, `created`, `updated`) SELECT ?, c.coupon_id, ?, ?, '', now(), now() FROM vcommerce.coupon c WHERE c.coupon_id=? AND c.valid_until > now() AND (SELECT COUNT(*) FROM vcommerce.user_coupon u WHERE u.coupon_id=c.coupon_id AND u.unique_id=?) < c.per_user_limit"
+
+// 발급받은 쿠폰. column 순서: user_coupon_id, status, order_id 다음에 couponSelect 의 column 들
+const userCouponSelect = "SELECT u.user_coupon_id, u.status, u.order_id, c.coupon_id, c.issuer_id, c.name, c.discount_type, c.discount_value, c.min_order_amount, c.max_discount, c.valid_from, c.valid_until, c.per_user_limit, c.scope, c.scope_ids_json FROM vcommerce.user_coupon u JOIN vcommerce.coupon c ON c.coupon_id=u.coupon_id"
+const SelectUserCoupons = userCouponSelect + " WHERE u.unique_id=? AND u.status=? ORDER BY c.valid_until, u.created LIMIT ? OFFSET ?"
+const SelectUserCoupon = userCouponSelect + " WHERE u.user_coupon_id=? AND u.unique_id=? LIMIT 1"
+const UpdateUserCouponUsed = "UPDATE vcommerce.user_coupon SET `status`=?, `order_id`=?, `updated`=now() WHERE user_coupon_id=? AND unique_id=? AND status=?"
+
+// 주문이 취소되면 그 주문에 쓴 쿠폰을 다시 쓸 수 있게 한다.
+const UpdateUserCouponsReleased = "UPDATE vcommerce.user_coupon SET `status`=?, `order_id`='', `updated`=now() WHERE order_id=? AND status=?"
+
+// 주문 하나에 결제 하나. order_id 가 primary key 이다.
+const InsertPayment = "INSERT INTO vcommerce.payment(`order_id`, `payment_key`, `amount`, `refunded`, `created`, `updated`) VALUES (?, ?, ?, 0, now(), now())"
+const SelectPayment = "SELECT order_id, payment_key, amount, refunded FROM vcommerce.payment WHERE order_id=? LIMIT 1"
+
+// 환불 합이 결제 금액을 넘지 않을 때만 바뀐다. 음수는 PG 취소 실패를 되돌릴 때 쓴다.
+const UpdatePaymentRefunded = "UPDATE vcommerce.payment SET `refunded`=`refunded`+?, `updated`=now() WHERE order_id=? AND `refunded`+? BETWEEN 0 AND `amount`"
+
+// payment_refund 는 reference_id unique key. 같은 주문 취소나 반품으로 두 번 환불되지 않는다.
+// PG 를 부르기 전에 pending 으로 넣고, PG 취소가 끝나면 refund_key 와 함께 done 으로 바꾼다.
+const InsertPaymentRefund = "INSERT INTO vcommerce.payment_refund(`refund_id`, `order_id`, `reference_id`, `refund_key`, `amount`, `reason`, `status`, `created`) VALUES (?, ?, ?, '', ?, ?, ?, now())"
+const SelectPaymentRefund = "SELECT refund_id, status FROM vcommerce.payment_refund WHERE reference_id=? LIMIT 1"
+const UpdatePaymentRefundDone = "UPDATE vcommerce.payment_refund SET `refund_key`=?, `status`=? WHERE refund_id=?"
+const DeletePaymentRefund = "DELETE FROM vcommerce.payment_refund WHERE refund_id=? AND status=?"
+const UpdateOrderLinesStatus = "UPDATE vcommerce.order_line SET `status`=?, `updated`=now() WHERE order_id=? AND status=?"
+
+// 반품, 교환 요청 조회는 api/claim.scanClaim 의 column 순서를 따른다.
+const claimSelect = "SELECT claim_id, order_line_id, order_id, unique_id, seller_id, kind, reason, media_info_json, status, reject_reason, created, updated FROM vcommerce.claim"
+
+// 같은 주문 line 에 거절되지 않은 요청이 없을 때만 넣는다.
+const InsertClaim = "INSERT INTO vcommerce.claim(`claim_id`, `order_line_id`, `order_id`, `unique_id`, `seller_id`, `kind`, `reason`, `media_info_json`, `status`, `reject_reason`, `created`, `updated`) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, '', now(), now() FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM vcommerce.claim WHERE order_line_id=? AND status<>?)"
+
+// 구매자와 판매자만 볼 수 있다.
+const SelectClaim = claimSelect + " WHERE claim_id=? AND (unique_id=? OR seller_id=?) LIMIT 1"
+const SelectClaimsByBuyer = claimSelect + " WHERE unique_id=? ORDER BY created DESC LIMIT ? OFFSET ?"
+const SelectClaimsBySeller = claimSelect + " WHERE seller_id=? ORDER BY status=0 DESC, created DESC LIMIT ? OFFSET ?"
+
+// 주문 line 당 거절되지 않은 요청은 하나이다.
+const SelectOpenClaimByOrderLine = "SELECT claim_id FROM vcommerce.claim WHERE order_line_id=? AND status<>? LIMIT 1"
+const UpdateClaimStatus = "UPDATE vcommerce.claim SET `status`=?, `reject_reason`=?, `updated`=now() WHERE claim_id=? AND status=?"
+const InsertClaimHistory = "INSERT INTO vcommerce.claim_history(`history_id`, `claim_id`, `status`, `actor_id`, `note`, `created`) VALUES (?, ?, ?, ?, ?, now())"
+const SelectClaimHistory = "SELECT status, actor_id, note, created FROM vcommerce.claim_history WHERE claim_id=? ORDER BY created, history_id"
+const UpdateOrderLineReturned = "UPDATE vcommerce.order_line SET `status`=?, `updated`=now() WHERE order_line_id=? AND status=?"
+
+// 주문별 재고 예약. order_id 가 primary key 이고 items_json 은 [{"SkuId", "Quantity"}] 이다.
+const InsertInventoryHold = "INSERT INTO vcommerce.inventory_hold(`order_id`, `items_json`, `status`, `expires_at`, `created`, `updated`) VALUES (?, ?, ?, ?, now(), now())"
+const SelectInventoryHold = "SELECT order_id, items_json, status, expires_at FROM vcommerce.inventory_hold WHERE order_id=? LIMIT 1"
+const UpdateInventoryHoldStatus = "UPDATE vcommerce.inventory_hold SET `status`=?, `updated`=now() WHERE order_id=? AND status=?"
+const SelectExpiredInventoryHolds = "SELECT order_id, items_json, status, expires_at FROM vcommerce.inventory_hold WHERE status=? AND expires_at<? ORDER BY expires_at LIMIT ?"
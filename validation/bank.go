@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownBank          = errors.New("unknown bank")
+	ErrInvalidAccountNumber = errors.New("invalid bank account number")
+)
+
+type Bank struct {
+	Code    string // 금융결제원 은행 코드
+	Name    string
+	Lengths []int // 하이픈을 제외한 계좌번호 자리수
+}
+
+// 판매 대금 정산을 받을 수 있는 은행 목록
+var banks = []Bank{
+	{Code: "002", Name: "산업은행", Lengths: []int{11, 14}},
+	{Code: "003", Name: "기업은행", Lengths: []int{10, 11, 12, 14}},
+	{Code: "004", Name: "국민은행", Lengths: []int{12, 14}},
+	{Code: "007", Name: "수협은행", Lengths: []int{11, 12, 14}},
+	{Code: "011", Name: "농협은행", Lengths: []int{11, 12, 13, 14}},
+	{Code: "020", Name: "우리은행", Lengths: []int{13}},
+	{Code: "023", Name: "SC제일은행", Lengths: []int{11}},
+	{Code: "027", Name: "씨티은행", Lengths: []int{10, 11, 12, 13}},
+	{Code: "031", Name: "대구은행", Lengths: []int{11, 12}},
+	{Code: "032", Name: "부산은행", Lengths: []int{12, 13}},
+	{Code: "034", Name: "광주은행", Lengths: []int{12, 13}},
+	{Code: "035", Name: "제주은행", Lengths: []int{10, 12}},
+	{Code: "037", Name: "전북은행", Lengths: []int{12, 13}},
+	{Code: "039", Name: "경남은행", Lengths: []int{12, 13}},
+	{Code: "045", Name: "새마을금고", Lengths: []int{13, 14}},
+	{Code: "048", Name: "신협", Lengths: []int{12, 13}},
+	{Code: "071", Name: "우체국", Lengths: []int{14}},
+	{Code: "081", Name: "하나은행", Lengths: []int{12, 14}},
+	{Code: "088", Name: "신한은행", Lengths: []int{11, 12}},
+	{Code: "089", Name: "케이뱅크", Lengths: []int{12}},
+	{Code: "090", Name: "카카오뱅크", Lengths: []int{13}},
+	{Code: "092", Name: "토스뱅크", Lengths: []int{12}},
+}
+
+// 은행 코드 혹은 이름으로 은행을 찾는다. "국민", "KB국민은행" 처럼 흔히 쓰는 이름도 허용한다.
+func FindBank(nameOrCode string) (Bank, error) {
+	key := strings.TrimSpace(nameOrCode)
+	if key == "" {
+		return Bank{}, ErrUnknownBank
+	}
+	for _, bank := range banks {
+		if key == bank.Code || key == bank.Name {
+			return bank, nil
+		}
+	}
+	short := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "KB"), "NH"), "은행")
+	for _, bank := range banks {
+		if short != "" && strings.TrimSuffix(bank.Name, "은행") == short {
+			return bank, nil
+		}
+	}
+	return Bank{}, ErrUnknownBank
+}
+
+// 은행별 계좌번호 형식을 확인한다. 하이픈을 제거한 계좌번호를 돌려준다.
+func AccountNumber(bank Bank, accountNumber string) (string, error) {
+	normalized := stripSeparators(accountNumber)
+	for _, r := range normalized {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidAccountNumber
+		}
+	}
+	for _, length := range bank.Lengths {
+		if len(normalized) == length {
+			return normalized, nil
+		}
+	}
+	return "", ErrInvalidAccountNumber
+}
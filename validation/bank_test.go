@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"github.com/4538cgy/backend-second/config"
+	"testing"
+)
+
+func TestFindBank(t *testing.T) {
+	tests := []struct {
+		nameOrCode string
+		want       string
+		err        error
+	}{
+		{"004", "국민은행", nil},
+		{"국민은행", "국민은행", nil},
+		{"KB국민은행", "국민은행", nil},
+		{"국민", "국민은행", nil},
+		{" 신한은행 ", "신한은행", nil},
+		{"NH농협은행", "농협은행", nil},
+		{"은행", "", ErrUnknownBank},
+		{"없는은행", "", ErrUnknownBank},
+		{"", "", ErrUnknownBank},
+	}
+	for _, test := range tests {
+		bank, err := FindBank(test.nameOrCode)
+		if bank.Name != test.want || err != test.err {
+			t.Errorf("FindBank(%q) = %q, %v. want %q, %v", test.nameOrCode, bank.Name, err, test.want, test.err)
+		}
+	}
+}
+
+func TestAccountNumber(t *testing.T) {
+	kb, _ := FindBank("004")
+	woori, _ := FindBank("020")
+	tests := []struct {
+		bank    Bank
+		account string
+		want    string
+		err     error
+	}{
+		{kb, "123456-78-901234", "12345678901234", nil},
+		{kb, "123456789012", "123456789012", nil},
+		{kb, "1234567890123", "", ErrInvalidAccountNumber},
+		{woori, "1002 123 456789", "1002123456789", nil},
+		{woori, "1002-123-45678x", "", ErrInvalidAccountNumber},
+		{woori, "1002/123/456789", "", ErrInvalidAccountNumber},
+		{woori, "", "", ErrInvalidAccountNumber},
+	}
+	for _, test := range tests {
+		got, err := AccountNumber(test.bank, test.account)
+		if got != test.want || err != test.err {
+			t.Errorf("AccountNumber(%s, %q) = %q, %v. want %q, %v", test.bank.Name, test.account, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestVerifySellerAccountHolder(t *testing.T) {
+	account := NewFakeAccountVerifier()
+	kb, _ := FindBank("004")
+	account.SetHolder(kb, "123456789012", "홍길동")
+	v := NewVerifierWith(NewFakeBusinessVerifier(), account)
+
+	tests := []struct {
+		owner string
+		err   error
+	}{
+		{"홍길동", nil},
+		{"김철수", ErrAccountHolderMismatch},
+	}
+	for _, test := range tests {
+		info := &SellerInfo{SellerType: SellerPersonal, OwnerName: test.owner, BankName: "국민", BankAccountNumber: "1234-5678-9012"}
+		if err := v.VerifySeller(info); err != test.err {
+			t.Errorf("owner %s: err %v. want %v", test.owner, err, test.err)
+			continue
+		}
+		if test.err == nil && (info.BankName != "국민은행" || info.BankAccountNumber != "123456789012") {
+			t.Errorf("owner %s: normalized bank %s, account %s", test.owner, info.BankName, info.BankAccountNumber)
+		}
+	}
+}
+
+func TestNewVerifierAccountVerifier(t *testing.T) {
+	tests := []struct {
+		accountVerifier string
+		ok              bool
+	}{
+		{"manual", true},
+		{"fake", true},
+		{"", false},
+		{"openbanking", false},
+	}
+	for _, test := range tests {
+		cfg := &config.Config{}
+		cfg.Verify.BusinessVerifier = "fake"
+		cfg.Verify.AccountVerifier = test.accountVerifier
+		if _, err := NewVerifier(cfg); (err == nil) != test.ok {
+			t.Errorf("account verifier %q: err %v", test.accountVerifier, err)
+		}
+	}
+}
+
+// manual 이면 예금주는 확인하지 않지만 은행과 계좌번호는 확인한다.
+func TestVerifySellerManualAccount(t *testing.T) {
+	v := NewVerifierWith(NewFakeBusinessVerifier(), nil)
+
+	info := &SellerInfo{SellerType: SellerPersonal, OwnerName: "홍길동", BankName: "국민", BankAccountNumber: "1234-5678-9012"}
+	if err := v.VerifySeller(info); err != nil {
+		t.Fatal(err)
+	}
+	if info.BankName != "국민은행" || info.BankAccountNumber != "123456789012" {
+		t.Fatalf("normalized bank %s, account %s", info.BankName, info.BankAccountNumber)
+	}
+
+	info = &SellerInfo{SellerType: SellerPersonal, OwnerName: "홍길동", BankName: "없는은행", BankAccountNumber: "1234-5678-9012"}
+	if err := v.VerifySeller(info); err == nil {
+		t.Fatal("unknown bank accepted")
+	}
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidBusinessNumber = errors.New("invalid business registration number")
+)
+
+// 사업자등록번호 check digit 가중치
+var businessNumberWeights = [9]int{1, 3, 7, 1, 3, 7, 1, 3, 5}
+
+// 사용자가 보낸 하이픈, 공백을 지운다. 그 외 문자는 남겨서 숫자 검사에 걸리게 한다.
+func stripSeparators(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// 사업자등록번호(XXX-XX-XXXXX)의 형식과 check digit 를 확인한다.
+// 하이픈을 제거한 10자리 번호를 돌려준다.
+func BusinessNumber(number string) (string, error) {
+	normalized := stripSeparators(number)
+	if len(normalized) != 10 {
+		return "", ErrInvalidBusinessNumber
+	}
+	for _, r := range normalized {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidBusinessNumber
+		}
+	}
+
+	sum := 0
+	for index, weight := range businessNumberWeights {
+		sum += int(normalized[index]-'0') * weight
+	}
+	// 9번째 자리는 5를 곱한 값의 십의 자리도 더한다.
+	sum += int(normalized[8]-'0') * 5 / 10
+
+	check := (10 - sum%10) % 10
+	if check != int(normalized[9]-'0') {
+		return "", ErrInvalidBusinessNumber
+	}
+	return normalized, nil
+}
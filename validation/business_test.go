@@ -0,0 +1,29 @@
+package validation
+
+import "testing"
+
+func TestBusinessNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+		want   string
+		err    error
+	}{
+		{"hyphen", "220-81-62517", "2208162517", nil},
+		{"digits only", "1248100998", "1248100998", nil},
+		{"space", "120 81 47521", "1208147521", nil},
+		{"ninth digit carry", "123-45-67891", "1234567891", nil},
+		{"wrong check digit", "123-45-67890", "", ErrInvalidBusinessNumber},
+		{"too short", "220-81-6251", "", ErrInvalidBusinessNumber},
+		{"too long", "220-81-625170", "", ErrInvalidBusinessNumber},
+		{"letter", "220-81-6251a", "", ErrInvalidBusinessNumber},
+		{"other separator", "220.81.62517", "", ErrInvalidBusinessNumber},
+		{"empty", "", "", ErrInvalidBusinessNumber},
+	}
+	for _, test := range tests {
+		got, err := BusinessNumber(test.number)
+		if got != test.want || err != test.err {
+			t.Errorf("%s: BusinessNumber(%q) = %q, %v. want %q, %v", test.name, test.number, got, err, test.want, test.err)
+		}
+	}
+}
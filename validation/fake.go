@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"sync"
+)
+
+// 외부 api 없이 판매자 등록을 확인하기 위한 구현.
+// 따로 지정하지 않은 사업자는 계속사업자, 계좌는 요청한 예금주로 간주한다.
+type FakeBusinessVerifier struct {
+	lock     sync.Mutex
+	statuses map[string]BusinessStatus
+}
+
+func NewFakeBusinessVerifier() *FakeBusinessVerifier {
+	return &FakeBusinessVerifier{
+		statuses: map[string]BusinessStatus{},
+	}
+}
+
+func (f *FakeBusinessVerifier) SetStatus(businessNumber string, status BusinessStatus) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.statuses[businessNumber] = status
+}
+
+func (f *FakeBusinessVerifier) BusinessStatus(businessNumber string) (BusinessStatus, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if status, ok := f.statuses[businessNumber]; ok {
+		return status, nil
+	}
+	return BusinessActive, nil
+}
+
+type FakeAccountVerifier struct {
+	lock    sync.Mutex
+	holders map[string]string // bank code + account number -> 예금주
+}
+
+func NewFakeAccountVerifier() *FakeAccountVerifier {
+	return &FakeAccountVerifier{
+		holders: map[string]string{},
+	}
+}
+
+func (f *FakeAccountVerifier) SetHolder(bank Bank, accountNumber, holderName string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.holders[bank.Code+accountNumber] = holderName
+}
+
+func (f *FakeAccountVerifier) VerifyAccountHolder(bank Bank, accountNumber, holderName string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if holder, ok := f.holders[bank.Code+accountNumber]; ok && holder != holderName {
+		return ErrAccountHolderMismatch
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/4538cgy/backend-second/config"
+	"github.com/4538cgy/backend-second/log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	verifierFake   = "fake"
+	verifierNts    = "nts"
+	verifierManual = "manual"
+
+	ntsStatusAddress = "https://api.odcloud.kr/api/nts-businessman/v1/status"
+	ntsTimeout       = 3 * time.Second
+
+	// 판매자 유형. seller.seller_type
+	SellerPersonal = 0
+	SellerBusiness = 1
+)
+
+var (
+	ErrBusinessNotActive     = errors.New("business is not active")
+	ErrAccountHolderMismatch = errors.New("bank account holder mismatch")
+)
+
+type BusinessStatus int
+
+const (
+	BusinessUnknown   = BusinessStatus(0) // 국세청에 등록되지 않은 번호
+	BusinessActive    = BusinessStatus(1) // 계속사업자
+	BusinessSuspended = BusinessStatus(2) // 휴업자
+	BusinessClosed    = BusinessStatus(3) // 폐업자
+)
+
+// 국세청 사업자 상태 조회
+type BusinessVerifier interface {
+	BusinessStatus(businessNumber string) (BusinessStatus, error)
+}
+
+// 1원 인증 혹은 예금주 조회로 계좌의 예금주를 확인한다.
+type AccountVerifier interface {
+	VerifyAccountHolder(bank Bank, accountNumber, holderName string) error
+}
+
+// 판매자 등록 정보. Verify 를 통과하면 번호들은 숫자만 남도록 바뀐다.
+type SellerInfo struct {
+	SellerType                int
+	CompanyRegistrationNumber string
+	OwnerName                 string
+	BankName                  string
+	BankAccountNumber         string
+}
+
+type Verifier interface {
+	VerifySeller(info *SellerInfo) error
+}
+
+type verifier struct {
+	business BusinessVerifier
+	account  AccountVerifier // nil 이면 예금주를 확인하지 않는다. 관리자가 승인할 때 통장 사본으로 확인한다.
+}
+
+func NewVerifier(cfg *config.Config) (Verifier, error) {
+	v := &verifier{}
+	switch cfg.Verify.BusinessVerifier {
+	case verifierNts:
+		if cfg.Verify.NtsServiceKey == "" {
+			return nil, errors.New("nts service key is empty")
+		}
+		v.business = &ntsBusinessVerifier{
+			serviceKey: cfg.Verify.NtsServiceKey,
+			client:     &http.Client{Timeout: ntsTimeout},
+		}
+	case verifierFake:
+		log.Warning("fake business verifier is used. every business number will be active.")
+		v.business = NewFakeBusinessVerifier()
+	case "":
+		return nil, errors.New("business verifier is not configured")
+	default:
+		return nil, fmt.Errorf("wrong business verifier: %s", cfg.Verify.BusinessVerifier)
+	}
+
+	switch cfg.Verify.AccountVerifier {
+	case verifierManual:
+		log.Error("account holder verification is disabled. check the bank account copy before approving sellers.")
+	case verifierFake:
+		log.Warning("fake account verifier is used. every account holder will be accepted.")
+		v.account = NewFakeAccountVerifier()
+	case "":
+		return nil, errors.New("account verifier is not configured")
+	default:
+		// TODO 오픈뱅킹 혹은 펌뱅킹 업체가 정해지면 추가.
+		return nil, fmt.Errorf("wrong account verifier: %s", cfg.Verify.AccountVerifier)
+	}
+	return v, nil
+}
+
+// 외부 검증 구현을 직접 지정한다. test 나 다른 업체 연동에 사용한다.
+func NewVerifierWith(business BusinessVerifier, account AccountVerifier) Verifier {
+	return &verifier{business: business, account: account}
+}
+
+func (v *verifier) VerifySeller(info *SellerInfo) error {
+	if info.SellerType == SellerBusiness {
+		number, err := BusinessNumber(info.CompanyRegistrationNumber)
+		if err != nil {
+			return err
+		}
+		status, err := v.business.BusinessStatus(number)
+		if err != nil {
+			return err
+		}
+		if status != BusinessActive {
+			return ErrBusinessNotActive
+		}
+		info.CompanyRegistrationNumber = number
+	}
+
+	bank, err := FindBank(info.BankName)
+	if err != nil {
+		return err
+	}
+	account, err := AccountNumber(bank, info.BankAccountNumber)
+	if err != nil {
+		return err
+	}
+	if v.account == nil {
+		log.Warning("account holder is not verified. bank: ", bank.Name, ", owner: ", info.OwnerName)
+	} else if err := v.account.VerifyAccountHolder(bank, account, info.OwnerName); err != nil {
+		return err
+	}
+	info.BankName = bank.Name
+	info.BankAccountNumber = account
+	return nil
+}
+
+// 공공데이터포털 국세청 사업자등록정보 상태조회 api
+type ntsBusinessVerifier struct {
+	serviceKey string
+	client     *http.Client
+}
+
+type ntsStatusRequest struct {
+	BusinessNumbers []string `json:"b_no"`
+}
+
+type ntsStatusResponse struct {
+	StatusCode string `json:"status_code"`
+	Data       []struct {
+		BusinessNumber string `json:"b_no"`
+		StatusCode     string `json:"b_stt_cd"` // 01 계속, 02 휴업, 03 폐업
+	} `json:"data"`
+}
+
+func (n *ntsBusinessVerifier) BusinessStatus(businessNumber string) (BusinessStatus, error) {
+	body, err := json.Marshal(&ntsStatusRequest{BusinessNumbers: []string{businessNumber}})
+	if err != nil {
+		return BusinessUnknown, err
+	}
+	address := fmt.Sprintf("%s?serviceKey=%s", ntsStatusAddress, url.QueryEscape(n.serviceKey))
+	res, err := n.client.Post(address, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return BusinessUnknown, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return BusinessUnknown, fmt.Errorf("nts status api failed. status: %d", res.StatusCode)
+	}
+
+	status := ntsStatusResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		return BusinessUnknown, err
+	}
+	for _, data := range status.Data {
+		if data.BusinessNumber != businessNumber {
+			continue
+		}
+		switch data.StatusCode {
+		case "01":
+			return BusinessActive, nil
+		case "02":
+			return BusinessSuspended, nil
+		case "03":
+			return BusinessClosed, nil
+		}
+	}
+	return BusinessUnknown, nil
+}